@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+)
+
+const credentialsFileOpt = "credentials-file"
+const credentialsPassEnvOpt = "credentials-pass-env"
+const tokenFileOpt = "token-file"
+const tokenEndpointOpt = "token-endpoint"
+const areaIdOpt = "area-id"
+const apiKeyOpt = "api-key"
+const usernameOpt = "username"
+const helpOpt = "help"
+
+var credentialsFile string
+var envCredentialsPass string
+var tokenFile string
+var tokenEndpoint string
+var cliCredentials v3client.MasheryV3Credentials
+var showHelp bool
+
+type ExecutorFunc func(context.Context, []string) int
+
+// subCommands the sub-commands of the utility, by name
+var subCommands = map[string]ExecutorFunc{}
+
+func enableSubcommand(name string, f ExecutorFunc) {
+	subCommands[name] = f
+}
+
+// credentials derives the credentials from the environment, the credentials file and the command line
+func credentials() v3client.MasheryV3Credentials {
+	return v3client.DeriveAccessCredentials(credentialsFile, os.Getenv(envCredentialsPass), &cliCredentials)
+}
+
+func oauthHelper() *v3client.V3OAuthHelper {
+	return v3client.NewOAuthHelper(v3client.OAuthHelperParams{
+		MasheryTokenEndpoint: tokenEndpoint,
+	})
+}
+
+// savedToken reads the token from the token file
+func savedToken() (*masherytypes.TimedAccessTokenResponse, error) {
+	if resp, err := v3client.LoadV3TokenResponse(tokenFile); err != nil {
+		return nil, err
+	} else if resp == nil {
+		return nil, errors.New(fmt.Sprintf("no access token is saved in %s; run `mash-connect init` first", tokenFile))
+	} else {
+		return resp, nil
+	}
+}
+
+// renew exchanges the refresh token of the saved token, falling back to the password grant where the refresh
+// token is not accepted and the credentials are fully specified
+func renew(helper *v3client.V3OAuthHelper, creds v3client.MasheryV3Credentials, saved *masherytypes.TimedAccessTokenResponse) (*masherytypes.TimedAccessTokenResponse, error) {
+	var refreshErr error
+	if saved != nil && len(saved.RefreshToken) > 0 {
+		var resp *masherytypes.TimedAccessTokenResponse
+		if resp, refreshErr = helper.ExchangeRefreshToken(&creds, saved.RefreshToken); refreshErr == nil {
+			return resp, nil
+		}
+	}
+
+	if !creds.FullySpecified() {
+		if refreshErr != nil {
+			return nil, refreshErr
+		}
+		return nil, errors.New("credentials are not fully specified, and no refresh token is available")
+	}
+	return helper.RetrieveAccessTokenFor(&creds)
+}
+
+// timeLeft the time left until the token expires
+func timeLeft(resp *masherytypes.TimedAccessTokenResponse) time.Duration {
+	return time.Duration(resp.TimeLeft()) * time.Second
+}
+
+func usage() {
+	fmt.Println("Usage: mash-connect [options] <sub-command> [sub-command options]")
+	fmt.Println()
+	fmt.Println("Sub-commands:")
+
+	names := make([]string, 0, len(subCommands))
+	for k := range subCommands {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fmt.Printf("  %s\n", n)
+	}
+
+	fmt.Println()
+	fmt.Println("Options:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.StringVar(&credentialsFile, credentialsFileOpt, v3client.DefaultCredentialsFile(), "File containing the Mashery V3 credentials")
+	flag.StringVar(&envCredentialsPass, credentialsPassEnvOpt, "MASHERY_CREDENTIALS_PASS", "An environment variable containing the password of the encrypted credentials file")
+	flag.StringVar(&tokenFile, tokenFileOpt, v3client.DefaultSavedAccessTokenFilePath(), "File the access token is saved to")
+	flag.StringVar(&tokenEndpoint, tokenEndpointOpt, v3client.MasheryTokenEndpoint, "Mashery V3 token endpoint")
+	flag.StringVar(&cliCredentials.AreaId, areaIdOpt, "", "Mashery area Id, overriding the environment and the credentials file")
+	flag.StringVar(&cliCredentials.ApiKey, apiKeyOpt, "", "Mashery V3 API key, overriding the environment and the credentials file")
+	flag.StringVar(&cliCredentials.Username, usernameOpt, "", "Mashery user name, overriding the environment and the credentials file")
+	flag.BoolVar(&showHelp, helpOpt, false, "Show help options")
+	flag.Parse()
+
+	if showHelp {
+		usage()
+		os.Exit(0)
+	}
+
+	args := flag.Args()
+	if len(args) == 0 {
+		fmt.Println("Sub-command required")
+		usage()
+		os.Exit(1)
+	}
+
+	execFunc, ok := subCommands[args[0]]
+	if !ok {
+		fmt.Printf("Unrecognized command %s", args[0])
+		fmt.Println()
+		usage()
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	exitCode := execFunc(ctx, args[1:])
+	stop()
+	os.Exit(exitCode)
+}
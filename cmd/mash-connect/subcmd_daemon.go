@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// daemonDelay the time until the saved token has to be renewed
+func daemonDelay(saved *masherytypes.TimedAccessTokenResponse, ahead time.Duration) time.Duration {
+	if saved == nil || saved.Expired() {
+		return 0
+	}
+
+	if rv := timeLeft(saved) - ahead; rv > 0 {
+		return rv
+	}
+	return 0
+}
+
+// execDaemon keeps the saved token fresh, renewing it ahead of the expiry until interrupted
+func execDaemon(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	ahead := fs.Duration("refresh-ahead", time.Minute*5, "How long before the expiry the token is renewed")
+	retry := fs.Duration("retry-interval", time.Minute, "How long to wait before retrying the failed renewal")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	logger.Info("keeping the access token fresh", "file", tokenFile)
+
+	for {
+		saved, err := v3client.LoadV3TokenResponse(tokenFile)
+		if err != nil {
+			logger.Warn("could not read the access token", "file", tokenFile, "error", err)
+		}
+
+		wait := daemonDelay(saved, *ahead)
+		if wait > 0 {
+			logger.Info("access token is valid", "time_left", timeLeft(saved), "next_refresh", time.Now().Add(wait).Format(time.RFC3339))
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Info("stopped")
+			return 0
+		case <-time.After(wait):
+		}
+
+		if resp, err := refreshTokenFile(saved); err != nil {
+			logger.Error("could not refresh the access token", "error", err, "retry_in", *retry)
+
+			select {
+			case <-ctx.Done():
+				logger.Info("stopped")
+				return 0
+			case <-time.After(*retry):
+			}
+		} else {
+			logger.Info("access token refreshed", "time_left", timeLeft(resp))
+		}
+	}
+}
+
+func init() {
+	enableSubcommand("daemon", execDaemon)
+}
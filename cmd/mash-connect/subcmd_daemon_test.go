@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDaemonDelay(t *testing.T) {
+	assert.Equal(t, time.Duration(0), daemonDelay(nil, time.Minute))
+
+	fresh := &masherytypes.TimedAccessTokenResponse{
+		Obtained:            time.Now(),
+		AccessTokenResponse: masherytypes.AccessTokenResponse{ExpiresIn: 3600},
+	}
+	delay := daemonDelay(fresh, time.Minute*5)
+	assert.True(t, delay > time.Minute*54 && delay <= time.Minute*55, delay)
+
+	expiring := &masherytypes.TimedAccessTokenResponse{
+		Obtained:            time.Now().Add(-time.Minute * 58),
+		AccessTokenResponse: masherytypes.AccessTokenResponse{ExpiresIn: 3600},
+	}
+	assert.Equal(t, time.Duration(0), daemonDelay(expiring, time.Minute*5))
+}
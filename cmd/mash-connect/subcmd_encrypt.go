@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"os"
+)
+
+// execEncrypt encrypts the credentials file in place with the password from the environment
+func execEncrypt(_ context.Context, _ []string) int {
+	pass := os.Getenv(envCredentialsPass)
+	if len(pass) == 0 {
+		fmt.Printf("The password of the credentials file is expected in %s environment variable", envCredentialsPass)
+		fmt.Println()
+		return 1
+	}
+
+	if err := v3client.EncryptInPlace(credentialsFile, pass); err != nil {
+		fmt.Printf("Could not encrypt %s: %s", credentialsFile, err)
+		fmt.Println()
+		return 2
+	}
+
+	fmt.Printf("Credentials file %s is encrypted", credentialsFile)
+	fmt.Println()
+	return 0
+}
+
+func init() {
+	enableSubcommand("encrypt", execEncrypt)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"os"
+	"strings"
+)
+
+// exportStatement the statement setting the environment variable in the specified shell
+func exportStatement(shell, variable, value string) (string, error) {
+	switch shell {
+	case "sh", "bash", "zsh":
+		return fmt.Sprintf("export %s='%s'", variable, strings.ReplaceAll(value, "'", `'\''`)), nil
+	case "fish":
+		return fmt.Sprintf("set -gx %s '%s'", variable, strings.ReplaceAll(value, "'", `\'`)), nil
+	case "powershell":
+		return fmt.Sprintf("$env:%s = '%s'", variable, strings.ReplaceAll(value, "'", "''")), nil
+	default:
+		return "", errors.New(fmt.Sprintf("unsupported shell %s", shell))
+	}
+}
+
+// execExport prints the statement exporting the saved access token into the environment variable, e.g.
+// for `eval $(mash-connect export)`
+func execExport(_ context.Context, args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	shell := fs.String("shell", "sh", "Shell the statement is printed for: sh, fish or powershell")
+	variable := fs.String("variable", v3client.AccessTokenEnv, "Environment variable the access token is exported into")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	resp, err := savedToken()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return 1
+	} else if resp.Expired() {
+		_, _ = fmt.Fprintln(os.Stderr, "Access token has expired")
+		return 1
+	}
+
+	if stmt, err := exportStatement(*shell, *variable, resp.AccessToken); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return 1
+	} else {
+		fmt.Println(stmt)
+		return 0
+	}
+}
+
+func init() {
+	enableSubcommand("export", execExport)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestExportStatement(t *testing.T) {
+	stmt, err := exportStatement("sh", "MASHERY_V3_TOKEN", "abc")
+	assert.Nil(t, err)
+	assert.Equal(t, "export MASHERY_V3_TOKEN='abc'", stmt)
+
+	stmt, err = exportStatement("fish", "MASHERY_V3_TOKEN", "abc")
+	assert.Nil(t, err)
+	assert.Equal(t, "set -gx MASHERY_V3_TOKEN 'abc'", stmt)
+
+	stmt, err = exportStatement("powershell", "MASHERY_V3_TOKEN", "a'bc")
+	assert.Nil(t, err)
+	assert.Equal(t, "$env:MASHERY_V3_TOKEN = 'a''bc'", stmt)
+
+	stmt, err = exportStatement("sh", "MASHERY_V3_TOKEN", "a'bc")
+	assert.Nil(t, err)
+	assert.Equal(t, `export MASHERY_V3_TOKEN='a'\''bc'`, stmt)
+
+	_, err = exportStatement("cmd", "MASHERY_V3_TOKEN", "abc")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+)
+
+// execInit obtains the access token with the password grant and saves it to the token file
+func execInit(_ context.Context, _ []string) int {
+	creds := credentials()
+	if !creds.FullySpecified() {
+		fmt.Println("Credentials are not fully specified: area Id, API key, secret, user name and password are required")
+		return 1
+	}
+
+	resp, err := oauthHelper().RetrieveAccessTokenFor(&creds)
+	if err != nil {
+		fmt.Printf("Could not obtain the access token: %s", err)
+		fmt.Println()
+		return 2
+	}
+
+	if err = v3client.PersistV3TokenResponse(resp, tokenFile); err != nil {
+		fmt.Printf("Could not save the access token to %s: %s", tokenFile, err)
+		fmt.Println()
+		return 2
+	}
+
+	fmt.Printf("Access token saved to %s; %s left", tokenFile, timeLeft(resp))
+	fmt.Println()
+	return 0
+}
+
+func init() {
+	enableSubcommand("init", execInit)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"os"
+)
+
+// execMigrate re-encrypts the credentials file into the current format
+func execMigrate(_ context.Context, args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	oldPassEnv := fs.String("old-pass-env", "", "An environment variable containing the current password of the credentials file; defaults to the new password")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	newPass := os.Getenv(envCredentialsPass)
+	if len(newPass) == 0 {
+		fmt.Printf("The password of the credentials file is expected in %s environment variable", envCredentialsPass)
+		fmt.Println()
+		return 1
+	}
+
+	oldPass := newPass
+	if len(*oldPassEnv) > 0 {
+		oldPass = os.Getenv(*oldPassEnv)
+	}
+
+	if err := v3client.MigrateCiphertext(credentialsFile, oldPass, newPass); err != nil {
+		fmt.Printf("Could not migrate %s: %s", credentialsFile, err)
+		fmt.Println()
+		return 2
+	}
+
+	fmt.Printf("Credentials file %s is re-encrypted", credentialsFile)
+	fmt.Println()
+	return 0
+}
+
+func init() {
+	enableSubcommand("migrate", execMigrate)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+)
+
+// execRefresh renews the saved access token
+func execRefresh(_ context.Context, _ []string) int {
+	saved, err := v3client.LoadV3TokenResponse(tokenFile)
+	if err != nil {
+		fmt.Printf("Could not read the access token from %s: %s", tokenFile, err)
+		fmt.Println()
+		return 1
+	}
+
+	resp, err := refreshTokenFile(saved)
+	if err != nil {
+		fmt.Printf("Could not refresh the access token: %s", err)
+		fmt.Println()
+		return 2
+	}
+
+	fmt.Printf("Access token saved to %s; %s left", tokenFile, timeLeft(resp))
+	fmt.Println()
+	return 0
+}
+
+// refreshTokenFile renews the token and saves it to the token file
+func refreshTokenFile(saved *masherytypes.TimedAccessTokenResponse) (*masherytypes.TimedAccessTokenResponse, error) {
+	resp, err := renew(oauthHelper(), credentials(), saved)
+	if err != nil {
+		return nil, err
+	}
+	return resp, v3client.PersistV3TokenResponse(resp, tokenFile)
+}
+
+func init() {
+	enableSubcommand("refresh", execRefresh)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// execShow displays how much time is left in the saved access token
+func execShow(_ context.Context, _ []string) int {
+	resp, err := savedToken()
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	fmt.Printf("Token file:  %s", tokenFile)
+	fmt.Println()
+	fmt.Printf("Obtained:    %s", resp.Obtained.Format(time.RFC1123))
+	fmt.Println()
+	fmt.Printf("Expires:     %s", resp.ExpiryTime().Format(time.RFC1123))
+	fmt.Println()
+	if resp.QPS > 0 {
+		fmt.Printf("QPS:         %d", resp.QPS)
+		fmt.Println()
+	}
+
+	if left := timeLeft(resp); left <= 0 {
+		fmt.Println("Access token has EXPIRED. Run `mash-connect refresh` or `mash-connect init` to obtain a new one.")
+		return 1
+	} else {
+		fmt.Printf("Time left:   %s", left)
+		fmt.Println()
+	}
+	return 0
+}
+
+func init() {
+	enableSubcommand("show", execShow)
+}
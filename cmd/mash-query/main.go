@@ -25,6 +25,7 @@ const qpsOps = "qps"
 const outputJsonOps = "as-json"
 const helpOpt = "help"
 const verboseTrafficOpt = "verbose-traffic"
+const harOutputOpt = "har-output"
 
 var qps int64
 var travelTimeComp string
@@ -36,6 +37,8 @@ var envVaultTokenResource string
 var globalOptOutputJson bool
 var showHelp bool
 var showVerboseTraffic bool
+var harOutput string
+var harRecorder *transport.HARRecorder
 var jsonEncoder *json.Encoder
 
 type ExecutorFunc func(context.Context, v3client.Client, []string) int
@@ -94,7 +97,7 @@ func kvArrayToMap(in []string) map[string]string {
 	return rv
 }
 
-func trafficListener(_ context.Context, req *transport.WrappedRequest, res *transport.WrappedResponse, err error) {
+func trafficListener(ctx context.Context, req *transport.WrappedRequest, res *transport.WrappedResponse, err error) {
 	if showVerboseTraffic {
 		fmt.Printf("-> %s %s\n", req.Request.Method, req.Request.URL.String())
 		if req.Body != nil {
@@ -104,6 +107,20 @@ func trafficListener(_ context.Context, req *transport.WrappedRequest, res *tran
 		fmt.Println("<-")
 		fmt.Println(string(res.MustBody()))
 	}
+
+	if harRecorder != nil {
+		harRecorder.Listener(ctx, req, res, err)
+	}
+}
+
+// saveHAR writes the traffic archive, if one was requested
+func saveHAR() {
+	if harRecorder != nil {
+		if err := harRecorder.Save(harOutput); err != nil {
+			fmt.Printf("Could not write traffic archive to %s: %s", harOutput, err)
+			fmt.Println()
+		}
+	}
 }
 
 func main() {
@@ -117,6 +134,7 @@ func main() {
 	flag.BoolVar(&globalOptOutputJson, outputJsonOps, false, "Output JSON rather than a pretty-printed template")
 	flag.BoolVar(&showHelp, helpOpt, false, "Show help options")
 	flag.BoolVar(&showVerboseTraffic, verboseTrafficOpt, false, "Show verbose traffic")
+	flag.StringVar(&harOutput, harOutputOpt, "", "Write the traffic, with secrets redacted, to the specified HTTP Archive (HAR) file")
 	flag.Parse()
 
 	if showHelp {
@@ -149,6 +167,10 @@ func main() {
 	} else {
 		ctx := context.TODO()
 
+		if len(harOutput) > 0 {
+			harRecorder = transport.NewHARRecorder()
+		}
+
 		dur, durErr := time.ParseDuration(travelTimeComp)
 		if durErr != nil {
 			dur = 173 * time.Millisecond
@@ -165,6 +187,7 @@ func main() {
 		})
 
 		exitCode := execFunc(ctx, cl, subCmd)
+		saveHAR()
 		os.Exit(exitCode)
 	}
 }
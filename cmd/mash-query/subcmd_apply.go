@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"flag"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client/declarative"
+)
+
+type ApplyArg struct {
+	Directory      string
+	DryRun         bool
+	AllowDeleteAll bool
+}
+
+//go:embed templates/apply.tmpl
+var applyTemplate string
+var subCmdApply *SubcommandTemplate[ApplyArg, *declarative.ChangeSet]
+
+func execApply(ctx context.Context, cl v3client.Client, arg ApplyArg) (*declarative.ChangeSet, error) {
+	return declarative.ApplyTree(ctx, cl, arg.Directory, declarative.SyncOptions{
+		DryRun:         arg.DryRun,
+		AllowDeleteAll: arg.AllowDeleteAll,
+	})
+}
+
+func initApplyFlagSet(arg *ApplyArg, fs *flag.FlagSet) {
+	fs.StringVar(&arg.Directory, "dir", "", "Directory containing the desired state of the area")
+	fs.BoolVar(&arg.DryRun, "dry-run", false, "Print the changes without applying them")
+	fs.BoolVar(&arg.AllowDeleteAll, "allow-delete-all", false, "Allow the changes deleting all services and packages of the area")
+}
+
+func validateApplyArg(arg *ApplyArg) error {
+	if len(arg.Directory) == 0 {
+		return errors.New("directory with the desired state is required")
+	}
+
+	return nil
+}
+
+func init() {
+	subCmdApply = &SubcommandTemplate[ApplyArg, *declarative.ChangeSet]{
+		Command:     []string{"apply"},
+		FlagSetInit: initApplyFlagSet,
+		Validator:   validateApplyArg,
+		Executor:    execApply,
+		Template:    mustTemplate(applyTemplate),
+	}
+
+	enableSubcommand(subCmdApply.Finder())
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client/declarative"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestApplyTemplate(t *testing.T) {
+	str, code := executeTemplate(subCmdApply.Template, &declarative.ChangeSet{
+		DryRun: true,
+		Operations: []*declarative.Operation{
+			{Action: declarative.ActionCreate, Kind: "service", Name: "svc"},
+			{Action: declarative.ActionDelete, Kind: "plan endpoint", Name: "pack/plan/svc/endp"},
+		},
+	})
+	assert.Equal(t, 0, code)
+	fmt.Println(str)
+
+	str, code = executeTemplate(subCmdApply.Template, &declarative.ChangeSet{})
+	assert.Equal(t, 0, code)
+	fmt.Println(str)
+}
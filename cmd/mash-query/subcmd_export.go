@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"flag"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client/declarative"
+)
+
+type ExportArg struct {
+	Directory string
+	Format    string
+}
+
+//go:embed templates/export.tmpl
+var exportTemplate string
+var subCmdExport *SubcommandTemplate[ExportArg, declarative.ExportSummary]
+
+func execExport(ctx context.Context, cl v3client.Client, arg ExportArg) (declarative.ExportSummary, error) {
+	format, err := declarative.ParseFormat(arg.Format)
+	if err != nil {
+		return declarative.ExportSummary{}, err
+	}
+
+	return declarative.ExportArea(ctx, cl, arg.Directory, format)
+}
+
+func initExportFlagSet(arg *ExportArg, fs *flag.FlagSet) {
+	fs.StringVar(&arg.Directory, "dir", "", "Directory to write the exported objects into")
+	fs.StringVar(&arg.Format, "format", "yaml", "Format of the exported files: yaml or json")
+}
+
+func validateExportArg(arg *ExportArg) error {
+	if len(arg.Directory) == 0 {
+		return errors.New("output directory is required")
+	}
+
+	_, err := declarative.ParseFormat(arg.Format)
+	return err
+}
+
+func init() {
+	subCmdExport = &SubcommandTemplate[ExportArg, declarative.ExportSummary]{
+		Command:     []string{"export"},
+		FlagSetInit: initExportFlagSet,
+		Validator:   validateExportArg,
+		Executor:    execExport,
+		Template:    mustTemplate(exportTemplate),
+	}
+
+	enableSubcommand(subCmdExport.Finder())
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client/declarative"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestExportTemplate(t *testing.T) {
+	str, code := executeTemplate(subCmdExport.Template, declarative.ExportSummary{
+		Directory: "./out",
+		Services:  1,
+		Endpoints: 2,
+		Methods:   3,
+		Packages:  1,
+		Plans:     1,
+		Files:     9,
+	})
+	assert.Equal(t, 0, code)
+	fmt.Println(str)
+}
+
+func TestExportArgValidation(t *testing.T) {
+	assert.NotNil(t, validateExportArg(&ExportArg{Format: "yaml"}))
+	assert.NotNil(t, validateExportArg(&ExportArg{Directory: "./out", Format: "xml"}))
+	assert.Nil(t, validateExportArg(&ExportArg{Directory: "./out", Format: "json"}))
+}
@@ -2,6 +2,7 @@ module github.com/aliakseiyanchuk/mashery-v3-go-client
 
 require (
 	github.com/stretchr/testify v1.9.0
+	golang.org/x/crypto v0.31.0
 	gopkg.in/yaml.v2 v2.4.0
 )
 
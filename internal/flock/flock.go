@@ -0,0 +1,8 @@
+// Package flock locks the files shared between the processes, such as the saved access token and the state
+// of the rate limiter.
+package flock
+
+import "errors"
+
+// ErrUnsupported the files cannot be locked on this platform
+var ErrUnsupported = errors.New("file locking is not supported on this platform")
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package flock
+
+import (
+	"os"
+)
+
+// Lock acquires an exclusive advisory lock on the file; not supported on this platform
+func Lock(_ *os.File) error {
+	return ErrUnsupported
+}
+
+// Unlock releases the lock acquired with Lock
+func Unlock(_ *os.File) error {
+	return nil
+}
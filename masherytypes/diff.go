@@ -0,0 +1,258 @@
+package masherytypes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ServerManagedFields JSON properties that are maintained by Mashery at any nesting level, e.g. also in
+// the organization embedded into the service. These are removed by RemoveServerManagedFields.
+var ServerManagedFields = []string{"created", "updated", "revisionNumber"}
+
+// ServerManagedProperties top-level JSON properties, in addition to ServerManagedFields, that identify the object
+// and are ignored when objects are compared. Parent identifiers are not serialized, with the exception of
+// the package plan method.
+var ServerManagedProperties = []string{"id", "PackagePlanServiceEndpoint"}
+
+// PropertyChange a difference in a single JSON property of an object. The path uses dot notation for nested
+// objects and the index in square brackets for array elements, e.g. `publicDomains[0].address`. Old and New
+// contain the JSON representation of the values; nil means the property is absent.
+type PropertyChange struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+func (pc PropertyChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", pc.Path, describeValue(pc.Old), describeValue(pc.New))
+}
+
+// PropertyChanges differences between two objects, ordered by path
+type PropertyChanges []PropertyChange
+
+// Empty checks whether the objects are equivalent
+func (pcs PropertyChanges) Empty() bool {
+	return len(pcs) == 0
+}
+
+// Paths returns the changed property paths
+func (pcs PropertyChanges) Paths() []string {
+	rv := make([]string, len(pcs))
+	for i, pc := range pcs {
+		rv[i] = pc.Path
+	}
+	return rv
+}
+
+// TopLevelProperties returns the names of the top-level properties that contain changes.
+func (pcs PropertyChanges) TopLevelProperties() []string {
+	var rv []string
+	seen := map[string]bool{}
+
+	for _, pc := range pcs {
+		name := pc.Path
+		if idx := strings.IndexAny(name, ".["); idx >= 0 {
+			name = name[:idx]
+		}
+		if !seen[name] {
+			seen[name] = true
+			rv = append(rv, name)
+		}
+	}
+	return rv
+}
+
+func (pcs PropertyChanges) String() string {
+	sb := strings.Builder{}
+	for _, pc := range pcs {
+		sb.WriteString(pc.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Diff compares JSON representations of two objects, typically two versions of the same Mashery object, and
+// returns the properties that differ. Server-managed properties and any of the additional top-level properties
+// passed in ignore are excluded from the comparison. Following Mashery semantics, null values and empty arrays
+// are considered equivalent to an absent property.
+func Diff(old, new interface{}, ignore ...string) (PropertyChanges, error) {
+	oldGeneric, err := GenericJSON(old)
+	if err != nil {
+		return nil, err
+	}
+	newGeneric, err := GenericJSON(new)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, g := range []interface{}{oldGeneric, newGeneric} {
+		RemoveServerManagedFields(g)
+		if m, ok := g.(map[string]interface{}); ok {
+			for _, k := range ServerManagedProperties {
+				delete(m, k)
+			}
+			for _, k := range ignore {
+				delete(m, k)
+			}
+		}
+	}
+
+	var rv PropertyChanges
+	diffValues("", oldGeneric, newGeneric, &rv)
+
+	sort.SliceStable(rv, func(i, j int) bool {
+		return rv[i].Path < rv[j].Path
+	})
+	return rv, nil
+}
+
+// UpdatePayload builds the minimal payload to update the object: the top-level properties that contain changes,
+// with the values of the updated object.
+func UpdatePayload(updated interface{}, changes PropertyChanges) (map[string]interface{}, error) {
+	rv := map[string]interface{}{}
+
+	generic, err := GenericJSON(updated)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := generic.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("object of type %T is not represented as JSON object", updated))
+	}
+
+	for _, p := range changes.TopLevelProperties() {
+		rv[p] = m[p]
+	}
+
+	return rv, nil
+}
+
+// GenericJSON converts the object into its JSON representation made of maps, slices and plain values. JSON numbers
+// are converted into integers where possible, or into floats otherwise, so that the values can be compared with
+// the values of Go types.
+func GenericJSON(obj interface{}) (interface{}, error) {
+	dat, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(dat))
+	dec.UseNumber()
+
+	var rv interface{}
+	if err = dec.Decode(&rv); err != nil {
+		return nil, err
+	}
+	return plainValue(rv), nil
+}
+
+// RemoveServerManagedFields removes ServerManagedFields at any nesting level of the generic JSON representation
+// and returns it.
+func RemoveServerManagedFields(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, k := range ServerManagedFields {
+			delete(t, k)
+		}
+		for _, child := range t {
+			RemoveServerManagedFields(child)
+		}
+	case []interface{}:
+		for _, child := range t {
+			RemoveServerManagedFields(child)
+		}
+	}
+	return v
+}
+
+// isAbsent checks whether the value is equivalent to the absent property
+func isAbsent(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	arr, ok := v.([]interface{})
+	return ok && len(arr) == 0
+}
+
+func childPath(parent, name string) string {
+	if len(parent) == 0 {
+		return name
+	}
+	return parent + "." + name
+}
+
+func diffValues(path string, old, new interface{}, dest *PropertyChanges) {
+	if isAbsent(old) && isAbsent(new) {
+		return
+	}
+
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := new.(map[string]interface{})
+	if oldIsMap && newIsMap {
+		keys := map[string]bool{}
+		for k := range oldMap {
+			keys[k] = true
+		}
+		for k := range newMap {
+			keys[k] = true
+		}
+
+		for k := range keys {
+			diffValues(childPath(path, k), oldMap[k], newMap[k], dest)
+		}
+		return
+	}
+
+	oldArr, oldIsArr := old.([]interface{})
+	newArr, newIsArr := new.([]interface{})
+	if oldIsArr && newIsArr && len(oldArr) == len(newArr) {
+		for i := range oldArr {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), oldArr[i], newArr[i], dest)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(old, new) {
+		*dest = append(*dest, PropertyChange{Path: path, Old: old, New: new})
+	}
+}
+
+// plainValue converts JSON numbers into integers or floats
+func plainValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		} else if f, err := t.Float64(); err == nil {
+			return f
+		}
+		return t.String()
+	case map[string]interface{}:
+		for k, child := range t {
+			t[k] = plainValue(child)
+		}
+		return t
+	case []interface{}:
+		for i, child := range t {
+			t[i] = plainValue(child)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+func describeValue(v interface{}) string {
+	if v == nil {
+		return "<absent>"
+	}
+	if dat, err := json.Marshal(v); err == nil {
+		return string(dat)
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,91 @@
+package masherytypes
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDiffIgnoresServerManagedFields(t *testing.T) {
+	created := MasheryJSONTime(time.Now())
+
+	old := Endpoint{
+		AddressableV3Object: AddressableV3Object{Id: "a", Name: "endp", Created: &created},
+		ParentServiceId:     ServiceIdentifier{ServiceId: "s1"},
+	}
+	new := Endpoint{
+		AddressableV3Object: AddressableV3Object{Id: "b", Name: "endp", Retrieved: time.Now()},
+		ParentServiceId:     ServiceIdentifier{ServiceId: "s2"},
+	}
+
+	changes, err := Diff(old, new)
+	assert.Nil(t, err)
+	assert.True(t, changes.Empty(), changes.String())
+}
+
+func TestDiffTreatsNilAndEmptySlicesAsEqual(t *testing.T) {
+	old := Endpoint{AddressableV3Object: AddressableV3Object{Name: "endp"}}
+	new := Endpoint{AddressableV3Object: AddressableV3Object{Name: "endp"},
+		PublicDomains:    []Domain{},
+		ForwardedHeaders: []string{},
+	}
+
+	changes, err := Diff(old, new)
+	assert.Nil(t, err)
+	assert.True(t, changes.Empty(), changes.String())
+}
+
+func TestDiffReportsNestedPaths(t *testing.T) {
+	old := Endpoint{
+		AddressableV3Object: AddressableV3Object{Name: "endp"},
+		RequestPathAlias:    "/a",
+		PublicDomains:       []Domain{{Address: "a.example.com"}},
+		Cors:                &Cors{AllDomainsEnabled: false, MaxAge: 10},
+	}
+	new := Endpoint{
+		AddressableV3Object: AddressableV3Object{Name: "endp"},
+		RequestPathAlias:    "/b",
+		PublicDomains:       []Domain{{Address: "b.example.com"}},
+		Cors:                &Cors{AllDomainsEnabled: true, MaxAge: 10},
+	}
+
+	changes, err := Diff(old, new)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cors.allDomainsEnabled", "publicDomains[0].address", "requestPathAlias"}, changes.Paths())
+	assert.Equal(t, PropertyChange{Path: "requestPathAlias", Old: "/a", New: "/b"}, changes[2])
+	assert.Equal(t, []string{"cors", "publicDomains", "requestPathAlias"}, changes.TopLevelProperties())
+
+	payload, err := UpdatePayload(new, changes)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(payload))
+	assert.Equal(t, "/b", payload["requestPathAlias"])
+}
+
+func TestDiffWithIgnoredProperties(t *testing.T) {
+	old := Service{AddressableV3Object: AddressableV3Object{Name: "a"}, Version: "1"}
+	new := Service{AddressableV3Object: AddressableV3Object{Name: "a"}, Version: "2"}
+
+	changes, err := Diff(old, new, "version")
+	assert.Nil(t, err)
+	assert.True(t, changes.Empty())
+
+	changes, err = Diff(old, new)
+	assert.Nil(t, err)
+	assert.Equal(t, "version: \"1\" -> \"2\"\n", changes.String())
+}
+
+func TestRemoveServerManagedFieldsAtAnyLevel(t *testing.T) {
+	created := MasheryJSONTime(time.Now())
+	svc := Service{
+		AddressableV3Object: AddressableV3Object{Id: "s", Name: "svc", Created: &created},
+		Organization:        &Organization{AddressableV3Object: AddressableV3Object{Name: "org", Updated: &created}},
+	}
+
+	generic, err := GenericJSON(svc)
+	assert.Nil(t, err)
+	m := RemoveServerManagedFields(generic).(map[string]interface{})
+
+	assert.Equal(t, "s", m["id"])
+	assert.NotContains(t, m, "created")
+	assert.NotContains(t, m["organization"], "updated")
+}
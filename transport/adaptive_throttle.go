@@ -0,0 +1,185 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// HeaderMasheryErrorCode Mashery error code of the rejected call
+	HeaderMasheryErrorCode = "X-Mashery-Error-Code"
+	// HeaderPlanQPSAllotted the QPS allowed by the plan of the API key
+	HeaderPlanQPSAllotted = "X-Plan-QPS-Allotted"
+	// HeaderRetryAfter standard header indicating when the call can be retried
+	HeaderRetryAfter = "Retry-After"
+
+	// CodeDeveloperOverQPS Mashery error code of the call rejected because the QPS limit was exceeded
+	CodeDeveloperOverQPS = "ERR_403_DEVELOPER_OVER_QPS"
+	// CodeDeveloperOverRate Mashery error code of the call rejected because the daily quota was exhausted
+	CodeDeveloperOverRate = "ERR_403_DEVELOPER_OVER_RATE"
+)
+
+// AdjustableRateLimiter rate limiter which rate can be changed while it is in use
+type AdjustableRateLimiter interface {
+	RateLimiter
+	QPS() float64
+	SetQPS(qps float64)
+}
+
+// AdaptiveThrottle adjusts the rate of the transport's limiter to the rate Mashery actually allows. Each call
+// rejected with ERR_403_DEVELOPER_OVER_QPS reduces the rate by DecreaseFactor and is retried after a jittered
+// exponential backoff (or after the Retry-After interval, if Mashery sends it); each successful (2xx or 3xx) call
+// increases the rate by IncreaseStep. The rate stays between MinQPS and MaxQPS; MaxQPS is further capped by
+// the X-Plan-QPS-Allotted header. Where neither MaxQPS nor the allotment is known, the rate is not raised above
+// the rate the limiter had initially. Long-running jobs thus converge on the allowed rate.
+//
+// The transport's RateLimiter must implement AdjustableRateLimiter, e.g. TokenBucketLimiter; otherwise only
+// the backoff is applied.
+type AdaptiveThrottle struct {
+	MinQPS         float64
+	MaxQPS         float64
+	IncreaseStep   float64
+	DecreaseFactor float64
+
+	MaxRetries  int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	mutex   sync.Mutex
+	allowed float64
+	initial float64
+}
+
+// NewAdaptiveThrottle creates the adaptive throttle with the rate between 1 QPS and maxQPS
+func NewAdaptiveThrottle(maxQPS float64) *AdaptiveThrottle {
+	return &AdaptiveThrottle{
+		MinQPS:         1,
+		MaxQPS:         maxQPS,
+		IncreaseStep:   0.1,
+		DecreaseFactor: 0.5,
+		MaxRetries:     10,
+		BaseBackoff:    time.Millisecond * 250,
+		MaxBackoff:     time.Second * 30,
+	}
+}
+
+// Func is the ChainedMiddlewareFunc applying the throttle. It should be placed in the pipeline after ThrottleFunc,
+// in place of BackOffOnDeveloperOverQPSFunc, so that each retry again waits for the rate limiter.
+func (at *AdaptiveThrottle) Func(ctx context.Context, c *HttpTransport, next MiddlewareFunc) (*WrappedResponse, error) {
+	var last *WrappedResponse
+	for i := 0; i <= at.MaxRetries; i++ {
+		wr, err := next(ctx, c)
+		if err != nil || wr == nil {
+			return wr, err
+		}
+
+		if wr.StatusCode != 403 || wr.Header.Get(HeaderMasheryErrorCode) != CodeDeveloperOverQPS {
+			at.onAccepted(c, wr)
+			return wr, err
+		}
+
+		last = wr
+		at.onRejected(c)
+		if i == at.MaxRetries {
+			break
+		}
+
+		delay := at.Backoff(i)
+		if ra, ok := RetryAfter(wr.Header, time.Now()); ok {
+			delay = ra
+		}
+		started := time.Now()
+		waitErr := waitFor(ctx, delay)
+		noteThrottleWait(ctx, time.Since(started))
+		if waitErr != nil {
+			return wr, waitErr
+		}
+		noteRetry(ctx)
+	}
+
+	return last, retriesExhausted(last)
+}
+
+// Backoff returns the jittered exponential backoff before the retry following the specified attempt
+func (at *AdaptiveThrottle) Backoff(attempt int) time.Duration {
+	return jitteredBackoff(at.BaseBackoff, at.MaxBackoff, attempt)
+}
+
+// ceiling the rate the limiter is not raised above: the allotted rate capped by MaxQPS, or the initial rate
+// of the limiter where neither is known
+func (at *AdaptiveThrottle) ceiling() float64 {
+	if at.allowed > 0 && (at.MaxQPS <= 0 || at.allowed < at.MaxQPS) {
+		return at.allowed
+	} else if at.MaxQPS > 0 {
+		return at.MaxQPS
+	}
+	return at.initial
+}
+
+// adjustable returns the limiter of the transport which rate can be adjusted, remembering its initial rate.
+// Should be called while holding the mutex.
+func (at *AdaptiveThrottle) adjustable(c *HttpTransport) (AdjustableRateLimiter, bool) {
+	arl, ok := c.RateLimiter.(AdjustableRateLimiter)
+	if ok && at.initial <= 0 {
+		at.initial = arl.QPS()
+	}
+	return arl, ok
+}
+
+func (at *AdaptiveThrottle) onAccepted(c *HttpTransport, wr *WrappedResponse) {
+	at.mutex.Lock()
+	defer at.mutex.Unlock()
+
+	if str := wr.Header.Get(HeaderPlanQPSAllotted); len(str) > 0 {
+		if allowed, err := strconv.ParseFloat(str, 64); err == nil && allowed > 0 {
+			at.allowed = allowed
+		}
+	}
+
+	arl, ok := at.adjustable(c)
+	if !ok || wr.StatusCode < 200 || wr.StatusCode >= 400 {
+		return
+	}
+
+	qps := arl.QPS() + at.IncreaseStep
+	if ceiling := at.ceiling(); ceiling > 0 && qps > ceiling {
+		qps = ceiling
+	}
+	arl.SetQPS(qps)
+}
+
+func (at *AdaptiveThrottle) onRejected(c *HttpTransport) {
+	at.mutex.Lock()
+	defer at.mutex.Unlock()
+
+	if arl, ok := at.adjustable(c); ok {
+		qps := arl.QPS() * at.DecreaseFactor
+		if qps < at.MinQPS {
+			qps = at.MinQPS
+		}
+		arl.SetQPS(qps)
+	}
+}
+
+// RetryAfter parses the Retry-After header, specified either in seconds or as HTTP date
+func RetryAfter(hdr http.Header, now time.Time) (time.Duration, bool) {
+	str := hdr.Get(HeaderRetryAfter)
+	if len(str) == 0 {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(str); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(str); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
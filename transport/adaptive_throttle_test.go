@@ -0,0 +1,171 @@
+package transport_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedExecutor returns the scripted responses in order, repeating the last one
+type scriptedExecutor struct {
+	responses []func() *http.Response
+	calls     int
+}
+
+func (se *scriptedExecutor) Do(_ *http.Request) (*http.Response, error) {
+	idx := se.calls
+	if idx >= len(se.responses) {
+		idx = len(se.responses) - 1
+	}
+	se.calls++
+	return se.responses[idx](), nil
+}
+
+func (se *scriptedExecutor) CloseIdleConnections() {}
+
+func respondWith(code int, hdr http.Header) func() *http.Response {
+	return func() *http.Response {
+		if hdr == nil {
+			hdr = http.Header{}
+		}
+		return &http.Response{StatusCode: code, Header: hdr, Body: io.NopCloser(bytes.NewReader([]byte("{}")))}
+	}
+}
+
+var overQPS = http.Header{transport.HeaderMasheryErrorCode: {transport.CodeDeveloperOverQPS}}
+
+func adaptiveTransport(se *scriptedExecutor, at *transport.AdaptiveThrottle, limiter transport.RateLimiter) *transport.HttpTransport {
+	return &transport.HttpTransport{
+		Mutex:        &sync.Mutex{},
+		RateLimiter:  limiter,
+		HttpExecutor: se,
+		Pipeline: transport.BuildPipeline(transport.ExecuteFunction, []transport.ChainedMiddlewareFunc{
+			transport.ThrottleFunc,
+			at.Func,
+		}),
+	}
+}
+
+func fetchOnce(c *transport.HttpTransport) (bool, error) {
+	spec := transport.ObjectFetchSpecBuilder[map[string]interface{}]{}
+	spec.WithValueFactory(func() map[string]interface{} { return map[string]interface{}{} }).WithResource("/x")
+
+	_, exists, err := transport.GetObject(context.Background(), spec.Build(), c)
+	return exists, err
+}
+
+func TestAdaptiveThrottleLowersRateOnOverQPS(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{
+		respondWith(403, overQPS),
+		respondWith(403, overQPS),
+		respondWith(200, nil),
+	}}
+	at := transport.NewAdaptiveThrottle(8)
+	at.BaseBackoff = time.Millisecond
+	limiter := transport.NewTokenBucketLimiter(8, 100)
+
+	exists, err := fetchOnce(adaptiveTransport(se, at, limiter))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, 3, se.calls)
+
+	// Halved twice, then increased once
+	assert.InDelta(t, 2.1, limiter.QPS(), 0.0001)
+}
+
+func TestAdaptiveThrottleRaisesRateUpToAllotted(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{
+		respondWith(200, http.Header{http.CanonicalHeaderKey(transport.HeaderPlanQPSAllotted): {"3"}}),
+	}}
+	at := transport.NewAdaptiveThrottle(10)
+	at.IncreaseStep = 1
+	limiter := transport.NewTokenBucketLimiter(1, 100)
+	c := adaptiveTransport(se, at, limiter)
+
+	for i := 0; i < 5; i++ {
+		_, err := fetchOnce(c)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, float64(3), limiter.QPS())
+}
+
+func TestAdaptiveThrottleDoesNotRaiseRateOnFailures(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{
+		respondWith(500, nil),
+		respondWith(404, nil),
+	}}
+	at := transport.NewAdaptiveThrottle(8)
+	limiter := transport.NewTokenBucketLimiter(4, 100)
+	c := adaptiveTransport(se, at, limiter)
+
+	_, _ = fetchOnce(c)
+	_, _ = fetchOnce(c)
+	assert.Equal(t, 2, se.calls)
+	assert.Equal(t, float64(4), limiter.QPS())
+}
+
+func TestAdaptiveThrottleKeepsInitialRateWithoutKnownMaximum(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{
+		respondWith(403, overQPS),
+		respondWith(200, nil),
+	}}
+	at := transport.NewAdaptiveThrottle(0)
+	at.BaseBackoff = time.Millisecond
+	at.IncreaseStep = 1
+	limiter := transport.NewTokenBucketLimiter(4, 100)
+	c := adaptiveTransport(se, at, limiter)
+
+	for i := 0; i < 5; i++ {
+		_, err := fetchOnce(c)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, float64(4), limiter.QPS())
+}
+
+func TestAdaptiveThrottleGivesUpAfterMaxRetries(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{respondWith(403, overQPS)}}
+	at := transport.NewAdaptiveThrottle(4)
+	at.BaseBackoff = time.Millisecond
+	at.MaxRetries = 3
+	limiter := transport.NewTokenBucketLimiter(4, 100)
+
+	_, err := fetchOnce(adaptiveTransport(se, at, limiter))
+	assert.NotNil(t, err)
+	assert.Equal(t, 4, se.calls)
+	assert.Equal(t, float64(1), limiter.QPS())
+}
+
+func TestAdaptiveThrottleBackoffIsJitteredAndBounded(t *testing.T) {
+	at := transport.NewAdaptiveThrottle(4)
+	at.BaseBackoff = time.Second
+	at.MaxBackoff = time.Second * 5
+
+	for i := 0; i < 20; i++ {
+		d := at.Backoff(1)
+		assert.True(t, d >= time.Second && d <= time.Second*2, "unexpected backoff %s", d)
+
+		d = at.Backoff(10)
+		assert.True(t, d >= time.Millisecond*2500 && d <= time.Second*5, "unexpected backoff %s", d)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	d, ok := transport.RetryAfter(http.Header{"Retry-After": {"7"}}, now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Second*7, d)
+
+	d, ok = transport.RetryAfter(http.Header{"Retry-After": {"Mon, 01 Jan 2024 10:00:30 GMT"}}, now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Second*30, d)
+
+	_, ok = transport.RetryAfter(http.Header{}, now)
+	assert.False(t, ok)
+}
@@ -0,0 +1,31 @@
+package transport
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+var jitterMutex = sync.Mutex{}
+
+// jitteredBackoff returns the exponential backoff before the retry following the specified attempt (counted from
+// zero), capped at max: a random duration between half and the full exponential delay.
+func jitteredBackoff(base time.Duration, max time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 0; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	if d <= 0 {
+		return 0
+	}
+
+	jitterMutex.Lock()
+	defer jitterMutex.Unlock()
+
+	half := d / 2
+	return half + time.Duration(jitterRand.Int63n(int64(d-half)+1))
+}
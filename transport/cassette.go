@@ -0,0 +1,370 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// RedactedValue the value the secrets are replaced with in the cassettes
+const RedactedValue = "REDACTED"
+
+// RecordedRequest the request of the recorded exchange
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse the response of the recorded exchange
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Interaction the recorded exchange with Mashery
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// Cassette the sequence of the recorded exchanges
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// LoadCassette reads the cassette from the file
+func LoadCassette(path string) (*Cassette, error) {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := Cassette{}
+	if err = json.Unmarshal(dat, &rv); err != nil {
+		return nil, errors.New(fmt.Sprintf("cassette %s is malformed: %s", path, err.Error()))
+	}
+	return &rv, nil
+}
+
+// Save writes the cassette to the file, which only the current user can read
+func (c *Cassette) Save(path string) error {
+	dat, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, dat, 0600)
+}
+
+// Redaction the secrets removed from the recorded exchanges: the values of the headers, the query parameters
+// and the fields of JSON or form-encoded bodies with the specified names are replaced with RedactedValue.
+// The names are matched case-insensitively.
+type Redaction struct {
+	Headers     []string
+	QueryParams []string
+	Fields      []string
+}
+
+// DefaultRedaction removes the authorization headers, the API keys and signatures, the secrets of the package keys,
+// the tokens and the passwords.
+func DefaultRedaction() Redaction {
+	return Redaction{
+		Headers:     []string{"Authorization", "Proxy-Authorization", "Set-Cookie", "Cookie"},
+		QueryParams: []string{"api_key", "apikey", "sig"},
+		Fields: []string{
+			"secret", "password", "passwdNew", "client_secret", "access_token", "refresh_token",
+			"systemDomainCredentialSecret",
+		},
+	}
+}
+
+func matchesAny(name string, names []string) bool {
+	for _, n := range names {
+		if strings.EqualFold(name, n) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Redaction) header(hdr http.Header) http.Header {
+	if len(hdr) == 0 {
+		return nil
+	}
+
+	rv := hdr.Clone()
+	// The length of the redacted body differs from the original one.
+	rv.Del("Content-Length")
+	for k := range rv {
+		if matchesAny(k, r.Headers) {
+			rv[k] = []string{RedactedValue}
+		}
+	}
+	return rv
+}
+
+func (r Redaction) query(qs url.Values) url.Values {
+	for k := range qs {
+		if matchesAny(k, r.QueryParams) {
+			qs[k] = []string{RedactedValue}
+		}
+	}
+	return qs
+}
+
+func (r Redaction) url(u *url.URL) string {
+	rv := *u
+	rv.RawQuery = r.query(u.Query()).Encode()
+	return rv.String()
+}
+
+func (r Redaction) jsonValue(v interface{}) interface{} {
+	switch typed := v.(type) {
+	case map[string]interface{}:
+		for k, fv := range typed {
+			if matchesAny(k, r.Fields) {
+				typed[k] = RedactedValue
+			} else {
+				typed[k] = r.jsonValue(fv)
+			}
+		}
+	case []interface{}:
+		for i, ev := range typed {
+			typed[i] = r.jsonValue(ev)
+		}
+	}
+	return v
+}
+
+// body redacts the body and brings it to the normal form: JSON is re-encoded with sorted keys, and the form
+// parameters are sorted by name.
+func (r Redaction) body(dat []byte, contentType string) string {
+	if len(bytes.TrimSpace(dat)) == 0 {
+		return ""
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(dat, &v); err == nil {
+		if rv, err := json.Marshal(r.jsonValue(v)); err == nil {
+			return string(rv)
+		}
+	}
+
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		if qs, err := url.ParseQuery(string(dat)); err == nil {
+			for k := range qs {
+				if matchesAny(k, r.Fields) {
+					qs[k] = []string{RedactedValue}
+				}
+			}
+			return qs.Encode()
+		}
+	}
+
+	return string(dat)
+}
+
+// readRequestBody reads the body of the request, leaving it readable for the executor
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	dat, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(dat))
+	return dat, err
+}
+
+// recordRequest the redacted, normal form of the request
+func (r Redaction) recordRequest(req *http.Request) (RecordedRequest, error) {
+	dat, err := readRequestBody(req)
+	if err != nil {
+		return RecordedRequest{}, err
+	}
+
+	return RecordedRequest{
+		Method: req.Method,
+		URL:    r.url(req.URL),
+		Header: r.header(req.Header),
+		Body:   r.body(dat, req.Header.Get("Content-Type")),
+	}, nil
+}
+
+// RecordingExecutor HttpExecutor passing the requests to the Delegate and recording the exchanges, with the secrets
+// redacted, into the cassette. Save writes the recorded cassette to the file; the recorded scenario can then be
+// replayed with ReplayExecutor.
+type RecordingExecutor struct {
+	Delegate  HttpExecutor
+	Redaction Redaction
+
+	mutex    sync.Mutex
+	cassette Cassette
+}
+
+// NewRecordingExecutor creates the executor recording the exchanges made with the delegate with DefaultRedaction
+func NewRecordingExecutor(delegate HttpExecutor) *RecordingExecutor {
+	return &RecordingExecutor{
+		Delegate:  delegate,
+		Redaction: DefaultRedaction(),
+	}
+}
+
+func (re *RecordingExecutor) Do(req *http.Request) (*http.Response, error) {
+	recReq, err := re.Redaction.recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := re.Delegate.Do(req)
+	if err != nil {
+		return resp, err
+	}
+
+	dat, err := ReadResponseBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(dat))
+
+	re.mutex.Lock()
+	defer re.mutex.Unlock()
+
+	re.cassette.Interactions = append(re.cassette.Interactions, Interaction{
+		Request: recReq,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     re.Redaction.header(resp.Header),
+			Body:       re.Redaction.body(dat, resp.Header.Get("Content-Type")),
+		},
+	})
+
+	return resp, nil
+}
+
+func (re *RecordingExecutor) CloseIdleConnections() {
+	re.Delegate.CloseIdleConnections()
+}
+
+// Cassette returns the exchanges recorded so far
+func (re *RecordingExecutor) Cassette() *Cassette {
+	re.mutex.Lock()
+	defer re.mutex.Unlock()
+
+	rv := Cassette{Interactions: make([]Interaction, len(re.cassette.Interactions))}
+	copy(rv.Interactions, re.cassette.Interactions)
+	return &rv
+}
+
+// Save writes the exchanges recorded so far to the cassette file
+func (re *RecordingExecutor) Save(path string) error {
+	return re.Cassette().Save(path)
+}
+
+// ReplayExecutor HttpExecutor serving the responses recorded in the cassette. The request is matched with
+// the recorded one by the method, the path, the query and the body, after these are redacted and brought
+// to the normal form; the host of the URL is not compared, so that the scenario recorded against a sandbox
+// can be replayed against any endpoint. Each recorded exchange is replayed once, in the recorded order.
+type ReplayExecutor struct {
+	Cassette  *Cassette
+	Redaction Redaction
+
+	mutex    sync.Mutex
+	replayed []bool
+}
+
+// NewReplayExecutor creates the executor replaying the cassette recorded with DefaultRedaction
+func NewReplayExecutor(c *Cassette) *ReplayExecutor {
+	return &ReplayExecutor{
+		Cassette:  c,
+		Redaction: DefaultRedaction(),
+	}
+}
+
+// sizeReplayed sizes the replay marks to the cassette, also where the executor was not created with
+// NewReplayExecutor. Should be called while holding the mutex.
+func (rp *ReplayExecutor) sizeReplayed() {
+	if rp.Cassette != nil && len(rp.replayed) < len(rp.Cassette.Interactions) {
+		rp.replayed = append(rp.replayed, make([]bool, len(rp.Cassette.Interactions)-len(rp.replayed))...)
+	}
+}
+
+// LoadReplayExecutor creates the executor replaying the cassette file
+func LoadReplayExecutor(path string) (*ReplayExecutor, error) {
+	if c, err := LoadCassette(path); err != nil {
+		return nil, err
+	} else {
+		return NewReplayExecutor(c), nil
+	}
+}
+
+func sameRequest(a, b RecordedRequest) bool {
+	ua, errA := url.Parse(a.URL)
+	ub, errB := url.Parse(b.URL)
+	if errA != nil || errB != nil {
+		return false
+	}
+
+	return a.Method == b.Method &&
+		ua.Path == ub.Path &&
+		ua.Query().Encode() == ub.Query().Encode() &&
+		a.Body == b.Body
+}
+
+func (rp *ReplayExecutor) Do(req *http.Request) (*http.Response, error) {
+	recReq, err := rp.Redaction.recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	rp.mutex.Lock()
+	defer rp.mutex.Unlock()
+
+	rp.sizeReplayed()
+	for i, inter := range rp.Cassette.Interactions {
+		if rp.replayed[i] || !sameRequest(recReq, inter.Request) {
+			continue
+		}
+
+		rp.replayed[i] = true
+		hdr := inter.Response.Header.Clone()
+		if hdr == nil {
+			hdr = http.Header{}
+		}
+		return &http.Response{
+			Status:     http.StatusText(inter.Response.StatusCode),
+			StatusCode: inter.Response.StatusCode,
+			Header:     hdr,
+			Body:       io.NopCloser(strings.NewReader(inter.Response.Body)),
+			Request:    req,
+		}, nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("no recorded exchange matches %s %s", req.Method, recReq.URL))
+}
+
+func (rp *ReplayExecutor) CloseIdleConnections() {}
+
+// Remaining returns the number of the recorded exchanges that were not replayed yet
+func (rp *ReplayExecutor) Remaining() int {
+	rp.mutex.Lock()
+	defer rp.mutex.Unlock()
+
+	rp.sizeReplayed()
+	rv := 0
+	for _, r := range rp.replayed {
+		if !r {
+			rv++
+		}
+	}
+	return rv
+}
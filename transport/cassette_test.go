@@ -0,0 +1,167 @@
+package transport_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// keyServer responds with the package key carrying the secret
+type keyServer struct {
+	calls int
+}
+
+func (ks *keyServer) Do(req *http.Request) (*http.Response, error) {
+	ks.calls++
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+	}
+	if req.Method == http.MethodPut && !strings.Contains(string(body), `"status"`) {
+		return &http.Response{StatusCode: 400, Header: http.Header{}, Body: io.NopCloser(bytes.NewReader(nil))}, nil
+	}
+
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"id":"k","apikey":"key","secret":"s3cr3t","status":"active"}`)),
+	}, nil
+}
+
+func (ks *keyServer) CloseIdleConnections() {}
+
+func cassetteTransport(endpoint string, exec transport.HttpExecutor) *transport.HttpTransport {
+	return &transport.HttpTransport{
+		MashEndpoint: endpoint,
+		Mutex:        &sync.Mutex{},
+		RateLimiter:  transport.NewTokenBucketLimiter(1000, 1000),
+		HttpExecutor: exec,
+		Pipeline: transport.BuildPipeline(transport.ExecuteFunction, []transport.ChainedMiddlewareFunc{
+			transport.ThrottleFunc,
+		}),
+	}
+}
+
+func exchange(t *testing.T, c *transport.HttpTransport) {
+	req, _ := http.NewRequest(http.MethodGet, c.MashEndpoint+"/packageKeys/k?fields=id,secret&api_key=xyz", nil)
+	req.Header.Set("Authorization", "Bearer tkn")
+	resp, err := c.HttpExecutor.Do(req)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	wr, err := c.Put(context.Background(), "/packageKeys/k", map[string]string{"status": "active", "secret": "n3w"})
+	assert.Nil(t, err)
+	assert.Equal(t, 200, wr.StatusCode)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	ks := &keyServer{}
+	rec := transport.NewRecordingExecutor(ks)
+	exchange(t, cassetteTransport("https://sandbox.example.com/v3/rest", rec))
+	assert.Equal(t, 2, ks.calls)
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	assert.Nil(t, rec.Save(path))
+
+	stat, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+
+	dat, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	for _, secret := range []string{"Bearer tkn", "xyz", "s3cr3t", "n3w"} {
+		assert.False(t, strings.Contains(string(dat), secret), secret)
+	}
+
+	replay, err := transport.LoadReplayExecutor(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, replay.Remaining())
+
+	exchange(t, cassetteTransport("http://localhost/v3/rest", replay))
+	assert.Equal(t, 0, replay.Remaining())
+
+	// Each exchange is replayed once only.
+	_, err = replay.Do(httptestRequest(http.MethodGet, "http://localhost/v3/rest/packageKeys/k?api_key=xyz&fields=id,secret"))
+	assert.NotNil(t, err)
+}
+
+func TestReplayMatchesNormalisedRequests(t *testing.T) {
+	replay := transport.NewReplayExecutor(&transport.Cassette{Interactions: []transport.Interaction{
+		{
+			Request:  transport.RecordedRequest{Method: "POST", URL: "https://sandbox/services?a=1&b=2", Body: `{"a":1,"b":2}`},
+			Response: transport.RecordedResponse{StatusCode: 200, Body: `{"id":"s"}`},
+		},
+	}})
+
+	req, _ := http.NewRequest("POST", "http://localhost/services?b=2&a=1", strings.NewReader(`{ "b": 2, "a": 1 }`))
+	resp, err := replay.Do(req)
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, `{"id":"s"}`, string(body))
+
+	req, _ = http.NewRequest("POST", "http://localhost/services?b=2&a=1", strings.NewReader(`{"a":2}`))
+	_, err = replay.Do(req)
+	assert.NotNil(t, err)
+}
+
+func httptestRequest(method, uri string) *http.Request {
+	req, _ := http.NewRequest(method, uri, nil)
+	return req
+}
+
+func TestReplayExecutorCreatedAsLiteral(t *testing.T) {
+	replay := &transport.ReplayExecutor{Cassette: &transport.Cassette{Interactions: []transport.Interaction{
+		{
+			Request:  transport.RecordedRequest{Method: http.MethodGet, URL: "https://sandbox.example.com/v3/rest/services"},
+			Response: transport.RecordedResponse{StatusCode: 200, Body: "[]"},
+		},
+	}}}
+	assert.Equal(t, 1, replay.Remaining())
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.example.com/v3/rest/services", nil)
+	resp, err := replay.Do(req)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, 0, replay.Remaining())
+}
+
+// lengthServer echoes the request body, stating its length
+type lengthServer struct{}
+
+func (ls lengthServer) Do(req *http.Request) (*http.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": {"application/json"}, "Content-Length": {strconv.Itoa(len(body))}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}, nil
+}
+
+func (ls lengthServer) CloseIdleConnections() {}
+
+func TestRecordingDropsContentLength(t *testing.T) {
+	rec := transport.NewRecordingExecutor(lengthServer{})
+
+	body := `{"secret":"s3cr3t"}`
+	req, _ := http.NewRequest(http.MethodPut, "https://sandbox.example.com/v3/rest/packageKeys/k", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	_, err := rec.Do(req)
+	assert.Nil(t, err)
+
+	recorded := rec.Cassette().Interactions
+	assert.Equal(t, 1, len(recorded))
+	assert.Equal(t, "", recorded[0].Request.Header.Get("Content-Length"))
+	assert.Equal(t, "", recorded[0].Response.Header.Get("Content-Length"))
+	assert.Equal(t, "application/json", recorded[0].Response.Header.Get("Content-Type"))
+	assert.False(t, strings.Contains(recorded[0].Response.Body, "s3cr3t"))
+}
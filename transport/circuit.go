@@ -0,0 +1,282 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResourceFamilyKey context key holding the resource family of the call, set for the calls made via
+// the generic object functions of this package.
+const ResourceFamilyKey = ".resource.family"
+
+// ResourceFamilyOf returns the family of the resource: the names of the collections on the resource path without
+// the object identifiers, e.g. `services/endpoints` for `/services/abc/endpoints/def`.
+func ResourceFamilyOf(resource string) string {
+	if idx := strings.IndexByte(resource, '?'); idx >= 0 {
+		resource = resource[:idx]
+	}
+
+	var rv []string
+	for i, seg := range strings.Split(strings.Trim(resource, "/"), "/") {
+		if i%2 == 0 && len(seg) > 0 {
+			rv = append(rv, seg)
+		}
+	}
+	return strings.Join(rv, "/")
+}
+
+// ResourceFamily returns the resource family of the call
+func ResourceFamily(ctx context.Context) string {
+	if v := ctx.Value(ResourceFamilyKey); v != nil {
+		if str, ok := v.(string); ok {
+			return str
+		}
+	}
+	return ""
+}
+
+// CircuitState the state of the circuit
+type CircuitState int
+
+const (
+	// CircuitClosed calls are sent to Mashery
+	CircuitClosed CircuitState = iota
+	// CircuitOpen calls fail immediately with CircuitOpenError
+	CircuitOpen
+	// CircuitHalfOpen a single probe call is sent to Mashery to determine whether the API has recovered
+	CircuitHalfOpen
+)
+
+func (cs CircuitState) String() string {
+	switch cs {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(cs))
+	}
+}
+
+// CircuitOpenError the call was not made because the circuit of its resource family is open. The error
+// matches ErrCircuitOpen.
+type CircuitOpenError struct {
+	Family    string
+	OpenUntil time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit for %s is open until %s", e.Family, e.OpenUntil.Format(time.RFC3339))
+}
+
+func (e *CircuitOpenError) Unwrap() error {
+	return ErrCircuitOpen
+}
+
+type callOutcome struct {
+	at     time.Time
+	failed bool
+}
+
+type circuit struct {
+	state     CircuitState
+	outcomes  []callOutcome
+	openUntil time.Time
+	probing   bool
+}
+
+// CircuitBreaker stops sending calls to the resource family where the failure rate over the Window exceeds
+// FailureRate. The open circuit fails the calls immediately for the Cooldown period; a single probe call is then
+// made and closes the circuit if it succeeds. By default, the transient failures (network errors, 5xx responses)
+// are counted as failures.
+//
+// The breaker should be the last in the pipeline, so that the retries of the call are counted as a single call.
+type CircuitBreaker struct {
+	Window      time.Duration
+	MinCalls    int
+	FailureRate float64
+	Cooldown    time.Duration
+
+	// IsFailure determines whether the outcome of the call counts as failure
+	IsFailure func(wr *WrappedResponse, err error) bool
+	// OnStateChange receives the notification of the state changes. It is called while the breaker is locked
+	// and must not call the breaker.
+	OnStateChange func(family string, from, to CircuitState)
+
+	mutex    sync.Mutex
+	circuits map[string]*circuit
+	now      func() time.Time
+}
+
+// NewCircuitBreaker creates the breaker opening the circuit when more than half of at least 10 calls over
+// the last minute have failed, and probing the API after 30 seconds.
+func NewCircuitBreaker() *CircuitBreaker {
+	return &CircuitBreaker{
+		Window:      time.Minute,
+		MinCalls:    10,
+		FailureRate: 0.5,
+		Cooldown:    time.Second * 30,
+	}
+}
+
+// IsTransientFailure the default failure predicate
+func IsTransientFailure(_ *WrappedResponse, err error) bool {
+	return errors.Is(err, ErrTransient)
+}
+
+func (cb *CircuitBreaker) clock() time.Time {
+	if cb.now != nil {
+		return cb.now()
+	}
+	return time.Now()
+}
+
+func (cb *CircuitBreaker) circuitFor(family string) *circuit {
+	if cb.circuits == nil {
+		cb.circuits = map[string]*circuit{}
+	}
+	rv, ok := cb.circuits[family]
+	if !ok {
+		rv = &circuit{}
+		cb.circuits[family] = rv
+	}
+	return rv
+}
+
+func (cb *CircuitBreaker) transition(family string, cr *circuit, to CircuitState) {
+	from := cr.state
+	if from == to {
+		return
+	}
+	cr.state = to
+	if cb.OnStateChange != nil {
+		cb.OnStateChange(family, from, to)
+	}
+}
+
+// State returns the current state of the circuit of the resource family
+func (cb *CircuitBreaker) State(family string) CircuitState {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cr := cb.circuitFor(family)
+	if cr.state == CircuitOpen && !cb.clock().Before(cr.openUntil) {
+		cb.transition(family, cr, CircuitHalfOpen)
+	}
+	return cr.state
+}
+
+// acquire checks whether the call can be made. The returned flag indicates the call is the probe.
+func (cb *CircuitBreaker) acquire(family string) (bool, error) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cr := cb.circuitFor(family)
+	now := cb.clock()
+
+	if cr.state == CircuitOpen && !now.Before(cr.openUntil) {
+		cb.transition(family, cr, CircuitHalfOpen)
+	}
+
+	switch cr.state {
+	case CircuitOpen:
+		return false, &CircuitOpenError{Family: family, OpenUntil: cr.openUntil}
+	case CircuitHalfOpen:
+		if cr.probing {
+			return false, &CircuitOpenError{Family: family, OpenUntil: cr.openUntil}
+		}
+		cr.probing = true
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func (cb *CircuitBreaker) record(family string, probe bool, failed bool) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cr := cb.circuitFor(family)
+	now := cb.clock()
+
+	if probe {
+		cr.probing = false
+		cr.outcomes = nil
+		if failed {
+			cr.openUntil = now.Add(cb.Cooldown)
+			cb.transition(family, cr, CircuitOpen)
+		} else {
+			cb.transition(family, cr, CircuitClosed)
+		}
+		return
+	}
+
+	if cr.state != CircuitClosed {
+		return
+	}
+
+	cr.outcomes = append(cr.outcomes, callOutcome{at: now, failed: failed})
+
+	cutoff := now.Add(-cb.Window)
+	first := 0
+	for first < len(cr.outcomes) && cr.outcomes[first].at.Before(cutoff) {
+		first++
+	}
+	cr.outcomes = cr.outcomes[first:]
+
+	if len(cr.outcomes) < cb.MinCalls || len(cr.outcomes) == 0 {
+		return
+	}
+
+	failures := 0
+	for _, o := range cr.outcomes {
+		if o.failed {
+			failures++
+		}
+	}
+	if float64(failures)/float64(len(cr.outcomes)) > cb.FailureRate {
+		cr.outcomes = nil
+		cr.openUntil = now.Add(cb.Cooldown)
+		cb.transition(family, cr, CircuitOpen)
+	}
+}
+
+// Func is the ChainedMiddlewareFunc applying the breaker
+func (cb *CircuitBreaker) Func(ctx context.Context, c *HttpTransport, next MiddlewareFunc) (*WrappedResponse, error) {
+	family := ResourceFamily(ctx)
+
+	probe, err := cb.acquire(family)
+	if err != nil {
+		return nil, err
+	}
+
+	wr, err := next(ctx, c)
+
+	isFailure := cb.IsFailure
+	if isFailure == nil {
+		isFailure = IsTransientFailure
+	}
+	// Calls abandoned by the caller say nothing about the health of the API
+	if ctx.Err() != nil {
+		if probe {
+			cb.releaseProbe(family)
+		}
+		return wr, err
+	}
+	cb.record(family, probe, isFailure(wr, err))
+
+	return wr, err
+}
+
+func (cb *CircuitBreaker) releaseProbe(family string) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.circuitFor(family).probing = false
+}
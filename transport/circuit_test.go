@@ -0,0 +1,123 @@
+package transport_test
+
+import (
+	"errors"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func circuitTransport(se *scriptedExecutor, cb *transport.CircuitBreaker) *transport.HttpTransport {
+	return &transport.HttpTransport{
+		Mutex:        &sync.Mutex{},
+		RateLimiter:  transport.NewTokenBucketLimiter(1000, 1000),
+		HttpExecutor: se,
+		Pipeline: transport.BuildPipeline(transport.ExecuteFunction, []transport.ChainedMiddlewareFunc{
+			transport.ThrottleFunc,
+			transport.UnmarshalServerError,
+			cb.Func,
+		}),
+	}
+}
+
+type stateChange struct {
+	family   string
+	from, to transport.CircuitState
+}
+
+func TestResourceFamilyOf(t *testing.T) {
+	assert.Equal(t, "services", transport.ResourceFamilyOf("/services"))
+	assert.Equal(t, "services", transport.ResourceFamilyOf("/services/abc"))
+	assert.Equal(t, "services/endpoints", transport.ResourceFamilyOf("/services/abc/endpoints/def?fields=id"))
+	assert.Equal(t, "", transport.ResourceFamilyOf("/"))
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{
+		respondWith(503, nil),
+		respondWith(503, nil),
+		respondWith(503, nil),
+		respondWith(200, nil),
+	}}
+
+	var changes []stateChange
+	cb := &transport.CircuitBreaker{
+		Window:      time.Minute,
+		MinCalls:    3,
+		FailureRate: 0.5,
+		Cooldown:    time.Millisecond * 50,
+		OnStateChange: func(family string, from, to transport.CircuitState) {
+			changes = append(changes, stateChange{family, from, to})
+		},
+	}
+	c := circuitTransport(se, cb)
+
+	for i := 0; i < 3; i++ {
+		_, err := fetchOnce(c)
+		assert.True(t, errors.Is(err, transport.ErrTransient))
+	}
+	assert.Equal(t, transport.CircuitOpen, cb.State("x"))
+	assert.Equal(t, transport.CircuitClosed, cb.State("services"))
+
+	_, err := fetchOnce(c)
+	assert.True(t, errors.Is(err, transport.ErrCircuitOpen))
+	var coErr *transport.CircuitOpenError
+	assert.True(t, errors.As(err, &coErr))
+	assert.Equal(t, "x", coErr.Family)
+	assert.Equal(t, 3, se.calls)
+
+	time.Sleep(time.Millisecond * 60)
+	exists, err := fetchOnce(c)
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, transport.CircuitClosed, cb.State("x"))
+
+	assert.Equal(t, []stateChange{
+		{"x", transport.CircuitClosed, transport.CircuitOpen},
+		{"x", transport.CircuitOpen, transport.CircuitHalfOpen},
+		{"x", transport.CircuitHalfOpen, transport.CircuitClosed},
+	}, changes)
+}
+
+func TestCircuitBreakerReopensOnFailedProbe(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{
+		respondWith(502, nil),
+	}}
+	cb := &transport.CircuitBreaker{
+		Window:      time.Minute,
+		MinCalls:    1,
+		FailureRate: 0.5,
+		Cooldown:    time.Millisecond * 20,
+	}
+	c := circuitTransport(se, cb)
+
+	_, _ = fetchOnce(c)
+	assert.Equal(t, transport.CircuitOpen, cb.State("x"))
+
+	time.Sleep(time.Millisecond * 30)
+	assert.Equal(t, transport.CircuitHalfOpen, cb.State("x"))
+
+	_, err := fetchOnce(c)
+	assert.True(t, errors.Is(err, transport.ErrTransient))
+	assert.Equal(t, transport.CircuitOpen, cb.State("x"))
+	assert.Equal(t, 2, se.calls)
+}
+
+func TestCircuitBreakerIgnoresNonTransientFailures(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{
+		respondWith(400, nil),
+	}}
+	cb := transport.NewCircuitBreaker()
+	cb.MinCalls = 2
+	c := circuitTransport(se, cb)
+
+	for i := 0; i < 5; i++ {
+		_, err := fetchOnce(c)
+		assert.True(t, errors.Is(err, transport.ErrValidation))
+	}
+	assert.Equal(t, transport.CircuitClosed, cb.State("x"))
+	assert.Equal(t, 5, se.calls)
+}
@@ -0,0 +1,174 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"net/http"
+	"strings"
+)
+
+// Categories of the failures. V3Error matches the category of the failure with errors.Is, e.g.
+// errors.Is(err, transport.ErrNotFound).
+var (
+	ErrNotFound          = errors.New("object not found")
+	ErrConflict          = errors.New("conflicting modification")
+	ErrValidation        = errors.New("request is not valid")
+	ErrUnauthorized      = errors.New("access token is invalid or has expired")
+	ErrForbidden         = errors.New("operation is not permitted")
+	ErrThrottled         = errors.New("call rate limit exceeded")
+	ErrTransient         = errors.New("transient failure")
+	ErrRetriesExhausted  = errors.New("operation unsuccessful after all available retries")
+	ErrInvalidIdentifier = errors.New("object identifier is not valid")
+	ErrCircuitOpen       = errors.New("circuit is open")
+)
+
+// V3Error a failure of the call to Mashery V3 API. Kind is one of the failure categories above; Cause is the
+// error reported by Mashery (V3GenericErrorResponse, V3PropertyErrorMessages or V3UndeterminedError) or the
+// underlying network error. Both are accessible with errors.Is and errors.As.
+type V3Error struct {
+	StatusCode int
+	// ErrorCode Mashery error code, from the X-Mashery-Error-Code header or the response body
+	ErrorCode string
+	Method    string
+	URL       string
+	Message   string
+	// Properties the validation messages Mashery has returned for individual properties
+	Properties []masherytypes.V3PropertyErrorMessage
+
+	Kind  error
+	Cause error
+}
+
+func (e *V3Error) Error() string {
+	sb := strings.Builder{}
+	if len(e.Method) > 0 || len(e.URL) > 0 {
+		sb.WriteString(strings.TrimSpace(e.Method + " " + e.URL))
+		sb.WriteString(": ")
+	}
+
+	if len(e.Message) > 0 {
+		sb.WriteString(e.Message)
+	}
+	if e.Cause != nil {
+		if len(e.Message) > 0 {
+			sb.WriteString(": ")
+		}
+		sb.WriteString(e.Cause.Error())
+	} else if len(e.Message) == 0 && e.Kind != nil {
+		sb.WriteString(e.Kind.Error())
+	}
+
+	return sb.String()
+}
+
+func (e *V3Error) Unwrap() []error {
+	var rv []error
+	if e.Kind != nil {
+		rv = append(rv, e.Kind)
+	}
+	if e.Cause != nil {
+		rv = append(rv, e.Cause)
+	}
+	return rv
+}
+
+// Temporary checks whether the call can be expected to succeed if repeated later
+func (e *V3Error) Temporary() bool {
+	return e.Kind == ErrTransient || e.Kind == ErrThrottled
+}
+
+func (e *V3Error) withRequest(wr *WrappedResponse) *V3Error {
+	if wr != nil && wr.Request != nil && wr.Request.Request != nil {
+		e.Method = wr.Request.Request.Method
+		e.URL = wr.Request.Request.URL.String()
+	}
+	return e
+}
+
+// CodeNotAuthorized Mashery error code of the call rejected with 403 because the access token is missing, invalid,
+// expired or revoked
+const CodeNotAuthorized = "ERR_403_NOT_AUTHORIZED"
+
+// kindOfStatus classifies the failure by the status code and Mashery error code
+func kindOfStatus(code int, errorCode string, hdr http.Header) error {
+	switch {
+	case code == 400:
+		return ErrValidation
+	case code == 401:
+		return ErrUnauthorized
+	case code == 403 && (errorCode == CodeDeveloperOverQPS || errorCode == CodeDeveloperOverRate):
+		return ErrThrottled
+	case code == 403 && (errorCode == CodeNotAuthorized || strings.Contains(hdr.Get("WWW-Authenticate"), "invalid_token")):
+		return ErrUnauthorized
+	case code == 403:
+		return ErrForbidden
+	case code == 404:
+		return ErrNotFound
+	case code == 409:
+		return ErrConflict
+	case code == 429:
+		return ErrThrottled
+	case code >= 500:
+		return ErrTransient
+	default:
+		return nil
+	}
+}
+
+// ResponseError builds the V3Error describing the unsuccessful response
+func ResponseError(wr *WrappedResponse) *V3Error {
+	rv := &V3Error{
+		StatusCode: wr.StatusCode,
+		ErrorCode:  wr.Header.Get(HeaderMasheryErrorCode),
+	}
+	rv.withRequest(wr)
+
+	body := wr.MustBody()
+
+	var generic masherytypes.V3GenericErrorResponse
+	var propRv masherytypes.V3PropertyErrorMessages
+
+	if err := json.Unmarshal(body, &generic); err == nil && generic.HasData() {
+		rv.Cause = &generic
+		if len(rv.ErrorCode) == 0 {
+			rv.ErrorCode = generic.ErrorCode
+		}
+	} else if err = json.Unmarshal(body, &propRv); err == nil && len(propRv.Errors) > 0 {
+		rv.Cause = &propRv
+		rv.Properties = propRv.Errors
+	} else {
+		rv.Cause = &masherytypes.V3UndeterminedError{
+			Code:   wr.StatusCode,
+			Header: wr.Header,
+			Body:   body,
+		}
+	}
+
+	rv.Kind = kindOfStatus(wr.StatusCode, rv.ErrorCode, wr.Header)
+	return rv
+}
+
+// callError wraps the error of the call that has not produced the response
+func callError(ctx context.Context, wrq *WrappedRequest, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var v3 *V3Error
+	if errors.As(err, &v3) {
+		return err
+	}
+
+	rv := &V3Error{Cause: err}
+	if wrq != nil && wrq.Request != nil {
+		rv.Method = wrq.Request.Method
+		rv.URL = wrq.Request.URL.String()
+	}
+	// Cancellations requested by the caller are not transient failures
+	if ctx.Err() == nil {
+		rv.Kind = ErrTransient
+	}
+	return rv
+}
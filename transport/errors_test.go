@@ -0,0 +1,127 @@
+package transport_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func responseTo(method string, code int, hdr http.Header, body string) *transport.WrappedResponse {
+	req, _ := http.NewRequest(method, "https://api.mashery.com/v3/rest/services", nil)
+	if hdr == nil {
+		hdr = http.Header{}
+	}
+	return &transport.WrappedResponse{
+		Request:    &transport.WrappedRequest{Request: req},
+		Response:   &http.Response{StatusCode: code, Header: hdr, Body: io.NopCloser(bytes.NewReader([]byte(body)))},
+		StatusCode: code,
+		Header:     hdr,
+	}
+}
+
+func TestResponseErrorClassification(t *testing.T) {
+	tokenHdr := http.Header{}
+	tokenHdr.Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+
+	cases := []struct {
+		code int
+		hdr  http.Header
+		kind error
+	}{
+		{400, nil, transport.ErrValidation},
+		{401, nil, transport.ErrUnauthorized},
+		{403, tokenHdr, transport.ErrUnauthorized},
+		{403, http.Header{transport.HeaderMasheryErrorCode: {transport.CodeNotAuthorized}}, transport.ErrUnauthorized},
+		{403, http.Header{transport.HeaderMasheryErrorCode: {"ERR_403_DEVELOPER_INACTIVE"}}, transport.ErrForbidden},
+		{403, overQPS, transport.ErrThrottled},
+		{403, nil, transport.ErrForbidden},
+		{404, nil, transport.ErrNotFound},
+		{409, nil, transport.ErrConflict},
+		{429, nil, transport.ErrThrottled},
+		{502, nil, transport.ErrTransient},
+	}
+
+	for _, tc := range cases {
+		err := transport.ResponseError(responseTo("GET", tc.code, tc.hdr, ""))
+		assert.True(t, errors.Is(err, tc.kind), "status %d should be %s", tc.code, tc.kind)
+		assert.Equal(t, tc.code, err.StatusCode)
+	}
+}
+
+func TestResponseErrorCarriesMasheryDetails(t *testing.T) {
+	err := transport.ResponseError(responseTo("POST", 400, nil, `{"errors":[{"property":"name","message":"is required"}]}`))
+
+	assert.Equal(t, "POST", err.Method)
+	assert.Equal(t, "https://api.mashery.com/v3/rest/services", err.URL)
+	assert.Equal(t, []masherytypes.V3PropertyErrorMessage{{Property: "name", Message: "is required"}}, err.Properties)
+	assert.Equal(t, "POST https://api.mashery.com/v3/rest/services: error in property name: is required;", err.Error())
+
+	var propErr *masherytypes.V3PropertyErrorMessages
+	assert.True(t, errors.As(err, &propErr))
+
+	err = transport.ResponseError(responseTo("GET", 409, nil, `{"errorCode":"ERR_409_CONFLICT","errorMessage":"conflict"}`))
+	assert.Equal(t, "ERR_409_CONFLICT", err.ErrorCode)
+
+	var genErr *masherytypes.V3GenericErrorResponse
+	assert.True(t, errors.As(err, &genErr))
+}
+
+// failingExecutor fails every call with the network error
+type failingExecutor struct{}
+
+func (failingExecutor) Do(r *http.Request) (*http.Response, error) {
+	if err := r.Context().Err(); err != nil {
+		return nil, &url.Error{Op: r.Method, URL: r.URL.String(), Err: err}
+	}
+	return nil, &url.Error{Op: r.Method, URL: r.URL.String(), Err: errors.New("connection refused")}
+}
+
+func (failingExecutor) CloseIdleConnections() {}
+
+func TestNetworkErrorsAreTransient(t *testing.T) {
+	c := &transport.HttpTransport{
+		Mutex:        &sync.Mutex{},
+		MaxQPS:       100,
+		HttpExecutor: failingExecutor{},
+	}
+
+	_, err := c.Fetch(context.Background(), "/services")
+	assert.True(t, errors.Is(err, transport.ErrTransient))
+
+	var v3Err *transport.V3Error
+	assert.True(t, errors.As(err, &v3Err))
+	assert.Equal(t, "GET", v3Err.Method)
+	assert.Equal(t, "/services", v3Err.URL)
+}
+
+func TestRetriesExhaustedKeepsLastResponse(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{respondWith(403, overQPS)}}
+	at := transport.NewAdaptiveThrottle(4)
+	at.BaseBackoff = 0
+	at.MaxRetries = 1
+
+	_, err := fetchOnce(adaptiveTransport(se, at, transport.NewTokenBucketLimiter(100, 100)))
+	assert.True(t, errors.Is(err, transport.ErrRetriesExhausted))
+	assert.True(t, errors.Is(err, transport.ErrThrottled))
+}
+
+func TestRateExhaustedErrorIsThrottled(t *testing.T) {
+	var err error = &transport.RateExhaustedError{}
+	assert.True(t, errors.Is(err, transport.ErrThrottled))
+	assert.Nil(t, err.(*transport.RateExhaustedError).Cause)
+
+	err = &transport.RateExhaustedError{Cause: transport.ResponseError(responseTo("GET", 403, overRate(""), ""))}
+	assert.True(t, errors.Is(err, transport.ErrThrottled))
+
+	var v3Err *transport.V3Error
+	assert.True(t, errors.As(err, &v3Err))
+	assert.Equal(t, transport.CodeDeveloperOverRate, v3Err.ErrorCode)
+}
@@ -0,0 +1,255 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HTTP Archive 1.2 structures, see http://www.softwareishard.com/blog/har-12-spec/
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+	Error       string         `json:"_error,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+type harEntry struct {
+	StartedDateTime string                 `json:"startedDateTime"`
+	Time            float64                `json:"time"`
+	Request         harRequest             `json:"request"`
+	Response        harResponse            `json:"response"`
+	Cache           map[string]interface{} `json:"cache"`
+	Timings         harTimings             `json:"timings"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+// HARRecorder collects the exchanges with Mashery into HTTP Archive (HAR 1.2), which can be attached to
+// the support tickets. The secrets are redacted with the Redaction. Listener is the ExchangeListener to
+// be configured on the client.
+type HARRecorder struct {
+	Redaction Redaction
+
+	mutex   sync.Mutex
+	entries []harEntry
+}
+
+// NewHARRecorder creates the recorder redacting the secrets with DefaultRedaction
+func NewHARRecorder() *HARRecorder {
+	return &HARRecorder{
+		Redaction: DefaultRedaction(),
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	if d < 0 {
+		return 0
+	}
+	return float64(d.Microseconds()) / 1000
+}
+
+func (h *HARRecorder) headers(hdr http.Header) []harNameValue {
+	redacted := h.Redaction.header(hdr)
+
+	keys := make([]string, 0, len(redacted))
+	for k := range redacted {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	rv := []harNameValue{}
+	for _, k := range keys {
+		for _, v := range redacted[k] {
+			rv = append(rv, harNameValue{Name: k, Value: v})
+		}
+	}
+	return rv
+}
+
+func (h *HARRecorder) request(req *WrappedRequest) harRequest {
+	rv := harRequest{
+		Method:      req.Request.Method,
+		URL:         h.Redaction.url(req.Request.URL),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []harNameValue{},
+		Headers:     h.headers(req.Request.Header),
+		QueryString: []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    0,
+	}
+
+	qs := h.Redaction.query(req.Request.URL.Query())
+	keys := make([]string, 0, len(qs))
+	for k := range qs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range qs[k] {
+			rv.QueryString = append(rv.QueryString, harNameValue{Name: k, Value: v})
+		}
+	}
+
+	if req.Body != nil {
+		if dat, err := json.Marshal(req.Body); err == nil {
+			text := h.Redaction.body(dat, "application/json")
+			rv.PostData = &harPostData{MimeType: "application/json", Text: text}
+			rv.BodySize = len(text)
+		}
+	}
+
+	return rv
+}
+
+func (h *HARRecorder) response(res *WrappedResponse, err error) harResponse {
+	rv := harResponse{
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []harNameValue{},
+		Headers:     []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+	if err != nil {
+		rv.Error = err.Error()
+	}
+	if res == nil {
+		return rv
+	}
+
+	rv.Status = res.StatusCode
+	rv.StatusText = http.StatusText(res.StatusCode)
+	rv.Headers = h.headers(res.Header)
+
+	mimeType := res.Header.Get("Content-Type")
+	body, _ := res.Body()
+	text := h.Redaction.body(body, mimeType)
+	rv.Content = harContent{Size: len(body), MimeType: mimeType, Text: text}
+	rv.BodySize = len(body)
+
+	return rv
+}
+
+// Listener the ExchangeListener recording the exchange
+func (h *HARRecorder) Listener(_ context.Context, req *WrappedRequest, res *WrappedResponse, err error) {
+	if req == nil || req.Request == nil {
+		return
+	}
+
+	started := req.Sent
+	if started.IsZero() {
+		started = time.Now()
+	}
+
+	entry := harEntry{
+		StartedDateTime: started.Format(time.RFC3339Nano),
+		Request:         h.request(req),
+		Response:        h.response(res, err),
+		Cache:           map[string]interface{}{},
+	}
+
+	finished := time.Now()
+	if res != nil && !res.Received.IsZero() {
+		entry.Timings.Wait = milliseconds(res.Received.Sub(started))
+		entry.Timings.Receive = milliseconds(finished.Sub(res.Received))
+	} else {
+		entry.Timings.Wait = milliseconds(finished.Sub(started))
+	}
+	entry.Time = entry.Timings.Send + entry.Timings.Wait + entry.Timings.Receive
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.entries = append(h.entries, entry)
+}
+
+// WriteTo writes the archive of the exchanges recorded so far
+func (h *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	h.mutex.Lock()
+	har := harFile{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "mashery-v3-go-client", Version: "1.0"},
+		Entries: append([]harEntry{}, h.entries...),
+	}}
+	h.mutex.Unlock()
+
+	dat, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(dat)
+	return int64(n), err
+}
+
+// Save writes the archive of the exchanges recorded so far to the file, which only the current user can read
+func (h *HARRecorder) Save(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err = h.WriteTo(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
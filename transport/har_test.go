@@ -0,0 +1,52 @@
+package transport_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestHARRecorderWritesRedactedArchive(t *testing.T) {
+	har := transport.NewHARRecorder()
+	c := cassetteTransport("https://api.example.com/v3/rest", &keyServer{})
+	c.Authorizer = transport.NewBearerAuthorizer("tkn")
+	c.ExchangeListener = har.Listener
+
+	_, err := c.Put(context.Background(), "/packageKeys/k?fields=id,secret", map[string]string{"status": "active", "secret": "n3w"})
+	assert.Nil(t, err)
+
+	buf := bytes.Buffer{}
+	_, err = har.WriteTo(&buf)
+	assert.Nil(t, err)
+	for _, secret := range []string{"Bearer tkn", "s3cr3t", "n3w"} {
+		assert.False(t, strings.Contains(buf.String(), secret), secret)
+	}
+
+	var doc map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &doc))
+
+	log := doc["log"].(map[string]interface{})
+	assert.Equal(t, "1.2", log["version"])
+
+	entries := log["entries"].([]interface{})
+	assert.Equal(t, 1, len(entries))
+
+	entry := entries[0].(map[string]interface{})
+	req := entry["request"].(map[string]interface{})
+	assert.Equal(t, "PUT", req["method"])
+	assert.Equal(t, "https://api.example.com/v3/rest/packageKeys/k?fields=id%2Csecret", req["url"])
+	assert.Equal(t, `{"secret":"REDACTED","status":"active"}`, req["postData"].(map[string]interface{})["text"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "fields", "value": "id,secret"}}, req["queryString"])
+
+	resp := entry["response"].(map[string]interface{})
+	assert.Equal(t, float64(200), resp["status"])
+	assert.Equal(t, `{"apikey":"key","id":"k","secret":"REDACTED","status":"active"}`, resp["content"].(map[string]interface{})["text"])
+
+	timings := entry["timings"].(map[string]interface{})
+	assert.Contains(t, timings, "wait")
+	assert.Contains(t, timings, "receive")
+}
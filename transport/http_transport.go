@@ -21,52 +21,93 @@ type HttpTransport struct {
 	AvgNetLatency time.Duration
 	HttpExecutor  HttpExecutor
 
-	PlannedSecond  int64
+	// Deprecated: PlannedSecond is not used; the calls are allocated by the PerSecondLimiter
+	PlannedSecond int64
+	// Deprecated: AllocatedCalls is not used; the calls are allocated by the PerSecondLimiter
 	AllocatedCalls int64
 	MaxQPS         int64
 
+	// RateLimiter controls the rate of the calls. Where not set, the calls are allocated per second
+	// by the PerSecondLimiter with MaxQPS and AvgNetLatency of this transport.
+	RateLimiter RateLimiter
+	perSecond   *PerSecondLimiter
+
+	// MaxConcurrentFetches the number of pages that are fetched concurrently when retrieving all objects
+	// of a collection. DefaultMaxConcurrentFetches is used if not set.
+	MaxConcurrentFetches int
+
+	// ResponseCache where set, the GET requests for the resources fetched before are made conditional,
+	// and the unmodified resources are served from the cache.
+	ResponseCache ResponseCache
+
 	Mutex *sync.Mutex
 
 	ExchangeListener ExchangeListener
 	Pipeline         MiddlewareFunc
 }
 
+// DefaultMaxConcurrentFetches default number of pages fetched concurrently
+const DefaultMaxConcurrentFetches = 4
+
+func (c *HttpTransport) fetchParallelism() int {
+	if c.MaxConcurrentFetches > 0 {
+		return c.MaxConcurrentFetches
+	}
+	return DefaultMaxConcurrentFetches
+}
+
+// DelayBeforeCall allocates the call with the PerSecondLimiter created from MaxQPS and AvgNetLatency of
+// the transport. It is used where the transport has no RateLimiter.
 func (c *HttpTransport) DelayBeforeCall() time.Duration {
 	c.Mutex.Lock()
-	defer c.Mutex.Unlock()
-
-	// A call to Mashery will be received at this time
-	nextServTime := time.Now().Add(c.AvgNetLatency)
-	nextServSecond := nextServTime.Unix()
-
-	if nextServSecond > c.PlannedSecond {
-		c.PlannedSecond = nextServSecond
-		c.AllocatedCalls = 1
-		return time.Duration(0)
-	} else if nextServSecond == c.PlannedSecond && c.AllocatedCalls < c.MaxQPS {
-		c.AllocatedCalls++
-		return time.Duration(0)
-	} else {
-		wait := c.PlannedSecond - nextServSecond
-		if c.AllocatedCalls < c.MaxQPS {
-			c.AllocatedCalls++
-		} else {
-			wait++
-			c.PlannedSecond++
-			c.AllocatedCalls = 1
-		}
-		return time.Second * time.Duration(wait)
+	if c.perSecond == nil {
+		c.perSecond = &PerSecondLimiter{MaxQPS: c.MaxQPS, AvgNetLatency: c.AvgNetLatency}
 	}
+	ps := c.perSecond
+	c.Mutex.Unlock()
+
+	return ps.DelayBeforeCall()
+}
+
+// WaitBeforeCall blocks until the next call is permitted by the rate limiter of this transport
+func (c *HttpTransport) WaitBeforeCall(ctx context.Context) error {
+	if c.RateLimiter != nil {
+		return c.RateLimiter.Wait(ctx)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return waitFor(ctx, c.DelayBeforeCall())
 }
 
 func (c *HttpTransport) Fetch(ctx context.Context, res string) (*WrappedResponse, error) {
 	uri := fmt.Sprintf("%s%s", c.MashEndpoint, res)
 
-	if req, err := http.NewRequest("GET", uri, nil); err != nil {
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
 		return nil, err
-	} else {
+	}
+	if c.ResponseCache == nil {
 		return c.httpExec(ctx, &WrappedRequest{Request: req})
 	}
+
+	cached, found := c.ResponseCache.Get(uri)
+	if found {
+		makeConditional(req, cached)
+	}
+
+	wr, err := c.httpExec(ctx, &WrappedRequest{Request: req})
+	if err != nil {
+		return wr, err
+	}
+
+	if wr.StatusCode == http.StatusNotModified && found {
+		return serveNotModified(wr, cached), nil
+	} else if wr.StatusCode == http.StatusOK {
+		storeResponse(c.ResponseCache, uri, wr)
+	}
+	return wr, err
 }
 
 func (c *HttpTransport) Delete(ctx context.Context, res string) (*WrappedResponse, error) {
@@ -123,13 +164,19 @@ func (c *HttpTransport) httpExec(ctx context.Context, wrq *WrappedRequest) (*Wra
 	}
 
 	var wrs *WrappedResponse
-	resp, lastErr := c.HttpExecutor.Do(wrq.Request)
+	// The request is bound to the context, so that cancelling the context aborts the call in progress.
+	noteMethod(ctx, wrq.Request.Method)
+	wrq.Sent = time.Now()
+	resp, lastErr := c.HttpExecutor.Do(wrq.Request.WithContext(ctx))
 	if lastErr == nil {
 		wrs = &WrappedResponse{
-			Request:    wrq,
-			Response:   resp,
-			StatusCode: resp.StatusCode,
-			Header:     resp.Header,
+			Request:      wrq,
+			Response:     resp,
+			StatusCode:   resp.StatusCode,
+			Header:       resp.Header,
+			Received:     time.Now(),
+			ETag:         resp.Header.Get(HeaderETag),
+			LastModified: resp.Header.Get(HeaderLastModified),
 		}
 	}
 
@@ -139,7 +186,7 @@ func (c *HttpTransport) httpExec(ctx context.Context, wrq *WrappedRequest) (*Wra
 
 	// Where the response is successful or cannot be re-tried, the both
 	// are returned to the caller
-	return wrs, lastErr
+	return wrs, callError(ctx, wrq, lastErr)
 }
 
 // ReadResponseBody Reads the response body of the response
@@ -0,0 +1,153 @@
+package transport
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// ObjectIterator streams the objects of a Mashery collection page by page. Only a single page is held in
+// memory at any time. The iterator is not safe for concurrent use.
+//
+// Typical use:
+//
+//	it, err := transport.Iterate(ctx, spec, c)
+//	if err != nil { ... }
+//	defer it.Close()
+//
+//	for it.Next() {
+//		obj := it.Value()
+//	}
+//	if it.Err() != nil { ... }
+type ObjectIterator[T any] struct {
+	// Decorate is applied to each object before it is returned by Value, e.g. to set the parent identifier.
+	Decorate func(*T)
+
+	ctx   context.Context
+	spec  ObjectListFetchSpec[T]
+	c     *HttpTransport
+	total int64
+
+	page     []T
+	pos      int
+	pageSize int
+	pageNo   int
+	read     int64
+
+	err    error
+	closed bool
+}
+
+// Iterate starts the iteration over the collection described by the spec. The first page is fetched before
+// this function returns, so that Total reports the number of objects in the collection upfront.
+func Iterate[T any](ctx context.Context, opCtx ObjectListFetchSpec[T], c *HttpTransport) (*ObjectIterator[T], error) {
+	rv := &ObjectIterator[T]{
+		ctx:  ctx,
+		spec: opCtx,
+		c:    c,
+		pos:  -1,
+	}
+
+	page, wr, err := performGenericObjectCRUDWithResponse[[]T](ctx, c, opCtx.AsObjectFetchSpec(), func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
+		return c.Fetch(ctx, opCtx.DestResource())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rv.page = page
+	rv.pageSize = len(page)
+	rv.read = int64(len(page))
+
+	// Without the total count only the first page is available, which is consistent with FetchAll.
+	if wr != nil && len(wr.Header.Get("X-Total-Count")) > 0 {
+		rv.total = extractTotalCount(wr)
+	} else {
+		rv.total = rv.read
+	}
+
+	return rv, nil
+}
+
+// Total the number of objects in the collection, as reported by Mashery when the iteration has started
+func (it *ObjectIterator[T]) Total() int64 {
+	return it.total
+}
+
+// Next advances to the next object, fetching the next page where necessary. It returns false when the
+// collection is exhausted, the iterator was closed, or an error has occurred.
+func (it *ObjectIterator[T]) Next() bool {
+	if it.closed || it.err != nil {
+		return false
+	}
+
+	if it.pos+1 < len(it.page) {
+		it.pos++
+		return true
+	}
+
+	if !it.fetchNextPage() {
+		return false
+	}
+
+	it.pos = 0
+	return true
+}
+
+// Value the current object
+func (it *ObjectIterator[T]) Value() T {
+	rv := it.page[it.pos]
+	if it.Decorate != nil {
+		it.Decorate(&rv)
+	}
+	return rv
+}
+
+// Err returns the error that has terminated the iteration, if any
+func (it *ObjectIterator[T]) Err() error {
+	return it.err
+}
+
+// Close terminates the iteration early. No further pages are fetched.
+func (it *ObjectIterator[T]) Close() {
+	it.closed = true
+	it.page = nil
+}
+
+func (it *ObjectIterator[T]) fetchNextPage() bool {
+	if it.pageSize == 0 || it.read >= it.total {
+		return false
+	}
+	if it.err = it.ctx.Err(); it.err != nil {
+		return false
+	}
+
+	it.pageNo++
+	offset := it.pageNo
+	if it.spec.Pagination == PerItem {
+		offset = int(it.read)
+	}
+
+	builder := it.spec.ToBuilder()
+	builder.WithMergedQuery(url.Values{
+		"offset": {strconv.Itoa(offset)},
+	})
+	pageSpec := builder.Build().AsObjectFetchSpec()
+
+	page, err := performGenericObjectCRUD(it.ctx, it.c, pageSpec, func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
+		return c.Fetch(ctx, pageSpec.DestResource())
+	})
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	// The collection may shrink while it is iterated.
+	if len(page) == 0 {
+		return false
+	}
+
+	it.page = page
+	it.read += int64(len(page))
+	return true
+}
@@ -0,0 +1,75 @@
+package transport_test
+
+import (
+	"context"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestIterateReportsTotalUpfront(t *testing.T) {
+	pe := &pagedExecutor{total: 23, pageSize: 5}
+
+	it, err := transport.Iterate(context.Background(), pagedSpec(), pagedTransport(pe, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(23), it.Total())
+	assert.Equal(t, int32(1), pe.calls)
+
+	expected := 0
+	for it.Next() {
+		assert.Equal(t, expected, it.Value())
+		expected++
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, 23, expected)
+	assert.Equal(t, int32(5), pe.calls)
+}
+
+func TestIterateAppliesDecorator(t *testing.T) {
+	pe := &pagedExecutor{total: 7, pageSize: 5}
+
+	it, err := transport.Iterate(context.Background(), pagedSpec(), pagedTransport(pe, 0))
+	assert.Nil(t, err)
+	it.Decorate = func(v *int) { *v *= 10 }
+
+	var rv []int
+	for it.Next() {
+		rv = append(rv, it.Value())
+	}
+	assert.Equal(t, []int{0, 10, 20, 30, 40, 50, 60}, rv)
+}
+
+func TestIterateStopsOnError(t *testing.T) {
+	pe := &pagedExecutor{total: 30, pageSize: 5, failAt: 10}
+
+	it, err := transport.Iterate(context.Background(), pagedSpec(), pagedTransport(pe, 0))
+	assert.Nil(t, err)
+
+	read := 0
+	for it.Next() {
+		read++
+	}
+	assert.Equal(t, 10, read)
+	assert.NotNil(t, it.Err())
+	assert.False(t, it.Next())
+	assert.Equal(t, int32(3), pe.calls)
+}
+
+func TestIterateStopsWhenContextIsCancelled(t *testing.T) {
+	pe := &pagedExecutor{total: 30, pageSize: 5}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	it, err := transport.Iterate(ctx, pagedSpec(), pagedTransport(pe, 0))
+	assert.Nil(t, err)
+
+	read := 0
+	for it.Next() {
+		read++
+		if read == 5 {
+			cancel()
+		}
+	}
+	assert.Equal(t, 5, read)
+	assert.Equal(t, context.Canceled, it.Err())
+}
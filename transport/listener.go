@@ -7,3 +7,26 @@ import (
 // ExchangeListener receive a notification of a raw exchange: what was actually sent to Mashery, and which response
 // was received
 type ExchangeListener func(ctx context.Context, req *WrappedRequest, res *WrappedResponse, err error)
+
+// ChainListeners creates the listener notifying each of the non-nil listeners in turn
+func ChainListeners(listeners ...ExchangeListener) ExchangeListener {
+	var rv []ExchangeListener
+	for _, l := range listeners {
+		if l != nil {
+			rv = append(rv, l)
+		}
+	}
+
+	switch len(rv) {
+	case 0:
+		return nil
+	case 1:
+		return rv[0]
+	default:
+		return func(ctx context.Context, req *WrappedRequest, res *WrappedResponse, err error) {
+			for _, l := range rv {
+				l(ctx, req, res, err)
+			}
+		}
+	}
+}
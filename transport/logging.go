@@ -0,0 +1,210 @@
+package transport
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// AppContextKey context key holding the application context of the spec the call is made for, set for the calls
+// made via the generic object functions of this package.
+const AppContextKey = ".app.context"
+
+// AppContext returns the application context of the call
+func AppContext(ctx context.Context) string {
+	if v := ctx.Value(AppContextKey); v != nil {
+		if str, ok := v.(string); ok {
+			return str
+		}
+	}
+	return ""
+}
+
+// LogOptions the options of the call logging
+type LogOptions struct {
+	// Level the level the successful calls are logged with
+	Level slog.Level
+	// ErrorLevel the level the failed calls and the calls with 4xx and 5xx responses are logged with
+	ErrorLevel slog.Level
+	// Redaction the secrets removed from the log records
+	Redaction Redaction
+}
+
+// DefaultLogOptions logs the successful calls at debug and the failed calls at warning level, redacting the
+// secrets with DefaultLogRedaction
+func DefaultLogOptions() LogOptions {
+	return LogOptions{
+		Level:      slog.LevelDebug,
+		ErrorLevel: slog.LevelWarn,
+		Redaction:  DefaultLogRedaction(),
+	}
+}
+
+// DefaultLogRedaction extends DefaultRedaction with the API keys and the signatures, which should not appear
+// in the logs.
+func DefaultLogRedaction() Redaction {
+	rv := DefaultRedaction()
+	rv.Fields = append(rv.Fields, "apikey", "api_key", "sig")
+	return rv
+}
+
+var bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`)
+
+// redactingHandler slog.Handler removing the secrets from the records before passing them to the next handler.
+// The attributes named as the secrets are replaced with RedactedValue; within the messages and the string values,
+// the bearer tokens and the secrets formatted as the query parameters or the JSON fields are replaced.
+type redactingHandler struct {
+	next      slog.Handler
+	names     []string
+	pairs     *regexp.Regexp
+	jsonPairs *regexp.Regexp
+}
+
+// NewRedactingHandler wraps the handler, removing the secrets described by the redaction from the records. Where
+// the handler already redacts, the names it removes are kept alongside those of the redaction.
+func NewRedactingHandler(h slog.Handler, r Redaction) slog.Handler {
+	var names []string
+	if rh, ok := h.(*redactingHandler); ok {
+		h = rh.next
+		names = append(names, rh.names...)
+	}
+
+	names = append(names, r.Headers...)
+	names = append(names, r.QueryParams...)
+	names = append(names, r.Fields...)
+
+	rv := &redactingHandler{next: h, names: names}
+	if len(names) > 0 {
+		quoted := make([]string, len(names))
+		for i, n := range names {
+			quoted[i] = regexp.QuoteMeta(n)
+		}
+		alt := strings.Join(quoted, "|")
+
+		rv.pairs = regexp.MustCompile(`(?i)\b(` + alt + `)=[^&\s"']+`)
+		rv.jsonPairs = regexp.MustCompile(`(?i)("(?:` + alt + `)"\s*:\s*)"[^"]*"`)
+	}
+	return rv
+}
+
+func (h *redactingHandler) redactString(s string) string {
+	s = bearerPattern.ReplaceAllString(s, "${1}"+RedactedValue)
+	if h.pairs != nil {
+		s = h.pairs.ReplaceAllString(s, "${1}="+RedactedValue)
+		s = h.jsonPairs.ReplaceAllString(s, `${1}"`+RedactedValue+`"`)
+	}
+	return s
+}
+
+func (h *redactingHandler) attr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if matchesAny(a.Key, h.names) {
+		return slog.String(a.Key, RedactedValue)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, h.redactString(a.Value.String()))
+	case slog.KindGroup:
+		group := a.Value.Group()
+		rv := make([]any, len(group))
+		for i, ga := range group {
+			rv[i] = h.attr(ga)
+		}
+		return slog.Group(a.Key, rv...)
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, h.redactString(err.Error()))
+		}
+	}
+	return a
+}
+
+func (h *redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, rec slog.Record) error {
+	rv := slog.NewRecord(rec.Time, rec.Level, h.redactString(rec.Message), rec.PC)
+	rec.Attrs(func(a slog.Attr) bool {
+		rv.AddAttrs(h.attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, rv)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.attr(a)
+	}
+
+	rv := *h
+	rv.next = h.next.WithAttrs(redacted)
+	return &rv
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	rv := *h
+	rv.next = h.next.WithGroup(name)
+	return &rv
+}
+
+// NewSlogListener creates the ExchangeListener logging each call with the logger, or with the default logger where
+// the logger is nil. The record carries the method, the resource, the status, the latency, the attempt, and the
+// application context of the call; the secrets are redacted as specified by the options.
+func NewSlogListener(logger *slog.Logger, opts LogOptions) ExchangeListener {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger = slog.New(NewRedactingHandler(logger.Handler(), opts.Redaction))
+
+	return func(ctx context.Context, req *WrappedRequest, res *WrappedResponse, err error) {
+		if req == nil || req.Request == nil {
+			return
+		}
+
+		level := opts.Level
+		if err != nil || (res != nil && res.StatusCode >= 400) {
+			level = opts.ErrorLevel
+		}
+		if !logger.Enabled(ctx, level) {
+			return
+		}
+
+		resource := *req.Request.URL
+		resource.RawQuery = opts.Redaction.query(resource.Query()).Encode()
+
+		attrs := []slog.Attr{
+			slog.String("method", req.Request.Method),
+			slog.String("resource", resource.RequestURI()),
+		}
+		if res != nil {
+			attrs = append(attrs, slog.Int("status", res.StatusCode))
+			if res.FromCache {
+				attrs = append(attrs, slog.Bool("cached", true))
+			}
+		}
+		if !req.Sent.IsZero() {
+			finished := time.Now()
+			if res != nil && !res.Received.IsZero() {
+				finished = res.Received
+			}
+			attrs = append(attrs, slog.Duration("latency", finished.Sub(req.Sent)))
+		}
+		attrs = append(attrs, slog.Int("attempt", RetryAttempt(ctx)))
+		if appCtx := AppContext(ctx); len(appCtx) > 0 {
+			attrs = append(attrs, slog.String("app_context", appCtx))
+		}
+		if family := ResourceFamily(ctx); len(family) > 0 {
+			attrs = append(attrs, slog.String("family", family))
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+
+		logger.LogAttrs(ctx, level, "mashery call", attrs...)
+	}
+}
@@ -0,0 +1,122 @@
+package transport_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var rv []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if len(line) == 0 {
+			continue
+		}
+		rec := map[string]interface{}{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &rec))
+		rv = append(rv, rec)
+	}
+	return rv
+}
+
+func TestSlogListenerLogsCalls(t *testing.T) {
+	buf := bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	c := cassetteTransport("https://api.example.com/v3/rest", &keyServer{})
+	c.Authorizer = transport.NewBearerAuthorizer("tkn")
+	c.ExchangeListener = transport.NewSlogListener(logger, transport.DefaultLogOptions())
+
+	spec := transport.ObjectFetchSpecBuilder[map[string]interface{}]{}
+	spec.WithValueFactory(func() map[string]interface{} { return map[string]interface{}{} }).
+		WithResource("/packageKeys/k").
+		WithQuery(map[string][]string{"fields": {"id,secret"}, "sig": {"abc"}}).
+		WithAppContext("get package key")
+
+	_, _, err := transport.GetObject(context.Background(), spec.Build(), c)
+	assert.Nil(t, err)
+
+	recs := logRecords(t, &buf)
+	assert.Equal(t, 1, len(recs))
+
+	rec := recs[0]
+	assert.Equal(t, "DEBUG", rec["level"])
+	assert.Equal(t, "mashery call", rec["msg"])
+	assert.Equal(t, "GET", rec["method"])
+	assert.Equal(t, "/v3/rest/packageKeys/k?fields=id%2Csecret&sig=REDACTED", rec["resource"])
+	assert.Equal(t, float64(200), rec["status"])
+	assert.Equal(t, float64(1), rec["attempt"])
+	assert.Equal(t, "get package key", rec["app_context"])
+	assert.Equal(t, "packageKeys", rec["family"])
+	assert.Contains(t, rec, "latency")
+}
+
+func TestSlogListenerLogsFailuresAtErrorLevel(t *testing.T) {
+	buf := bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	c := cassetteTransport("https://api.example.com/v3/rest", &keyServer{})
+	c.ExchangeListener = transport.NewSlogListener(logger, transport.DefaultLogOptions())
+
+	_, err := c.Fetch(context.Background(), "/packageKeys/k")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, buf.Len())
+
+	_, err = c.Put(context.Background(), "/packageKeys/k", map[string]string{"secret": "n3w"})
+	assert.Nil(t, err)
+
+	recs := logRecords(t, &buf)
+	assert.Equal(t, 1, len(recs))
+	assert.Equal(t, "WARN", recs[0]["level"])
+	assert.Equal(t, float64(400), recs[0]["status"])
+}
+
+func TestRedactingHandler(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := transport.NewRedactingHandler(slog.NewJSONHandler(&buf, nil), transport.DefaultLogRedaction())
+	logger := slog.New(h).With("apikey", "k3y")
+
+	logger.Info("sent Authorization: Bearer abc.def-ghi",
+		"secret", "s3cr3t",
+		"url", "https://api.example.com/v3/rest/x?api_key=k3y&sig=abc&limit=1",
+		"body", `{"passwdNew":"pwd","systemDomainCredentialSecret":"sds","name":"n"}`,
+		slog.Group("creds", "password", "pwd", "username", "user"),
+		"error", errors.New("rejected token Bearer abc.def-ghi"),
+	)
+
+	out := buf.String()
+	for _, secret := range []string{"k3y", "s3cr3t", "abc", "pwd", "sds"} {
+		assert.False(t, strings.Contains(out, secret), secret)
+	}
+
+	recs := logRecords(t, &buf)
+	assert.Equal(t, 1, len(recs))
+	rec := recs[0]
+	assert.Equal(t, "sent Authorization: Bearer REDACTED", rec["msg"])
+	assert.Equal(t, "REDACTED", rec["apikey"])
+	assert.Equal(t, "REDACTED", rec["secret"])
+	assert.Equal(t, "https://api.example.com/v3/rest/x?api_key=REDACTED&sig=REDACTED&limit=1", rec["url"])
+	assert.Equal(t, `{"passwdNew":"REDACTED","systemDomainCredentialSecret":"REDACTED","name":"n"}`, rec["body"])
+	assert.Equal(t, map[string]interface{}{"password": "REDACTED", "username": "user"}, rec["creds"])
+	assert.Equal(t, "rejected token Bearer REDACTED", rec["error"])
+}
+
+func TestRedactingHandlerKeepsInnerRedaction(t *testing.T) {
+	buf := bytes.Buffer{}
+	inner := transport.NewRedactingHandler(slog.NewJSONHandler(&buf, nil), transport.Redaction{Fields: []string{"pin"}})
+	h := transport.NewRedactingHandler(inner, transport.Redaction{Fields: []string{"secret"}})
+
+	slog.New(h).Info("call", "pin", "1234", "secret", "s3cr3t", "body", `{"pin":"1234","name":"n"}`)
+
+	recs := logRecords(t, &buf)
+	assert.Equal(t, 1, len(recs))
+	assert.Equal(t, "REDACTED", recs[0]["pin"])
+	assert.Equal(t, "REDACTED", recs[0]["secret"])
+	assert.Equal(t, `{"pin":"REDACTED","name":"n"}`, recs[0]["body"])
+}
@@ -0,0 +1,335 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// callStatsKey context key holding the callStats of the call measured by MetricsFunc
+const callStatsKey = ".call.stats"
+
+// callStats the measurements the middleware functions report for the call measured by MetricsFunc
+type callStats struct {
+	mutex        sync.Mutex
+	method       string
+	retries      int
+	throttleWait time.Duration
+}
+
+func statsOf(ctx context.Context) *callStats {
+	if v := ctx.Value(callStatsKey); v != nil {
+		if cs, ok := v.(*callStats); ok {
+			return cs
+		}
+	}
+	return nil
+}
+
+// noteMethod records the HTTP verb of the call
+func noteMethod(ctx context.Context, method string) {
+	if cs := statsOf(ctx); cs != nil {
+		cs.mutex.Lock()
+		cs.method = method
+		cs.mutex.Unlock()
+	}
+}
+
+// noteRetry records that the call is being repeated
+func noteRetry(ctx context.Context) {
+	if cs := statsOf(ctx); cs != nil {
+		cs.mutex.Lock()
+		cs.retries++
+		cs.mutex.Unlock()
+	}
+}
+
+// noteThrottleWait records the time the call has waited to observe the rate limits
+func noteThrottleWait(ctx context.Context, d time.Duration) {
+	if cs := statsOf(ctx); cs != nil && d > 0 {
+		cs.mutex.Lock()
+		cs.throttleWait += d
+		cs.mutex.Unlock()
+	}
+}
+
+// CallObservation the measurements of the call to Mashery, including all of its retries
+type CallObservation struct {
+	// Method the HTTP verb of the call; empty where the call has failed before the request was made
+	Method string
+	// AppContext the application context of the spec the call was made for
+	AppContext string
+	// StatusCode the status code of the last response; zero where no response was received
+	StatusCode int
+	// ErrorCode the Mashery error code, or the category of the failure; empty for the successful calls
+	ErrorCode string
+	Latency   time.Duration
+	Retries   int
+	// ThrottleWait the time the call has waited to observe the rate limits of Mashery
+	ThrottleWait time.Duration
+}
+
+// MetricsSink receives the observations of the calls made via MetricsFunc
+type MetricsSink interface {
+	ObserveCall(o CallObservation)
+}
+
+// errorCodeOf describes the failure with the Mashery error code or the category of the failure
+func errorCodeOf(err error) string {
+	var v3Err *V3Error
+	if errors.As(err, &v3Err) && len(v3Err.ErrorCode) > 0 {
+		return v3Err.ErrorCode
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, ErrNotFound):
+		return "not_found"
+	case errors.Is(err, ErrConflict):
+		return "conflict"
+	case errors.Is(err, ErrValidation):
+		return "validation"
+	case errors.Is(err, ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, ErrForbidden):
+		return "forbidden"
+	case errors.Is(err, ErrThrottled):
+		return "throttled"
+	case errors.Is(err, ErrRetriesExhausted):
+		return "retries_exhausted"
+	case errors.Is(err, ErrTransient):
+		return "transient"
+	default:
+		return "other"
+	}
+}
+
+// MetricsFunc creates the ChainedMiddlewareFunc reporting each call to the sink. It should be placed last in the
+// pipeline, so that the observation covers the throttling and the retries of the call.
+func MetricsFunc(sink MetricsSink) ChainedMiddlewareFunc {
+	return func(ctx context.Context, c *HttpTransport, next MiddlewareFunc) (*WrappedResponse, error) {
+		cs := &callStats{}
+		started := time.Now()
+
+		wr, err := next(context.WithValue(ctx, callStatsKey, cs), c)
+
+		cs.mutex.Lock()
+		o := CallObservation{
+			Method:       cs.method,
+			AppContext:   AppContext(ctx),
+			Latency:      time.Since(started),
+			Retries:      cs.retries,
+			ThrottleWait: cs.throttleWait,
+		}
+		cs.mutex.Unlock()
+
+		var v3Err *V3Error
+		if wr != nil {
+			o.StatusCode = wr.StatusCode
+		} else if errors.As(err, &v3Err) {
+			o.StatusCode = v3Err.StatusCode
+		}
+		if err != nil {
+			o.ErrorCode = errorCodeOf(err)
+		} else if wr != nil && wr.StatusCode >= 400 {
+			o.ErrorCode = wr.Header.Get(HeaderMasheryErrorCode)
+			if len(o.ErrorCode) == 0 {
+				o.ErrorCode = strconv.Itoa(wr.StatusCode)
+			}
+		}
+
+		sink.ObserveCall(o)
+		return wr, err
+	}
+}
+
+// DefaultLatencyBuckets the upper bounds, in seconds, of the latency histogram buckets
+var DefaultLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+type seriesLabels struct {
+	method     string
+	appContext string
+}
+
+type callSeries struct {
+	requests     map[string]uint64
+	errors       map[string]uint64
+	retries      uint64
+	throttleWait float64
+
+	buckets       []uint64
+	durationSum   float64
+	durationCount uint64
+}
+
+// PrometheusMetrics MetricsSink aggregating the observations in memory and exposing them in the Prometheus
+// text exposition format. The metrics are labelled by the HTTP verb and the application context of the call:
+//   - <Namespace>_requests_total, additionally labelled by the status code
+//   - <Namespace>_request_duration_seconds histogram
+//   - <Namespace>_retries_total
+//   - <Namespace>_throttle_wait_seconds_total
+//   - <Namespace>_errors_total, additionally labelled by the error code
+//
+// PrometheusMetrics is the http.Handler serving the metrics, e.g. on the /metrics path of the local listener.
+type PrometheusMetrics struct {
+	Namespace string
+	Buckets   []float64
+
+	mutex  sync.Mutex
+	series map[seriesLabels]*callSeries
+}
+
+// NewPrometheusMetrics creates the metrics named with the `mashery_client` prefix, using DefaultLatencyBuckets
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		Namespace: "mashery_client",
+		Buckets:   DefaultLatencyBuckets,
+		series:    map[seriesLabels]*callSeries{},
+	}
+}
+
+func (pm *PrometheusMetrics) ObserveCall(o CallObservation) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	if pm.series == nil {
+		pm.series = map[seriesLabels]*callSeries{}
+	}
+
+	key := seriesLabels{method: o.Method, appContext: o.AppContext}
+	s, ok := pm.series[key]
+	if !ok {
+		s = &callSeries{
+			requests: map[string]uint64{},
+			errors:   map[string]uint64{},
+			buckets:  make([]uint64, len(pm.Buckets)),
+		}
+		pm.series[key] = s
+	}
+
+	s.requests[strconv.Itoa(o.StatusCode)]++
+	if len(o.ErrorCode) > 0 {
+		s.errors[o.ErrorCode]++
+	}
+	s.retries += uint64(o.Retries)
+	s.throttleWait += o.ThrottleWait.Seconds()
+
+	secs := o.Latency.Seconds()
+	for i, le := range pm.Buckets {
+		if secs <= le {
+			s.buckets[i]++
+		}
+	}
+	s.durationSum += secs
+	s.durationCount++
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(l seriesLabels, extra ...string) string {
+	sb := strings.Builder{}
+	sb.WriteString(`{method="`)
+	sb.WriteString(labelValueEscaper.Replace(l.method))
+	sb.WriteString(`",app_context="`)
+	sb.WriteString(labelValueEscaper.Replace(l.appContext))
+	sb.WriteString(`"`)
+	for i := 0; i+1 < len(extra); i += 2 {
+		sb.WriteString(fmt.Sprintf(`,%s="%s"`, extra[i], labelValueEscaper.Replace(extra[i+1])))
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	rv := make([]string, 0, len(m))
+	for k := range m {
+		rv = append(rv, k)
+	}
+	sort.Strings(rv)
+	return rv
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format
+func (pm *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	pm.mutex.Lock()
+	labels := make([]seriesLabels, 0, len(pm.series))
+	for l := range pm.series {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].method != labels[j].method {
+			return labels[i].method < labels[j].method
+		}
+		return labels[i].appContext < labels[j].appContext
+	})
+
+	sb := strings.Builder{}
+	metric := func(name, kind, help string, lines func(name string)) {
+		name = pm.Namespace + "_" + name
+		sb.WriteString(fmt.Sprintf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind))
+		lines(name)
+	}
+
+	metric("requests_total", "counter", "Calls made to Mashery V3 API.", func(name string) {
+		for _, l := range labels {
+			s := pm.series[l]
+			for _, status := range sortedKeys(s.requests) {
+				sb.WriteString(fmt.Sprintf("%s%s %d\n", name, formatLabels(l, "status", status), s.requests[status]))
+			}
+		}
+	})
+	metric("request_duration_seconds", "histogram", "Latency of the calls, including the throttling and the retries.", func(name string) {
+		for _, l := range labels {
+			s := pm.series[l]
+			for i, le := range pm.Buckets {
+				sb.WriteString(fmt.Sprintf("%s_bucket%s %d\n", name, formatLabels(l, "le", formatFloat(le)), s.buckets[i]))
+			}
+			sb.WriteString(fmt.Sprintf("%s_bucket%s %d\n", name, formatLabels(l, "le", "+Inf"), s.durationCount))
+			sb.WriteString(fmt.Sprintf("%s_sum%s %s\n", name, formatLabels(l), formatFloat(s.durationSum)))
+			sb.WriteString(fmt.Sprintf("%s_count%s %d\n", name, formatLabels(l), s.durationCount))
+		}
+	})
+	metric("retries_total", "counter", "Repeated attempts of the calls.", func(name string) {
+		for _, l := range labels {
+			sb.WriteString(fmt.Sprintf("%s%s %d\n", name, formatLabels(l), pm.series[l].retries))
+		}
+	})
+	metric("throttle_wait_seconds_total", "counter", "Time the calls have waited to observe the rate limits.", func(name string) {
+		for _, l := range labels {
+			sb.WriteString(fmt.Sprintf("%s%s %s\n", name, formatLabels(l), formatFloat(pm.series[l].throttleWait)))
+		}
+	})
+	metric("errors_total", "counter", "Failed calls by the error code.", func(name string) {
+		for _, l := range labels {
+			s := pm.series[l]
+			for _, code := range sortedKeys(s.errors) {
+				sb.WriteString(fmt.Sprintf("%s%s %d\n", name, formatLabels(l, "code", code), s.errors[code]))
+			}
+		}
+	})
+	pm.mutex.Unlock()
+
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+func (pm *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = pm.WriteTo(w)
+}
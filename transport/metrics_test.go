@@ -0,0 +1,153 @@
+package transport_test
+
+import (
+	"context"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var overQPSRate = http.Header{transport.HeaderMasheryErrorCode: {transport.CodeDeveloperOverRate}}
+
+type observations struct {
+	list []transport.CallObservation
+}
+
+func (o *observations) ObserveCall(c transport.CallObservation) {
+	o.list = append(o.list, c)
+}
+
+func metricsTransport(se *scriptedExecutor, sink transport.MetricsSink) *transport.HttpTransport {
+	return &transport.HttpTransport{
+		Mutex:        &sync.Mutex{},
+		RateLimiter:  transport.NewTokenBucketLimiter(1000, 1000),
+		HttpExecutor: se,
+		Pipeline: transport.BuildPipeline(transport.ExecuteFunction, []transport.ChainedMiddlewareFunc{
+			transport.ThrottleFunc,
+			fastRetryPolicy().Func,
+			transport.EnsureBodyWasRead,
+			transport.UnmarshalServerError,
+			transport.MetricsFunc(sink),
+		}),
+	}
+}
+
+func fetchPlans(c *transport.HttpTransport) error {
+	spec := transport.ObjectFetchSpecBuilder[map[string]interface{}]{}
+	spec.WithValueFactory(func() map[string]interface{} { return map[string]interface{}{} }).
+		WithResource("/packages/p/plans/q").
+		WithAppContext("package plan")
+
+	_, _, err := transport.GetObject(context.Background(), spec.Build(), c)
+	return err
+}
+
+func TestMetricsFuncObservesCalls(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{
+		respondWith(503, nil),
+		respondWith(200, nil),
+		respondWith(403, overQPSRate),
+	}}
+	sink := &observations{}
+	c := metricsTransport(se, sink)
+
+	assert.Nil(t, fetchPlans(c))
+	assert.NotNil(t, fetchPlans(c))
+
+	assert.Equal(t, 2, len(sink.list))
+
+	first := sink.list[0]
+	assert.Equal(t, "GET", first.Method)
+	assert.Equal(t, "package plan", first.AppContext)
+	assert.Equal(t, 200, first.StatusCode)
+	assert.Equal(t, "", first.ErrorCode)
+	assert.Equal(t, 1, first.Retries)
+	assert.True(t, first.Latency > 0)
+
+	second := sink.list[1]
+	assert.Equal(t, 403, second.StatusCode)
+	assert.Equal(t, transport.CodeDeveloperOverRate, second.ErrorCode)
+	assert.Equal(t, 0, second.Retries)
+}
+
+func TestMetricsFuncCountsReplays(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{
+		respondWith(401, nil),
+		respondWith(200, nil),
+		respondWith(400, nil),
+		respondWith(200, nil),
+	}}
+	sink := &observations{}
+	c := &transport.HttpTransport{
+		Mutex:        &sync.Mutex{},
+		RateLimiter:  transport.NewTokenBucketLimiter(1000, 1000),
+		HttpExecutor: se,
+		Authorizer:   &rotatingAuthorizer{},
+		Pipeline: transport.BuildPipeline(transport.ExecuteFunction, []transport.ChainedMiddlewareFunc{
+			transport.ThrottleFunc,
+			transport.RetryOn400Func,
+			transport.EnsureBodyWasRead,
+			transport.UnmarshalServerError,
+			transport.ReauthenticateFunc,
+			transport.MetricsFunc(sink),
+		}),
+	}
+
+	// The re-authenticated call
+	assert.Nil(t, fetchPlans(c))
+	// The call retried on 400
+	spec := transport.ObjectFetchSpecBuilder[map[string]interface{}]{}
+	spec.WithValueFactory(func() map[string]interface{} { return map[string]interface{}{} }).WithResource("/x")
+	_, _, err := transport.GetObject(context.WithValue(context.Background(), transport.RetryOn400, true), spec.Build(), c)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 2, len(sink.list))
+	assert.Equal(t, 200, sink.list[0].StatusCode)
+	assert.Equal(t, 1, sink.list[0].Retries)
+	assert.Equal(t, 200, sink.list[1].StatusCode)
+	assert.Equal(t, 1, sink.list[1].Retries)
+}
+
+func TestPrometheusMetricsExposition(t *testing.T) {
+	pm := transport.NewPrometheusMetrics()
+	pm.Buckets = []float64{0.1, 1}
+
+	se := &scriptedExecutor{responses: []func() *http.Response{
+		respondWith(503, nil),
+		respondWith(200, nil),
+		respondWith(403, overQPSRate),
+	}}
+	c := metricsTransport(se, pm)
+	assert.Nil(t, fetchPlans(c))
+	assert.NotNil(t, fetchPlans(c))
+
+	srv := httptest.NewServer(pm)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", resp.Header.Get("Content-Type"))
+
+	dat, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	text := string(dat)
+
+	for _, line := range []string{
+		"# TYPE mashery_client_requests_total counter",
+		`mashery_client_requests_total{method="GET",app_context="package plan",status="200"} 1`,
+		`mashery_client_requests_total{method="GET",app_context="package plan",status="403"} 1`,
+		"# TYPE mashery_client_request_duration_seconds histogram",
+		`mashery_client_request_duration_seconds_bucket{method="GET",app_context="package plan",le="+Inf"} 2`,
+		`mashery_client_request_duration_seconds_count{method="GET",app_context="package plan"} 2`,
+		`mashery_client_retries_total{method="GET",app_context="package plan"} 1`,
+		`mashery_client_errors_total{method="GET",app_context="package plan",code="ERR_403_DEVELOPER_OVER_RATE"} 1`,
+	} {
+		assert.True(t, strings.Contains(text, line+"\n"), line)
+	}
+	assert.True(t, strings.Contains(text, `mashery_client_throttle_wait_seconds_total{method="GET",app_context="package plan"} `))
+}
@@ -3,7 +3,6 @@ package transport
 import (
 	"context"
 	"errors"
-	"github.com/aliakseiyanchuk/mashery-v3-go-client/errwrap"
 	"time"
 )
 
@@ -14,24 +13,29 @@ const (
 )
 
 func ThrottleFunc(ctx context.Context, c *HttpTransport, next MiddlewareFunc) (*WrappedResponse, error) {
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case <-time.After(c.DelayBeforeCall()):
-		return next(ctx, c)
+	started := time.Now()
+	err := c.WaitBeforeCall(ctx)
+	noteThrottleWait(ctx, time.Since(started))
+	if err != nil {
+		return nil, err
 	}
+	return next(ctx, c)
 }
 
 func BackOffOnDeveloperOverQPSFunc(ctx context.Context, c *HttpTransport, next MiddlewareFunc) (*WrappedResponse, error) {
+	var last *WrappedResponse
 	for i := 0; i < 10; i++ {
 		// If there's an error, return
 		if wr, err := next(ctx, c); err != nil {
 			return wr, err
 		} else if wr.StatusCode == 403 {
 			// Retry if developer over QPS has been received.
-			if str := wr.Header.Get("X-Mashery-Error-Code"); str == "ERR_403_DEVELOPER_OVER_QPS" {
+			if str := wr.Header.Get(HeaderMasheryErrorCode); str == CodeDeveloperOverQPS {
+				last = wr
 				d := time.Duration(1+i) * time.Second
 				time.Sleep(d)
+				noteThrottleWait(ctx, d)
+				noteRetry(ctx)
 				continue
 			} else {
 				return wr, err
@@ -41,19 +45,29 @@ func BackOffOnDeveloperOverQPSFunc(ctx context.Context, c *HttpTransport, next M
 		}
 	}
 
-	return nil, errors.New("operation unsuccessful after all available retries")
+	return last, retriesExhausted(last)
+}
+
+// retriesExhausted describes the failure of the last attempt of the call that could not be completed
+// after all retries
+func retriesExhausted(last *WrappedResponse) error {
+	if last == nil {
+		return &V3Error{Kind: ErrRetriesExhausted}
+	}
+
+	rv := ResponseError(last)
+	rv.Message = ErrRetriesExhausted.Error()
+	rv.Cause = errors.Join(ErrRetriesExhausted, rv.Cause)
+	return rv
 }
 
 func BreakOnDeveloperOverRateFunc(ctx context.Context, c *HttpTransport, next MiddlewareFunc) (*WrappedResponse, error) {
 	if wr, err := next(ctx, c); err != nil {
 		return wr, err
-	} else if str := wr.Header.Get("X-Mashery-Error-Code"); wr.StatusCode == 403 && str == "ERR_403_DEVELOPER_OVER_RATE" {
+	} else if str := wr.Header.Get(HeaderMasheryErrorCode); wr.StatusCode == 403 && str == CodeDeveloperOverRate {
 		// Break calling if developer call has been exhausted
-		if str := wr.Header.Get("X-Mashery-Error-Code"); str == "ERR_403_DEVELOPER_OVER_RATE" {
-			return wr, errors.New("further operations are impossible until developer rate is reset")
-		} else {
-			return wr, err
-		}
+		resetAt, _ := QuotaReset(wr.Header, time.Now())
+		return wr, &RateExhaustedError{ResetAt: resetAt, Cause: ResponseError(wr)}
 	} else {
 		return wr, err
 	}
@@ -73,7 +87,9 @@ func ErrorOn404Func(ctx context.Context, c *HttpTransport, next MiddlewareFunc)
 	if wr, err := next(ctx, c); err != nil {
 		return wr, err
 	} else if wr.StatusCode == 404 && boolKey(ctx, SendErrorOn404) {
-		return wr, errors.New("error code 404 is not an expected response to this request")
+		rv := ResponseError(wr)
+		rv.Message = "error code 404 is not an expected response to this request"
+		return wr, rv
 	} else {
 		return wr, err
 	}
@@ -81,10 +97,15 @@ func ErrorOn404Func(ctx context.Context, c *HttpTransport, next MiddlewareFunc)
 
 func RetryOn400Func(ctx context.Context, c *HttpTransport, next MiddlewareFunc) (*WrappedResponse, error) {
 	if boolKey(ctx, RetryOn400) {
+		var last *WrappedResponse
 		for i := 0; i < 5; i++ {
+			if i > 0 {
+				noteRetry(ctx)
+			}
 			if wr, err := next(ctx, c); err != nil {
 				return wr, err
 			} else if wr.StatusCode == 400 {
+				last = wr
 				time.Sleep(time.Second*3 + time.Duration(i*2))
 				continue
 			} else {
@@ -92,7 +113,7 @@ func RetryOn400Func(ctx context.Context, c *HttpTransport, next MiddlewareFunc)
 			}
 		}
 
-		return nil, errors.New("attempts to perform this operation were unsuccessful after all possible retries")
+		return last, retriesExhausted(last)
 	} else {
 		return next(ctx, c)
 	}
@@ -112,9 +133,12 @@ func EnsureBodyWasRead(ctx context.Context, c *HttpTransport, next MiddlewareFun
 	if wr, err := next(ctx, c); err != nil {
 		return wr, err
 	} else if _, readErr := wr.Body(); readErr != nil {
-		return wr, &errwrap.WrappedError{
-			Cause: readErr,
-		}
+		return wr, (&V3Error{
+			StatusCode: wr.StatusCode,
+			Message:    "reading response body",
+			Kind:       ErrTransient,
+			Cause:      readErr,
+		}).withRequest(wr)
 	} else {
 		return wr, err
 	}
@@ -0,0 +1,150 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HeaderPlanQuotaReset the time the quota of the plan will be reset at
+const HeaderPlanQuotaReset = "X-Plan-Quota-Reset"
+
+var quotaResetLayouts = []string{
+	http.TimeFormat,
+	time.RFC1123,
+	time.RFC1123Z,
+	time.RFC3339,
+	"Monday, January 2, 2006 3:04:05 PM MST",
+	"Monday, January 2, 2006 3:04:05 PM GMT-07:00",
+}
+
+// RateExhaustedError the daily call rate of the API key is exhausted (ERR_403_DEVELOPER_OVER_RATE). ResetAt is
+// the time the quota will be reset at; it is zero if Mashery has not reported it. Cause describes the rejected
+// call; it is nil where the call was not sent to Mashery because the quota is known to be exhausted.
+// The error matches ErrThrottled.
+type RateExhaustedError struct {
+	ResetAt time.Time
+	Cause   *V3Error
+}
+
+func (e *RateExhaustedError) Error() string {
+	if e.ResetAt.IsZero() {
+		return "further operations are impossible until developer rate is reset"
+	}
+	return fmt.Sprintf("further operations are impossible until developer rate is reset at %s", e.ResetAt.Format(time.RFC3339))
+}
+
+// Temporary the call can be repeated once the quota is reset
+func (e *RateExhaustedError) Temporary() bool {
+	return true
+}
+
+func (e *RateExhaustedError) Unwrap() []error {
+	if e.Cause != nil {
+		return []error{e.Cause}
+	}
+	return []error{ErrThrottled}
+}
+
+// QuotaReset determines the time the quota will be reset at from the Retry-After or X-Plan-Quota-Reset headers
+func QuotaReset(hdr http.Header, now time.Time) (time.Time, bool) {
+	if d, ok := RetryAfter(hdr, now); ok {
+		return now.Add(d), true
+	}
+
+	if str := strings.TrimSpace(hdr.Get(HeaderPlanQuotaReset)); len(str) > 0 {
+		for _, layout := range quotaResetLayouts {
+			if t, err := time.Parse(layout, str); err == nil {
+				return t, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// QuotaScheduler handles the exhaustion of the daily call rate. Once Mashery rejects a call with
+// ERR_403_DEVELOPER_OVER_RATE, the calls until the reset time fail with RateExhaustedError without being sent
+// to Mashery. Where Park is set, the calls instead wait until the quota is reset, provided the context
+// deadline permits it.
+type QuotaScheduler struct {
+	// Park the calls until the quota is reset
+	Park bool
+	// Margin added to the reset time before the parked calls are resumed, to allow for the clock skew
+	Margin time.Duration
+
+	mutex   sync.Mutex
+	resetAt time.Time
+}
+
+// ResetAt returns the time the exhausted quota will be reset at; zero if the quota is not known to be exhausted.
+func (qs *QuotaScheduler) ResetAt() time.Time {
+	qs.mutex.Lock()
+	defer qs.mutex.Unlock()
+
+	return qs.resetAt
+}
+
+// Func is the ChainedMiddlewareFunc applying the scheduler, used in place of BreakOnDeveloperOverRateFunc.
+func (qs *QuotaScheduler) Func(ctx context.Context, c *HttpTransport, next MiddlewareFunc) (*WrappedResponse, error) {
+	for {
+		if err := qs.awaitReset(ctx); err != nil {
+			return nil, err
+		}
+
+		wr, err := next(ctx, c)
+		if err != nil || wr == nil {
+			return wr, err
+		}
+		if wr.StatusCode != 403 || wr.Header.Get(HeaderMasheryErrorCode) != CodeDeveloperOverRate {
+			return wr, err
+		}
+
+		resetAt, known := QuotaReset(wr.Header, time.Now())
+		qs.exhausted(resetAt, known)
+
+		if !qs.Park || !known {
+			return wr, &RateExhaustedError{ResetAt: resetAt, Cause: ResponseError(wr)}
+		}
+	}
+}
+
+func (qs *QuotaScheduler) exhausted(resetAt time.Time, known bool) {
+	qs.mutex.Lock()
+	defer qs.mutex.Unlock()
+
+	if known {
+		qs.resetAt = resetAt
+	}
+}
+
+// awaitReset fails or parks the call while the quota is exhausted
+func (qs *QuotaScheduler) awaitReset(ctx context.Context) error {
+	resetAt := qs.ResetAt()
+	if resetAt.IsZero() {
+		return nil
+	}
+
+	resumeAt := resetAt.Add(qs.Margin)
+	wait := time.Until(resumeAt)
+	if wait <= 0 {
+		qs.mutex.Lock()
+		if qs.resetAt.Equal(resetAt) {
+			qs.resetAt = time.Time{}
+		}
+		qs.mutex.Unlock()
+		return nil
+	}
+
+	if !qs.Park {
+		return &RateExhaustedError{ResetAt: resetAt}
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(resumeAt) {
+		return &RateExhaustedError{ResetAt: resetAt}
+	}
+
+	return waitFor(ctx, wait)
+}
@@ -0,0 +1,114 @@
+package transport_test
+
+import (
+	"context"
+	"errors"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func overRate(retryAfter string) http.Header {
+	rv := http.Header{}
+	rv.Set(transport.HeaderMasheryErrorCode, transport.CodeDeveloperOverRate)
+	if len(retryAfter) > 0 {
+		rv.Set(transport.HeaderRetryAfter, retryAfter)
+	}
+	return rv
+}
+
+func quotaTransport(se *scriptedExecutor, mf transport.ChainedMiddlewareFunc) *transport.HttpTransport {
+	return &transport.HttpTransport{
+		Mutex:        &sync.Mutex{},
+		RateLimiter:  transport.NewTokenBucketLimiter(100, 100),
+		HttpExecutor: se,
+		Pipeline: transport.BuildPipeline(transport.ExecuteFunction, []transport.ChainedMiddlewareFunc{
+			transport.ThrottleFunc,
+			mf,
+		}),
+	}
+}
+
+func TestBreakOnDeveloperOverRateReturnsTypedError(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{respondWith(403, overRate("3600"))}}
+
+	before := time.Now()
+	_, err := fetchOnce(quotaTransport(se, transport.BreakOnDeveloperOverRateFunc))
+
+	var rateErr *transport.RateExhaustedError
+	assert.True(t, errors.As(err, &rateErr))
+	assert.True(t, rateErr.ResetAt.After(before.Add(time.Minute*59)))
+}
+
+func TestQuotaSchedulerFailsFastUntilReset(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{
+		respondWith(403, overRate("3600")),
+		respondWith(200, nil),
+	}}
+	qs := &transport.QuotaScheduler{}
+	c := quotaTransport(se, qs.Func)
+
+	_, err := fetchOnce(c)
+	var rateErr *transport.RateExhaustedError
+	assert.True(t, errors.As(err, &rateErr))
+	assert.False(t, qs.ResetAt().IsZero())
+
+	// The subsequent call is not sent to Mashery
+	_, err = fetchOnce(c)
+	assert.True(t, errors.As(err, &rateErr))
+	assert.Equal(t, 1, se.calls)
+}
+
+func TestQuotaSchedulerParksCallsUntilReset(t *testing.T) {
+	resetAt := time.Now().Add(time.Second * 2).UTC()
+	se := &scriptedExecutor{responses: []func() *http.Response{
+		respondWith(403, overRate(resetAt.Format(http.TimeFormat))),
+		respondWith(200, nil),
+	}}
+	qs := &transport.QuotaScheduler{Park: true}
+
+	start := time.Now()
+	exists, err := fetchOnce(quotaTransport(se, qs.Func))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, 2, se.calls)
+	assert.True(t, time.Since(start) >= time.Millisecond*500)
+}
+
+func TestQuotaSchedulerDoesNotParkBeyondDeadline(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{
+		respondWith(403, overRate("3600")),
+		respondWith(200, nil),
+	}}
+	qs := &transport.QuotaScheduler{Park: true}
+	c := quotaTransport(se, qs.Func)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	spec := transport.ObjectFetchSpecBuilder[map[string]interface{}]{}
+	spec.WithValueFactory(func() map[string]interface{} { return map[string]interface{}{} }).WithResource("/x")
+
+	start := time.Now()
+	_, _, err := transport.GetObject(ctx, spec.Build(), c)
+
+	var rateErr *transport.RateExhaustedError
+	assert.True(t, errors.As(err, &rateErr))
+	assert.True(t, time.Since(start) < time.Millisecond*500)
+}
+
+func TestQuotaReset(t *testing.T) {
+	now := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+
+	hdr := http.Header{}
+	hdr.Set(transport.HeaderPlanQuotaReset, "Wednesday, March 6, 2024 12:00:00 AM GMT")
+	rv, ok := transport.QuotaReset(hdr, now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC), rv.UTC())
+
+	_, ok = transport.QuotaReset(http.Header{}, now)
+	assert.False(t, ok)
+}
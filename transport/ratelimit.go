@@ -0,0 +1,190 @@
+package transport
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter controls the rate at which the calls are sent to Mashery. Wait blocks until the next call
+// is permitted, or returns the context error if the context is done first.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// waitFor waits for the specified duration or until the context is done
+func waitFor(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// bucketState the state of the token bucket
+type bucketState struct {
+	Tokens float64 `json:"tokens"`
+	Last   int64   `json:"last"`
+}
+
+// advance adds the tokens accumulated since the last update
+func (bs *bucketState) advance(now time.Time, qps float64, burst float64) {
+	nowNano := now.UnixNano()
+	if bs.Last > 0 && nowNano > bs.Last {
+		bs.Tokens += time.Duration(nowNano-bs.Last).Seconds() * qps
+		if bs.Tokens > burst {
+			bs.Tokens = burst
+		}
+	}
+	if nowNano > bs.Last {
+		bs.Last = nowNano
+	}
+}
+
+// reserve takes a token and returns the wait before it becomes available
+func (bs *bucketState) reserve(now time.Time, qps float64, burst float64) time.Duration {
+	bs.advance(now, qps, burst)
+	bs.Tokens--
+
+	if bs.Tokens >= 0 || qps <= 0 {
+		return 0
+	}
+	return time.Duration(-bs.Tokens / qps * float64(time.Second))
+}
+
+// release returns an unused token
+func (bs *bucketState) release(burst float64) {
+	bs.Tokens++
+	if bs.Tokens > burst {
+		bs.Tokens = burst
+	}
+}
+
+// TokenBucketLimiter smooth rate limiter. The bucket holds up to Burst tokens and is re-filled continuously
+// at the QPS rate; each call consumes one token. Where no token is available, the call waits for the exact
+// time the next token becomes available rather than for the start of the next second.
+//
+// The limiter is safe for concurrent use; transports sharing a single limiter share its budget.
+type TokenBucketLimiter struct {
+	mutex sync.Mutex
+
+	qps   float64
+	burst float64
+	state bucketState
+}
+
+// NewTokenBucketLimiter creates a token bucket limiter permitting qps calls per second on average, with bursts
+// of up to burst calls. A burst smaller than 1 is set to 1. The bucket starts full.
+func NewTokenBucketLimiter(qps float64, burst int) *TokenBucketLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &TokenBucketLimiter{
+		qps:   qps,
+		burst: float64(burst),
+		state: bucketState{Tokens: float64(burst)},
+	}
+}
+
+// QPS returns the current rate of the limiter
+func (tb *TokenBucketLimiter) QPS() float64 {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+
+	return tb.qps
+}
+
+// SetQPS changes the rate of the limiter. Tokens accumulated at the previous rate are retained.
+func (tb *TokenBucketLimiter) SetQPS(qps float64) {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+
+	tb.state.advance(time.Now(), tb.qps, tb.burst)
+	tb.qps = qps
+}
+
+// Reserve takes a token from the bucket and returns the time the caller has to wait before the call
+// can be made. The token is taken even where the wait is non-zero, so that concurrent callers queue up
+// one after another.
+func (tb *TokenBucketLimiter) Reserve() time.Duration {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+
+	return tb.state.reserve(time.Now(), tb.qps, tb.burst)
+}
+
+// cancel returns the token that was reserved, but not used
+func (tb *TokenBucketLimiter) cancel() {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+
+	tb.state.release(tb.burst)
+}
+
+func (tb *TokenBucketLimiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := waitFor(ctx, tb.Reserve()); err != nil {
+		tb.cancel()
+		return err
+	}
+	return nil
+}
+
+// PerSecondLimiter the original rate limiting algorithm of this library: up to MaxQPS calls are allocated
+// to each wall-clock second at which the call is expected to be received by Mashery, considering the average
+// network latency. Waits are rounded to whole seconds.
+type PerSecondLimiter struct {
+	MaxQPS        int64
+	AvgNetLatency time.Duration
+
+	mutex          sync.Mutex
+	plannedSecond  int64
+	allocatedCalls int64
+}
+
+// DelayBeforeCall allocates the call and returns the delay before it can be sent
+func (ps *PerSecondLimiter) DelayBeforeCall() time.Duration {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	// A call to Mashery will be received at this time
+	nextServSecond := time.Now().Add(ps.AvgNetLatency).Unix()
+
+	if nextServSecond > ps.plannedSecond {
+		ps.plannedSecond = nextServSecond
+		ps.allocatedCalls = 1
+		return time.Duration(0)
+	} else if nextServSecond == ps.plannedSecond && ps.allocatedCalls < ps.MaxQPS {
+		ps.allocatedCalls++
+		return time.Duration(0)
+	} else {
+		wait := ps.plannedSecond - nextServSecond
+		if ps.allocatedCalls < ps.MaxQPS {
+			ps.allocatedCalls++
+		} else {
+			wait++
+			ps.plannedSecond++
+			ps.allocatedCalls = 1
+		}
+		return time.Second * time.Duration(wait)
+	}
+}
+
+func (ps *PerSecondLimiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return waitFor(ctx, ps.DelayBeforeCall())
+}
@@ -0,0 +1,146 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/errwrap"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/internal/flock"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// sharedLimiter the limiter registered under the key, with the rate and burst it was created with
+type sharedLimiter struct {
+	limiter *TokenBucketLimiter
+	qps     float64
+	burst   int
+}
+
+var sharedLimiters = map[string]sharedLimiter{}
+var sharedLimitersMutex = sync.Mutex{}
+
+// SharedRateLimiter returns the limiter registered in this process under the key, typically the API key the
+// calls are made with. The limiter is created with the specified rate and burst on the first call; subsequent
+// calls with the same key return the same limiter, so that all transports using it share a single QPS budget.
+//
+// A subsequent call specifying a rate or burst other than the limiter was created with fails, as the limiter
+// cannot satisfy both. The limiter stays registered until released with ReleaseSharedRateLimiter.
+func SharedRateLimiter(key string, qps float64, burst int) (*TokenBucketLimiter, error) {
+	sharedLimitersMutex.Lock()
+	defer sharedLimitersMutex.Unlock()
+
+	if existing, ok := sharedLimiters[key]; ok {
+		if existing.qps != qps || existing.burst != burst {
+			return nil, errors.New(fmt.Sprintf("shared rate limiter is already registered with %g QPS and burst %d",
+				existing.qps, existing.burst))
+		}
+		return existing.limiter, nil
+	}
+
+	rv := NewTokenBucketLimiter(qps, burst)
+	sharedLimiters[key] = sharedLimiter{limiter: rv, qps: qps, burst: burst}
+	return rv, nil
+}
+
+// ReleaseSharedRateLimiter removes the limiter registered under the key. The transports already using it
+// are not affected; the next call to SharedRateLimiter with this key creates a new limiter.
+func ReleaseSharedRateLimiter(key string) {
+	sharedLimitersMutex.Lock()
+	defer sharedLimitersMutex.Unlock()
+
+	delete(sharedLimiters, key)
+}
+
+// FileRateLimiter token bucket limiter which state is kept in a file, so that separate processes on the same
+// machine using the same API key can share a single QPS budget. The processes coordinate via an exclusive lock
+// on the file; each reservation reads, updates and writes the bucket state while holding the lock.
+//
+// All processes sharing the file should use the same rate and burst.
+type FileRateLimiter struct {
+	Path  string
+	QPS   float64
+	Burst int
+}
+
+// NewFileRateLimiter creates a limiter keeping the state in the file at path. The file is created if it doesn't exist.
+func NewFileRateLimiter(path string, qps float64, burst int) (*FileRateLimiter, error) {
+	if burst < 1 {
+		burst = 1
+	}
+
+	rv := &FileRateLimiter{Path: path, QPS: qps, Burst: burst}
+	if err := rv.update(func(_ *bucketState) {}); err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+// update applies the function to the bucket state while holding the lock on the state file
+func (fl *FileRateLimiter) update(f func(state *bucketState)) error {
+	file, err := os.OpenFile(fl.Path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return &errwrap.WrappedError{Context: fmt.Sprintf("opening rate limiter state %s", fl.Path), Cause: err}
+	}
+	defer file.Close()
+
+	if err = flock.Lock(file); err != nil {
+		return &errwrap.WrappedError{Context: fmt.Sprintf("locking rate limiter state %s", fl.Path), Cause: err}
+	}
+	defer flock.Unlock(file)
+
+	dat, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	// An empty or unreadable file starts with a full bucket.
+	state := bucketState{Tokens: float64(fl.Burst)}
+	if len(dat) > 0 {
+		if jsonErr := json.Unmarshal(dat, &state); jsonErr != nil {
+			state = bucketState{Tokens: float64(fl.Burst)}
+		}
+	}
+
+	f(&state)
+
+	if dat, err = json.Marshal(state); err != nil {
+		return err
+	}
+	if err = file.Truncate(0); err != nil {
+		return err
+	}
+	_, err = file.WriteAt(dat, 0)
+	return err
+}
+
+// Reserve takes a token from the shared bucket and returns the time the caller has to wait before the call
+func (fl *FileRateLimiter) Reserve() (time.Duration, error) {
+	var rv time.Duration
+	err := fl.update(func(state *bucketState) {
+		rv = state.reserve(time.Now(), fl.QPS, float64(fl.Burst))
+	})
+	return rv, err
+}
+
+func (fl *FileRateLimiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	d, err := fl.Reserve()
+	if err != nil {
+		return err
+	}
+
+	if err = waitFor(ctx, d); err != nil {
+		_ = fl.update(func(state *bucketState) {
+			state.release(float64(fl.Burst))
+		})
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package transport_test
+
+import (
+	"context"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSharedRateLimiterIsRegisteredByKey(t *testing.T) {
+	a, err := transport.SharedRateLimiter("key-a", 2, 2)
+	assert.Nil(t, err)
+	b, err := transport.SharedRateLimiter("key-a", 2, 2)
+	assert.Nil(t, err)
+	c, err := transport.SharedRateLimiter("key-c", 2, 2)
+	assert.Nil(t, err)
+
+	assert.Same(t, a, b)
+	assert.NotSame(t, a, c)
+
+	// The limiter cannot be shared with different settings.
+	_, err = transport.SharedRateLimiter("key-a", 10, 10)
+	assert.NotNil(t, err)
+
+	transport.ReleaseSharedRateLimiter("key-a")
+	d, err := transport.SharedRateLimiter("key-a", 10, 10)
+	assert.Nil(t, err)
+	assert.NotSame(t, a, d)
+	assert.Equal(t, float64(10), d.QPS())
+
+	transport.ReleaseSharedRateLimiter("key-a")
+	transport.ReleaseSharedRateLimiter("key-c")
+}
+
+func TestFileRateLimiterSharesBudget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "budget.json")
+
+	// Two limiters over the same file stand for two processes using the same key.
+	first, err := transport.NewFileRateLimiter(path, 10, 2)
+	assert.Nil(t, err)
+	second, err := transport.NewFileRateLimiter(path, 10, 2)
+	assert.Nil(t, err)
+
+	d, err := first.Reserve()
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), d)
+
+	d, err = second.Reserve()
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), d)
+
+	// The burst is exhausted by both limiters together
+	d, err = first.Reserve()
+	assert.Nil(t, err)
+	assert.True(t, d > time.Millisecond*90 && d <= time.Millisecond*100, "unexpected wait %s", d)
+
+	stat, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+}
+
+func TestFileRateLimiterConcurrentWait(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "budget.json")
+	ctx := context.Background()
+
+	start := time.Now()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			fl, err := transport.NewFileRateLimiter(path, 50, 1)
+			assert.Nil(t, err)
+			for j := 0; j < 3; j++ {
+				assert.Nil(t, fl.Wait(ctx))
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 9 calls at 50 QPS with burst of 1 need at least 8 intervals of 20ms
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= time.Millisecond*155, "unexpected elapsed time %s", elapsed)
+}
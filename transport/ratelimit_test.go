@@ -0,0 +1,74 @@
+package transport_test
+
+import (
+	"context"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketAllowsBurst(t *testing.T) {
+	tb := transport.NewTokenBucketLimiter(10, 3)
+
+	assert.Equal(t, time.Duration(0), tb.Reserve())
+	assert.Equal(t, time.Duration(0), tb.Reserve())
+	assert.Equal(t, time.Duration(0), tb.Reserve())
+
+	// The next token is available in 1/10 of a second, not at the next second boundary
+	d := tb.Reserve()
+	assert.True(t, d > time.Millisecond*90 && d <= time.Millisecond*100, "unexpected wait %s", d)
+
+	// Callers queue one after another
+	d = tb.Reserve()
+	assert.True(t, d > time.Millisecond*190 && d <= time.Millisecond*200, "unexpected wait %s", d)
+}
+
+func TestTokenBucketWaitIsSmooth(t *testing.T) {
+	tb := transport.NewTokenBucketLimiter(50, 1)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		assert.Nil(t, tb.Wait(ctx))
+	}
+	elapsed := time.Since(start)
+
+	// 5 calls wait for 20ms each
+	assert.True(t, elapsed >= time.Millisecond*95 && elapsed < time.Millisecond*300, "unexpected elapsed time %s", elapsed)
+}
+
+func TestTokenBucketWaitRespectsContext(t *testing.T) {
+	tb := transport.NewTokenBucketLimiter(1, 1)
+	assert.Nil(t, tb.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, tb.Wait(ctx))
+
+	// The token of the cancelled call is returned to the bucket
+	d := tb.Reserve()
+	assert.True(t, d <= time.Second && d > time.Millisecond*900, "unexpected wait %s", d)
+}
+
+func TestTransportUsesRateLimiter(t *testing.T) {
+	tb := transport.NewTokenBucketLimiter(1, 1)
+	c := transport.HttpTransport{
+		Mutex:       &sync.Mutex{},
+		MaxQPS:      1000,
+		RateLimiter: tb,
+	}
+
+	assert.Nil(t, c.WaitBeforeCall(context.Background()))
+	assert.True(t, tb.Reserve() > time.Millisecond*900)
+}
+
+func TestPerSecondLimiter(t *testing.T) {
+	ps := &transport.PerSecondLimiter{MaxQPS: 2}
+
+	assert.Equal(t, time.Duration(0), ps.DelayBeforeCall())
+	assert.Equal(t, time.Duration(0), ps.DelayBeforeCall())
+	assert.Equal(t, time.Second, ps.DelayBeforeCall())
+}
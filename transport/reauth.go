@@ -0,0 +1,53 @@
+package transport
+
+import (
+	"context"
+	"errors"
+)
+
+// reauthenticatedKey context key marking the call that was already replayed with the new access token
+const reauthenticatedKey = ".reauthenticated"
+
+// TokenInvalidator Authorizer able to discard the access token Mashery has rejected, so that the next authorization
+// yields a new token
+type TokenInvalidator interface {
+	Authorizer
+	Invalidate()
+}
+
+// rejectedToken checks whether the call has failed as Mashery didn't accept the access token
+func rejectedToken(wr *WrappedResponse, err error) bool {
+	if err != nil {
+		return errors.Is(err, ErrUnauthorized)
+	}
+	return wr != nil && kindOfStatus(wr.StatusCode, wr.Header.Get(HeaderMasheryErrorCode), wr.Header) == ErrUnauthorized
+}
+
+// ReauthenticateFunc replays the call once where Mashery responds with 401 or reports the access token as invalid,
+// and the Authorizer of the transport implements TokenInvalidator. Unless the token was already renewed by the
+// concurrent call, the rejected token is invalidated before the replay. The replayed call is not replayed again,
+// and the outcome of the replay is returned as-is.
+func ReauthenticateFunc(ctx context.Context, c *HttpTransport, next MiddlewareFunc) (*WrappedResponse, error) {
+	wr, err := next(ctx, c)
+
+	invalidator, ok := c.Authorizer.(TokenInvalidator)
+	if !ok || ctx.Value(reauthenticatedKey) != nil || ctx.Err() != nil || !rejectedToken(wr, err) {
+		return wr, err
+	}
+
+	var rejected string
+	if wr != nil && wr.Request != nil && wr.Request.Request != nil {
+		rejected = wr.Request.Request.Header.Get("Authorization")
+	}
+	if current, authErr := invalidator.HeaderAuthorization(ctx); authErr != nil || current["Authorization"] == rejected {
+		invalidator.Invalidate()
+	}
+
+	// The response that is discarded must still be read to release the connection.
+	if wr != nil {
+		_, _ = wr.Body()
+	}
+
+	noteRetry(ctx)
+	return next(context.WithValue(ctx, reauthenticatedKey, true), c)
+}
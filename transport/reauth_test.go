@@ -0,0 +1,102 @@
+package transport_test
+
+import (
+	"context"
+	"errors"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// rotatingAuthorizer issues the new token each time the current one is invalidated
+type rotatingAuthorizer struct {
+	generation    int
+	invalidations int
+}
+
+func (ra *rotatingAuthorizer) HeaderAuthorization(_ context.Context) (map[string]string, error) {
+	return map[string]string{"Authorization": "Bearer t" + strconv.Itoa(ra.generation)}, nil
+}
+
+func (ra *rotatingAuthorizer) QueryStringAuthorization(_ context.Context) (map[string]string, error) {
+	return nil, nil
+}
+
+func (ra *rotatingAuthorizer) Close() {}
+
+func (ra *rotatingAuthorizer) Invalidate() {
+	ra.invalidations++
+	ra.generation++
+}
+
+func reauthTransport(se *scriptedExecutor, auth transport.Authorizer) *transport.HttpTransport {
+	return &transport.HttpTransport{
+		Mutex:        &sync.Mutex{},
+		RateLimiter:  transport.NewTokenBucketLimiter(1000, 1000),
+		HttpExecutor: se,
+		Authorizer:   auth,
+		Pipeline: transport.BuildPipeline(transport.ExecuteFunction, []transport.ChainedMiddlewareFunc{
+			transport.ThrottleFunc,
+			transport.EnsureBodyWasRead,
+			transport.UnmarshalServerError,
+			transport.ReauthenticateFunc,
+		}),
+	}
+}
+
+func TestReauthenticateReplaysWithNewToken(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{
+		respondWith(401, nil),
+		respondWith(200, nil),
+	}}
+	auth := &rotatingAuthorizer{}
+
+	_, _, err := transport.GetObject(context.Background(), objectSpec(), reauthTransport(se, auth))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, se.calls)
+	assert.Equal(t, 1, auth.invalidations)
+}
+
+func TestReauthenticateReplaysOnInvalidTokenError(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{
+		respondWith(403, http.Header{transport.HeaderMasheryErrorCode: {transport.CodeNotAuthorized}}),
+		respondWith(200, nil),
+	}}
+	auth := &rotatingAuthorizer{}
+
+	_, _, err := transport.GetObject(context.Background(), objectSpec(), reauthTransport(se, auth))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, se.calls)
+}
+
+func TestReauthenticateReplaysOnlyOnce(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{
+		respondWith(401, nil),
+	}}
+	auth := &rotatingAuthorizer{}
+
+	_, _, err := transport.GetObject(context.Background(), objectSpec(), reauthTransport(se, auth))
+	assert.True(t, errors.Is(err, transport.ErrUnauthorized))
+	assert.Equal(t, 2, se.calls)
+	assert.Equal(t, 1, auth.invalidations)
+}
+
+func TestReauthenticateRequiresInvalidator(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{
+		respondWith(401, nil),
+		respondWith(200, nil),
+	}}
+
+	_, _, err := transport.GetObject(context.Background(), objectSpec(), reauthTransport(se, transport.NewBearerAuthorizer("t")))
+	assert.True(t, errors.Is(err, transport.ErrUnauthorized))
+	assert.Equal(t, 1, se.calls)
+}
+
+func objectSpec() transport.ObjectFetchSpec[map[string]interface{}] {
+	spec := transport.ObjectFetchSpecBuilder[map[string]interface{}]{}
+	spec.WithValueFactory(func() map[string]interface{} { return map[string]interface{}{} }).WithResource("/x")
+	return spec.Build()
+}
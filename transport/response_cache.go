@@ -0,0 +1,160 @@
+package transport
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Validators of the responses and the headers of the conditional requests
+const (
+	HeaderETag            = "ETag"
+	HeaderLastModified    = "Last-Modified"
+	HeaderIfNoneMatch     = "If-None-Match"
+	HeaderIfModifiedSince = "If-Modified-Since"
+)
+
+// CachedResponse the successful response to the GET request, stored together with its validators
+type CachedResponse struct {
+	StatusCode   int
+	Header       http.Header
+	Body         []byte
+	ETag         string
+	LastModified string
+	StoredAt     time.Time
+}
+
+// ResponseCache stores the responses carrying ETag or Last-Modified validators. The transport uses the stored
+// validators to make the GET requests conditional; a 304 response is then served from the store.
+// The key is the request URL.
+type ResponseCache interface {
+	Get(key string) (*CachedResponse, bool)
+	Put(key string, resp *CachedResponse)
+	Delete(key string)
+}
+
+// MemoryResponseCache in-memory ResponseCache keeping up to MaxEntries recently used responses
+type MemoryResponseCache struct {
+	MaxEntries int
+
+	mutex   sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+type memoryCacheEntry struct {
+	key  string
+	resp *CachedResponse
+}
+
+// NewMemoryResponseCache creates the in-memory cache; zero maxEntries means the number of entries is not limited.
+func NewMemoryResponseCache(maxEntries int) *MemoryResponseCache {
+	return &MemoryResponseCache{
+		MaxEntries: maxEntries,
+		lru:        list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+func (mc *MemoryResponseCache) Get(key string) (*CachedResponse, bool) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	if el, ok := mc.entries[key]; ok {
+		mc.lru.MoveToFront(el)
+		return el.Value.(*memoryCacheEntry).resp, true
+	}
+	return nil, false
+}
+
+func (mc *MemoryResponseCache) Put(key string, resp *CachedResponse) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	if el, ok := mc.entries[key]; ok {
+		el.Value.(*memoryCacheEntry).resp = resp
+		mc.lru.MoveToFront(el)
+		return
+	}
+
+	mc.entries[key] = mc.lru.PushFront(&memoryCacheEntry{key: key, resp: resp})
+	for mc.MaxEntries > 0 && mc.lru.Len() > mc.MaxEntries {
+		last := mc.lru.Back()
+		mc.lru.Remove(last)
+		delete(mc.entries, last.Value.(*memoryCacheEntry).key)
+	}
+}
+
+func (mc *MemoryResponseCache) Delete(key string) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	if el, ok := mc.entries[key]; ok {
+		mc.lru.Remove(el)
+		delete(mc.entries, key)
+	}
+}
+
+// makeConditional adds the validators of the stored response to the request
+func makeConditional(req *http.Request, cached *CachedResponse) {
+	if len(cached.ETag) > 0 {
+		req.Header.Set(HeaderIfNoneMatch, cached.ETag)
+	}
+	if len(cached.LastModified) > 0 {
+		req.Header.Set(HeaderIfModifiedSince, cached.LastModified)
+	}
+}
+
+// serveNotModified builds the response from the stored one, refreshing the headers sent with 304
+func serveNotModified(wr *WrappedResponse, cached *CachedResponse) *WrappedResponse {
+	_, _ = wr.Body()
+
+	hdr := cached.Header.Clone()
+	for k, v := range wr.Header {
+		if k != "Content-Length" && k != "Content-Type" {
+			hdr[k] = v
+		}
+	}
+
+	return &WrappedResponse{
+		Request: wr.Request,
+		Response: &http.Response{
+			Status:     http.StatusText(cached.StatusCode),
+			StatusCode: cached.StatusCode,
+			Header:     hdr,
+			Body:       io.NopCloser(bytes.NewReader(cached.Body)),
+			Request:    wr.Response.Request,
+		},
+		StatusCode:   cached.StatusCode,
+		Header:       hdr,
+		FromCache:    true,
+		Received:     wr.Received,
+		ETag:         hdr.Get(HeaderETag),
+		LastModified: hdr.Get(HeaderLastModified),
+	}
+}
+
+// storeResponse stores the successful response carrying the validators
+func storeResponse(cache ResponseCache, key string, wr *WrappedResponse) {
+	if len(wr.ETag) == 0 && len(wr.LastModified) == 0 {
+		cache.Delete(key)
+		return
+	}
+
+	body, err := wr.Body()
+	if err != nil {
+		return
+	}
+
+	cache.Put(key, &CachedResponse{
+		StatusCode:   wr.StatusCode,
+		Header:       wr.Header.Clone(),
+		Body:         body,
+		ETag:         wr.ETag,
+		LastModified: wr.LastModified,
+		StoredAt:     time.Now(),
+	})
+}
@@ -0,0 +1,118 @@
+package transport_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// etagExecutor serves the object with the current entity tag, and responds with 304 to the matching
+// conditional requests.
+type etagExecutor struct {
+	etag        string
+	body        string
+	calls       int
+	conditional int
+}
+
+func (ee *etagExecutor) Do(req *http.Request) (*http.Response, error) {
+	ee.calls++
+	if inm := req.Header.Get(transport.HeaderIfNoneMatch); len(inm) > 0 {
+		ee.conditional++
+		if inm == ee.etag {
+			return &http.Response{
+				StatusCode: http.StatusNotModified,
+				Header:     http.Header{http.CanonicalHeaderKey(transport.HeaderETag): {ee.etag}},
+				Body:       io.NopCloser(bytes.NewReader(nil)),
+			}, nil
+		}
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			http.CanonicalHeaderKey(transport.HeaderETag): {ee.etag},
+			"Content-Type": {"application/json"},
+		},
+		Body: io.NopCloser(bytes.NewReader([]byte(ee.body))),
+	}, nil
+}
+
+func (ee *etagExecutor) CloseIdleConnections() {}
+
+func cachingTransport(ee *etagExecutor, cache transport.ResponseCache) *transport.HttpTransport {
+	return &transport.HttpTransport{
+		Mutex:         &sync.Mutex{},
+		RateLimiter:   transport.NewTokenBucketLimiter(1000, 1000),
+		HttpExecutor:  ee,
+		ResponseCache: cache,
+		Pipeline: transport.BuildPipeline(transport.ExecuteFunction, []transport.ChainedMiddlewareFunc{
+			transport.ThrottleFunc,
+			transport.EnsureBodyWasRead,
+			transport.UnmarshalServerError,
+		}),
+	}
+}
+
+func fetchName(t *testing.T, c *transport.HttpTransport) string {
+	spec := transport.ObjectFetchSpecBuilder[map[string]interface{}]{}
+	spec.WithValueFactory(func() map[string]interface{} { return map[string]interface{}{} }).WithResource("/x")
+
+	rv, exists, err := transport.GetObject(context.Background(), spec.Build(), c)
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	return rv["name"].(string)
+}
+
+func TestConditionalFetchServesNotModifiedFromCache(t *testing.T) {
+	ee := &etagExecutor{etag: `"v1"`, body: `{"name":"first"}`}
+	c := cachingTransport(ee, transport.NewMemoryResponseCache(10))
+
+	assert.Equal(t, "first", fetchName(t, c))
+	assert.Equal(t, "first", fetchName(t, c))
+	assert.Equal(t, 2, ee.calls)
+	assert.Equal(t, 1, ee.conditional)
+
+	ee.etag, ee.body = `"v2"`, `{"name":"second"}`
+	assert.Equal(t, "second", fetchName(t, c))
+	assert.Equal(t, "second", fetchName(t, c))
+	assert.Equal(t, 4, ee.calls)
+	assert.Equal(t, 3, ee.conditional)
+}
+
+func TestFetchSurfacesValidators(t *testing.T) {
+	ee := &etagExecutor{etag: `"v1"`, body: `{"name":"first"}`}
+	c := cachingTransport(ee, transport.NewMemoryResponseCache(10))
+
+	wr, err := c.Fetch(context.Background(), "/x")
+	assert.Nil(t, err)
+	assert.Equal(t, `"v1"`, wr.ETag)
+	assert.False(t, wr.FromCache)
+
+	wr, err = c.Fetch(context.Background(), "/x")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, wr.StatusCode)
+	assert.Equal(t, `"v1"`, wr.ETag)
+	assert.True(t, wr.FromCache)
+	assert.Equal(t, `{"name":"first"}`, string(wr.MustBody()))
+}
+
+func TestMemoryResponseCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	mc := transport.NewMemoryResponseCache(2)
+	mc.Put("a", &transport.CachedResponse{ETag: "a"})
+	mc.Put("b", &transport.CachedResponse{ETag: "b"})
+	_, _ = mc.Get("a")
+	mc.Put("c", &transport.CachedResponse{ETag: "c"})
+
+	_, found := mc.Get("b")
+	assert.False(t, found)
+	_, found = mc.Get("a")
+	assert.True(t, found)
+	_, found = mc.Get("c")
+	assert.True(t, found)
+}
@@ -0,0 +1,114 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// RetryAttemptKey context key holding the number of the attempt, starting from 1, of the call made under
+// the RetryPolicy. Exchange listeners can read it with RetryAttempt.
+const RetryAttemptKey = ".retry.attempt"
+
+// RetryAttempt returns the number of the attempt the exchange belongs to; 1 for the first attempt and for
+// the calls not made under a RetryPolicy.
+func RetryAttempt(ctx context.Context) int {
+	if v := ctx.Value(RetryAttemptKey); v != nil {
+		if i, ok := v.(int); ok {
+			return i
+		}
+	}
+	return 1
+}
+
+// RetryPolicy retries the calls failing with the transient network errors or with retryable status codes.
+// Calls with non-idempotent methods (POST, PATCH) are replayed only where RetryNonIdempotent is set, as
+// Mashery could have processed the failed call.
+//
+// The policy should be placed in the pipeline directly after ThrottleFunc, so that each attempt is throttled.
+// Each attempt is reported to the transport's ExchangeListener; the attempt number is available from
+// the context with RetryAttempt.
+type RetryPolicy struct {
+	// MaxAttempts the total number of attempts, including the first one
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	// RetryableStatuses the status codes indicating the call can be retried
+	RetryableStatuses  []int
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy makes up to 3 attempts for network errors and 502, 503 and 504 responses
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:       3,
+		BaseBackoff:       time.Millisecond * 500,
+		MaxBackoff:        time.Second * 10,
+		RetryableStatuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	}
+}
+
+// IsIdempotent checks whether repeating the call with this method has the same effect as making it once
+func IsIdempotent(method string) bool {
+	return method != http.MethodPost && method != http.MethodPatch
+}
+
+func (rp *RetryPolicy) retryableStatus(code int) bool {
+	for _, s := range rp.RetryableStatuses {
+		if s == code {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldRetry determines whether the outcome of the attempt is a transient failure that can be retried
+func (rp *RetryPolicy) shouldRetry(ctx context.Context, wr *WrappedResponse, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var method string
+	if err != nil {
+		var v3Err *V3Error
+		if !errors.As(err, &v3Err) || v3Err.Kind != ErrTransient || v3Err.StatusCode != 0 {
+			return false
+		}
+		method = v3Err.Method
+	} else if wr != nil && rp.retryableStatus(wr.StatusCode) {
+		if wr.Request != nil && wr.Request.Request != nil {
+			method = wr.Request.Request.Method
+		}
+	} else {
+		return false
+	}
+
+	return rp.RetryNonIdempotent || IsIdempotent(method)
+}
+
+// Func is the ChainedMiddlewareFunc applying the policy
+func (rp *RetryPolicy) Func(ctx context.Context, c *HttpTransport, next MiddlewareFunc) (*WrappedResponse, error) {
+	attempts := rp.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for i := 1; ; i++ {
+		wr, err := next(context.WithValue(ctx, RetryAttemptKey, i), c)
+		if i >= attempts || !rp.shouldRetry(ctx, wr, err) {
+			return wr, err
+		}
+
+		// The response that is discarded must still be read to release the connection.
+		if wr != nil {
+			_, _ = wr.Body()
+		}
+
+		if waitErr := waitFor(ctx, jitteredBackoff(rp.BaseBackoff, rp.MaxBackoff, i-1)); waitErr != nil {
+			return wr, err
+		}
+		noteRetry(ctx)
+	}
+}
@@ -0,0 +1,118 @@
+package transport_test
+
+import (
+	"context"
+	"errors"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyExecutor fails the first calls with the network error, then delegates to the scripted executor
+type flakyExecutor struct {
+	scriptedExecutor
+	failures int
+}
+
+func (fe *flakyExecutor) Do(r *http.Request) (*http.Response, error) {
+	if fe.failures > 0 {
+		fe.failures--
+		fe.calls++
+		return nil, errors.New("connection reset by peer")
+	}
+	return fe.scriptedExecutor.Do(r)
+}
+
+func retryTransport(exec transport.HttpExecutor, rp *transport.RetryPolicy, listener transport.ExchangeListener) *transport.HttpTransport {
+	return &transport.HttpTransport{
+		Mutex:            &sync.Mutex{},
+		RateLimiter:      transport.NewTokenBucketLimiter(1000, 1000),
+		HttpExecutor:     exec,
+		ExchangeListener: listener,
+		Pipeline: transport.BuildPipeline(transport.ExecuteFunction, []transport.ChainedMiddlewareFunc{
+			transport.ThrottleFunc,
+			rp.Func,
+			transport.EnsureBodyWasRead,
+			transport.UnmarshalServerError,
+		}),
+	}
+}
+
+func fastRetryPolicy() *transport.RetryPolicy {
+	rp := transport.DefaultRetryPolicy()
+	rp.BaseBackoff = time.Millisecond
+	rp.MaxBackoff = time.Millisecond * 5
+	return rp
+}
+
+func TestRetryPolicyRetriesRetryableStatus(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{
+		respondWith(503, nil),
+		respondWith(502, nil),
+		respondWith(200, nil),
+	}}
+
+	var attempts []int
+	listener := func(ctx context.Context, _ *transport.WrappedRequest, _ *transport.WrappedResponse, _ error) {
+		attempts = append(attempts, transport.RetryAttempt(ctx))
+	}
+
+	exists, err := fetchOnce(retryTransport(se, fastRetryPolicy(), listener))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, []int{1, 2, 3}, attempts)
+}
+
+func TestRetryPolicyGivesUpAfterMaxAttempts(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{respondWith(504, nil)}}
+
+	_, err := fetchOnce(retryTransport(se, fastRetryPolicy(), nil))
+	assert.True(t, errors.Is(err, transport.ErrTransient))
+	assert.Equal(t, 3, se.calls)
+}
+
+func TestRetryPolicyRetriesNetworkErrors(t *testing.T) {
+	fe := &flakyExecutor{
+		scriptedExecutor: scriptedExecutor{responses: []func() *http.Response{respondWith(200, nil)}},
+		failures:         2,
+	}
+
+	exists, err := fetchOnce(retryTransport(fe, fastRetryPolicy(), nil))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, 3, fe.calls)
+}
+
+func TestRetryPolicyDoesNotRetryOtherFailures(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{respondWith(500, nil), respondWith(200, nil)}}
+
+	_, err := fetchOnce(retryTransport(se, fastRetryPolicy(), nil))
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, se.calls)
+}
+
+func TestRetryPolicyDoesNotReplayPost(t *testing.T) {
+	se := &scriptedExecutor{responses: []func() *http.Response{respondWith(503, nil), respondWith(200, nil)}}
+	c := retryTransport(se, fastRetryPolicy(), nil)
+
+	spec := transport.ObjectUpsertSpecBuilder[map[string]interface{}]{}
+	spec.WithUpsert(map[string]interface{}{"name": "x"}).
+		WithValueFactory(func() map[string]interface{} { return map[string]interface{}{} }).
+		WithResource("/services")
+
+	_, err := transport.CreateObject(context.Background(), spec.Build(), c)
+	assert.True(t, errors.Is(err, transport.ErrTransient))
+	assert.Equal(t, 1, se.calls)
+
+	// Unless explicitly permitted
+	se.calls = 0
+	rp := fastRetryPolicy()
+	rp.RetryNonIdempotent = true
+	c = retryTransport(se, rp, nil)
+
+	_, err = transport.CreateObject(context.Background(), spec.Build(), c)
+	assert.Nil(t, err)
+}
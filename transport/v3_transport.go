@@ -6,10 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/errwrap"
-	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
-	"net/http"
 	"net/url"
 	"strconv"
+	"sync"
 )
 
 type MiddlewareFunc func(ctx context.Context, transport *HttpTransport) (*WrappedResponse, error)
@@ -99,7 +98,10 @@ func FetchAll[T any](ctx context.Context, opCtx ObjectListFetchSpec[T], c *HttpT
 	return rv, err
 }
 
-// FetchAllWithExists Fetch all Mashery objects, including the handling for the pagination
+// FetchAllWithExists Fetch all Mashery objects, including the handling for the pagination. The first page is
+// fetched to learn the total count of objects; the remaining pages are then fetched by at most
+// HttpTransport.MaxConcurrentFetches concurrent workers. The first error cancels the page fetches that are
+// still outstanding. The returned objects retain the order of the pages.
 func FetchAllWithExists[T any](ctx context.Context, opCtx ObjectListFetchSpec[T], c *HttpTransport) ([]T, bool, error) {
 
 	firstPageData, firstPageResponse, firstPageFetchErr := performGenericObjectCRUDWithResponse[[]T](ctx, c, opCtx.AsObjectFetchSpec(), func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
@@ -113,7 +115,6 @@ func FetchAllWithExists[T any](ctx context.Context, opCtx ObjectListFetchSpec[T]
 	}
 
 	rv := firstPageData
-	var collErr error
 
 	pageSize := len(rv)
 	// Don't try doing anything else if the response contains no data.
@@ -122,56 +123,97 @@ func FetchAllWithExists[T any](ctx context.Context, opCtx ObjectListFetchSpec[T]
 	}
 
 	totalCountHdr := firstPageResponse.Header.Get("X-Total-Count")
-	//fmt.Println(totalCountHdr)
+	if len(totalCountHdr) == 0 {
+		return rv, true, nil
+	}
 
-	if len(totalCountHdr) > 0 {
-		totalCount, _ := strconv.ParseInt(totalCountHdr, 10, 0)
+	totalCount, _ := strconv.ParseInt(totalCountHdr, 10, 0)
+	if totalCount <= int64(pageSize) {
+		return rv, true, nil
+	}
+
+	pages, err := fetchRemainingPages(ctx, opCtx, c, pageSize, int((totalCount-1)/int64(pageSize)))
+	for _, page := range pages {
+		rv = append(rv, page...)
+	}
+
+	return rv, true, err
+}
+
+// fetchRemainingPages fetches the pages following the first one with the bounded number of workers. The pages
+// are returned in the order of their offsets.
+func fetchRemainingPages[T any](ctx context.Context, opCtx ObjectListFetchSpec[T], c *HttpTransport, pageSize int, numPages int) ([][]T, error) {
+	pages := make([][]T, numPages)
+
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var firstErr error
+	errOnce := sync.Once{}
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	work := make(chan int)
+	wg := sync.WaitGroup{}
 
-		if totalCount > int64(pageSize) {
-			allFetches := int(totalCount / int64(pageSize))
+	workers := c.fetchParallelism()
+	if workers > numPages {
+		workers = numPages
+	}
 
-			commChan := make(chan AsyncFetchResult[[]T])
-			defer close(commChan)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
 
-			for p := 1; p <= allFetches; p++ {
-				offset := p
+			for idx := range work {
+				offset := idx + 1
 				if opCtx.Pagination == PerItem {
 					offset *= pageSize
 				}
 
-				offsetParam := url.Values{
-					"offset": {strconv.Itoa(offset)},
-				}
-				//fmt.Println(offsetParam)
-
 				pageFetchSpec := opCtx.ToBuilder()
-				pageFetchSpec.WithMergedQuery(offsetParam)
-
-				go AsyncFetch(ctx, pageFetchSpec.Build().AsObjectFetchSpec(), c, commChan)
-			}
-
-			for p := 1; p <= allFetches; p++ {
-				asyncRead := <-commChan
-				if asyncRead.Err != nil {
-					collErr = asyncRead.Err
-					// TODO: if error occurred, we might need to terminate the rest
-					// of the fetching operations.
+				pageFetchSpec.WithMergedQuery(url.Values{
+					"offset": {strconv.Itoa(offset)},
+				})
+				pageSpec := pageFetchSpec.Build().AsObjectFetchSpec()
+
+				data, err := performGenericObjectCRUD(fetchCtx, c, pageSpec, func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
+					return c.Fetch(ctx, pageSpec.DestResource())
+				})
+
+				if err != nil {
+					fail(err)
+				} else if data == nil {
+					fail(&errwrap.WrappedError{
+						Context: fmt.Sprintf("fetch all %s->read page at offset %d", opCtx.AppContext, offset),
+						Cause:   errors.New("nil response received"),
+					})
 				} else {
-					if asyncRead.Data == nil {
-						collErr = &errwrap.WrappedError{
-							Context: fmt.Sprintf("fetch all %s->read async response", opCtx.AppContext),
-							Cause:   errors.New("nil response received"),
-						}
-						// TODO Terminate making any remaining calls to the server
-					} else {
-						rv = append(rv, asyncRead.Data...)
-					}
+					pages[idx] = data
 				}
 			}
+		}()
+	}
+
+	for idx := 0; idx < numPages && fetchCtx.Err() == nil; idx++ {
+		select {
+		case work <- idx:
+		case <-fetchCtx.Done():
 		}
 	}
+	close(work)
+	wg.Wait()
 
-	return rv, true, collErr
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+
+	return pages, firstErr
 }
 
 type CallFunc func(ctx context.Context) (*WrappedResponse, error)
@@ -204,7 +246,8 @@ func executeCallPipeline(ctx context.Context, c *HttpTransport, execFunc Middlew
 }
 
 func performGenericObjectCRUDWithResponse[T any](entryCtx context.Context, c *HttpTransport, opCtx ObjectFetchSpec[T], f MiddlewareFunc) (T, *WrappedResponse, error) {
-	ctx := entryCtx
+	ctx := context.WithValue(entryCtx, ResourceFamilyKey, ResourceFamilyOf(opCtx.Resource))
+	ctx = context.WithValue(ctx, AppContextKey, opCtx.AppContext)
 	if !opCtx.Return404AsNil {
 		ctx = context.WithValue(ctx, SendErrorOn404, true)
 	}
@@ -216,10 +259,11 @@ func performGenericObjectCRUDWithResponse[T any](entryCtx context.Context, c *Ht
 
 		if wr.StatusCode == 200 && !opCtx.IgnoreResponse {
 			if jsonErr := json.Unmarshal(wr.MustBody(), &rv); jsonErr != nil {
-				return rv, wr, &errwrap.WrappedError{
-					Context: fmt.Sprintf("%s %s->unmarshal response", wr.Request.Request.Method, opCtx.AppContext),
-					Cause:   jsonErr,
-				}
+				return rv, wr, (&V3Error{
+					StatusCode: wr.StatusCode,
+					Message:    fmt.Sprintf("%s->unmarshal response", opCtx.AppContext),
+					Cause:      jsonErr,
+				}).withRequest(wr)
 			}
 		}
 
@@ -234,6 +278,7 @@ func (c *HttpTransport) Count(ctx context.Context, opCtx CommonFetchSpec) (int64
 		"limit": {"1"},
 	}).Build()
 
+	ctx = context.WithValue(ctx, AppContextKey, countSpec.AppContext)
 	if cnt, err := c.Fetch(ctx, countSpec.DestResource()); err != nil {
 		return -1, &errwrap.WrappedError{
 			Context: fmt.Sprintf("count %s->fetch count", countSpec.AppContext),
@@ -246,57 +291,7 @@ func (c *HttpTransport) Count(ctx context.Context, opCtx CommonFetchSpec) (int64
 }
 
 func v3BasicError(wr *WrappedResponse) error {
-	// Did we receive a generic error?
-	var rv masherytypes.V3GenericErrorResponse
-	if err := json.Unmarshal(wr.MustBody(), &rv); err == nil && rv.HasData() {
-		return &rv
-	}
-
-	// Did we receive at least one error?
-	var propRv masherytypes.V3PropertyErrorMessages
-	if err := json.Unmarshal(wr.MustBody(), &propRv); err == nil && len(propRv.Errors) > 0 {
-		return &propRv
-	}
-
-	// The error is not really know; so the output would be printed in the output
-
-	return &masherytypes.V3UndeterminedError{
-		Code:   wr.StatusCode,
-		Header: wr.Header,
-		Body:   wr.MustBody(),
-	}
-}
-
-func v3ErrorFromResponse(context string, code int, headers http.Header, data []byte) error {
-	uCtx := fmt.Sprintf("%s->api call", context)
-
-	// Did we receive a generic error?
-	var rv masherytypes.V3GenericErrorResponse
-	if err := json.Unmarshal(data, &rv); err == nil && rv.HasData() {
-		return &errwrap.WrappedError{
-			Context: uCtx,
-			Cause:   &rv,
-		}
-	}
-
-	// Did we receive at least one error?
-	var propRv masherytypes.V3PropertyErrorMessages
-	if err := json.Unmarshal(data, &propRv); err == nil && len(propRv.Errors) > 0 {
-		return &errwrap.WrappedError{
-			Context: uCtx,
-			Cause:   &propRv,
-		}
-	}
-
-	// The error is not really know; so the output would be printed in the output
-	return &errwrap.WrappedError{
-		Context: uCtx,
-		Cause: &masherytypes.V3UndeterminedError{
-			Code:   code,
-			Header: headers,
-			Body:   data,
-		},
-	}
+	return ResponseError(wr)
 }
 
 // Extract Mashery-supplied total count of elements from this response
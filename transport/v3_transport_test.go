@@ -0,0 +1,132 @@
+package transport_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// pagedExecutor serves a collection of integers in pages of the fixed size, using item-based offsets.
+type pagedExecutor struct {
+	total    int
+	pageSize int
+	delay    time.Duration
+	failAt   int
+
+	inFlight    int32
+	maxInFlight int32
+	calls       int32
+}
+
+func (pe *pagedExecutor) Do(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&pe.calls, 1)
+	cur := atomic.AddInt32(&pe.inFlight, 1)
+	defer atomic.AddInt32(&pe.inFlight, -1)
+
+	for {
+		prev := atomic.LoadInt32(&pe.maxInFlight)
+		if cur <= prev || atomic.CompareAndSwapInt32(&pe.maxInFlight, prev, cur) {
+			break
+		}
+	}
+
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	// Later pages are answered faster, so that the responses arrive out of order.
+	select {
+	case <-time.After(pe.delay * time.Duration(pe.total-offset) / time.Duration(pe.total)):
+	case <-r.Context().Done():
+		return nil, r.Context().Err()
+	}
+
+	if pe.failAt > 0 && offset == pe.failAt {
+		return nil, errors.New("page fetch failed")
+	}
+
+	var page []int
+	for i := offset; i < offset+pe.pageSize && i < pe.total; i++ {
+		page = append(page, i)
+	}
+
+	buf := bytes.Buffer{}
+	buf.WriteString("[")
+	for i, v := range page {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString(strconv.Itoa(v))
+	}
+	buf.WriteString("]")
+
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"X-Total-Count": {strconv.Itoa(pe.total)}},
+		Body:       io.NopCloser(&buf),
+	}, nil
+}
+
+func (pe *pagedExecutor) CloseIdleConnections() {}
+
+func pagedTransport(pe *pagedExecutor, parallelism int) *transport.HttpTransport {
+	return &transport.HttpTransport{
+		Mutex:                &sync.Mutex{},
+		MaxQPS:               1000,
+		MaxConcurrentFetches: parallelism,
+		HttpExecutor:         pe,
+		Pipeline: transport.BuildPipeline(transport.ExecuteFunction, []transport.ChainedMiddlewareFunc{
+			transport.EnsureBodyWasRead,
+			transport.UnmarshalServerError,
+		}),
+	}
+}
+
+func pagedSpec() transport.ObjectListFetchSpec[int] {
+	b := transport.ObjectListFetchSpecBuilder[int]{}
+	b.WithValueFactory(func() []int { return []int{} })
+	b.WithResource("/numbers").WithPagination(transport.PerItem).WithAppContext("numbers")
+	return b.Build()
+}
+
+func TestFetchAllPreservesPageOrder(t *testing.T) {
+	pe := &pagedExecutor{total: 53, pageSize: 5, delay: time.Millisecond * 20}
+
+	rv, exists, err := transport.FetchAllWithExists(context.Background(), pagedSpec(), pagedTransport(pe, 3))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, 53, len(rv))
+	for i, v := range rv {
+		assert.Equal(t, i, v, fmt.Sprintf("element %d", i))
+	}
+
+	assert.Equal(t, int32(11), pe.calls)
+	assert.True(t, pe.maxInFlight <= 3, "at most 3 concurrent fetches expected, got %d", pe.maxInFlight)
+}
+
+func TestFetchAllDoesNotRequestPageBeyondTotal(t *testing.T) {
+	pe := &pagedExecutor{total: 20, pageSize: 5}
+
+	rv, _, err := transport.FetchAllWithExists(context.Background(), pagedSpec(), pagedTransport(pe, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, 20, len(rv))
+	assert.Equal(t, int32(4), pe.calls)
+}
+
+func TestFetchAllCancelsRemainingPagesOnError(t *testing.T) {
+	pe := &pagedExecutor{total: 500, pageSize: 5, delay: time.Millisecond * 10, failAt: 5}
+
+	_, _, err := transport.FetchAllWithExists(context.Background(), pagedSpec(), pagedTransport(pe, 2))
+	assert.NotNil(t, err)
+	assert.True(t, errors.Is(err, transport.ErrTransient))
+	assert.Equal(t, "GET /numbers?offset=5: page fetch failed", err.Error())
+	assert.True(t, pe.calls < 10, "remaining pages should not be fetched, but %d calls were made", pe.calls)
+}
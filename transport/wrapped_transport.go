@@ -3,6 +3,7 @@ package transport
 import (
 	"net/http"
 	"sync"
+	"time"
 )
 
 // WrappedResponse Wraps the response so that calling applications can safely read the body multiple times.
@@ -11,6 +12,15 @@ type WrappedResponse struct {
 	Response   *http.Response
 	StatusCode int
 	Header     http.Header
+	// FromCache the response was served from the transport's ResponseCache after Mashery confirmed, with 304,
+	// it has not been modified
+	FromCache bool
+	// Received the time the response headers were received at
+	Received time.Time
+	// ETag the entity tag validator of the response, if any
+	ETag string
+	// LastModified the Last-Modified validator of the response, if any
+	LastModified string
 
 	once sync.Once
 
@@ -21,6 +31,8 @@ type WrappedResponse struct {
 type WrappedRequest struct {
 	Request *http.Request
 	Body    interface{}
+	// Sent the time the request was sent at
+	Sent time.Time
 }
 
 func (wr *WrappedResponse) Body() ([]byte, error) {
@@ -85,7 +85,9 @@ func (ci *ClientImpl) Close(ctx context.Context) {
 
 func (ci *ClientImpl) GetRawResponse(ctx context.Context, req V2Request) (*transport.WrappedResponse, error) {
 	// Implement rate-controls
-	time.Sleep(ci.transport.DelayBeforeCall())
+	if err := ci.transport.WaitBeforeCall(ctx); err != nil {
+		return nil, err
+	}
 
 	m, _ := ci.transport.Authorizer.QueryStringAuthorization(ctx)
 	qs := url.Values{}
@@ -110,6 +112,11 @@ type Params struct {
 	QPS            int64
 	TravelTimeComp time.Duration
 
+	// RateLimiter explicit rate limiter. If not set, a token bucket limiter permitting QPS calls per second
+	// with the burst of QPS calls is used; the earlier versions allocated the calls to wall-clock seconds
+	// instead, which transport.PerSecondLimiter still does where set here.
+	RateLimiter transport.RateLimiter
+
 	MasheryEndpoint string
 }
 
@@ -134,6 +141,9 @@ func (h *Params) FillDefaults() error {
 	if h.Timeout == 0 {
 		h.Timeout = time.Second * 60
 	}
+	if h.RateLimiter == nil {
+		h.RateLimiter = transport.NewTokenBucketLimiter(float64(h.QPS), int(h.QPS))
+	}
 
 	return nil
 }
@@ -154,5 +164,6 @@ func NewHTTPClient(params Params) Client {
 			HttpExecutor: params.CreateHttpExecutor(),
 			Mutex:        &sync.Mutex{},
 			MaxQPS:       params.QPS,
+			RateLimiter:  params.RateLimiter,
 		}}
 }
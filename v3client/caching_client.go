@@ -0,0 +1,669 @@
+package v3client
+
+import (
+	"container/list"
+	"context"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of the objects cached by the CachingClient. Each kind has its own CachePolicy and statistics.
+const (
+	CacheDomains               = "domains"
+	CacheRoles                 = "roles"
+	CacheServices              = "services"
+	CacheEndpoints             = "endpoints"
+	CacheEndpointMethods       = "endpointMethods"
+	CacheEndpointMethodFilters = "endpointMethodFilters"
+	// CacheServiceSettings service cache, OAuth security profile, roles and error sets of the service
+	CacheServiceSettings = "serviceSettings"
+	CachePackages        = "packages"
+	CachePlans           = "plans"
+)
+
+// CachePolicy how long, and how many, objects of a kind are kept in the cache. The kind with zero TTL is not cached;
+// zero MaxEntries means the number of entries is not limited.
+type CachePolicy struct {
+	TTL        time.Duration
+	MaxEntries int
+}
+
+// CacheStats the statistics of the cache usage
+type CacheStats struct {
+	Hits          int64
+	Misses        int64
+	Evictions     int64
+	Invalidations int64
+}
+
+// CachingClientParams cache policies of the CachingClient. Policies override the Default for individual kinds.
+type CachingClientParams struct {
+	Default  CachePolicy
+	Policies map[string]CachePolicy
+}
+
+// DefaultCachingClientParams caches the objects for 5 minutes, and the domains and roles, which change rarely,
+// for 15 minutes; up to 1000 objects of each kind.
+func DefaultCachingClientParams() CachingClientParams {
+	return CachingClientParams{
+		Default: CachePolicy{TTL: time.Minute * 5, MaxEntries: 1000},
+		Policies: map[string]CachePolicy{
+			CacheDomains: {TTL: time.Minute * 15, MaxEntries: 10},
+			CacheRoles:   {TTL: time.Minute * 15, MaxEntries: 1000},
+		},
+	}
+}
+
+type cacheEntry struct {
+	kind    string
+	key     string
+	path    string
+	value   interface{}
+	exists  bool
+	expires time.Time
+	elem    *list.Element
+}
+
+// pendingFetch the object being read from the wrapped client. The fetch becomes stale where the object
+// is invalidated before the read completes, so that the value read before the change is not cached.
+type pendingFetch struct {
+	path  string
+	stale bool
+}
+
+type cacheShelf struct {
+	lru   *list.List
+	stats CacheStats
+}
+
+// CachingClient read-through cache of the objects that are read repeatedly, such as services, endpoints and roles.
+// The objects are cached under their resource path and the fields requested with ReturnFields; creating or updating
+// the object through this client drops the object and the objects containing it from the cache, e.g. updating
+// an endpoint drops the endpoint, the endpoint list and the service with its endpoints; deleting the object also
+// drops the objects it contains, e.g. the endpoint's methods. The changes made by other clients become visible
+// once the cached objects expire.
+//
+// The cached objects are shared between the callers and must not be modified. Methods that are not cached
+// are passed to the wrapped Client.
+type CachingClient struct {
+	Client
+
+	params  CachingClientParams
+	mutex   sync.Mutex
+	entries map[string]*cacheEntry
+	shelves map[string]*cacheShelf
+	pending map[*pendingFetch]bool
+	now     func() time.Time
+}
+
+// NewCachingClient wraps the client with the read-through cache
+func NewCachingClient(cl Client, params CachingClientParams) *CachingClient {
+	return &CachingClient{
+		Client:  cl,
+		params:  params,
+		entries: map[string]*cacheEntry{},
+		shelves: map[string]*cacheShelf{},
+		pending: map[*pendingFetch]bool{},
+		now:     time.Now,
+	}
+}
+
+func (cc *CachingClient) policy(kind string) CachePolicy {
+	if p, ok := cc.params.Policies[kind]; ok {
+		return p
+	}
+	return cc.params.Default
+}
+
+func (cc *CachingClient) shelf(kind string) *cacheShelf {
+	rv, ok := cc.shelves[kind]
+	if !ok {
+		rv = &cacheShelf{lru: list.New()}
+		cc.shelves[kind] = rv
+	}
+	return rv
+}
+
+func (cc *CachingClient) remove(e *cacheEntry) {
+	cc.shelf(e.kind).lru.Remove(e.elem)
+	delete(cc.entries, e.key)
+}
+
+// keyPath the resource path of the object cached under the key
+func keyPath(key string) string {
+	if idx := strings.IndexAny(key, "#?"); idx >= 0 {
+		return key[:idx]
+	}
+	return key
+}
+
+// cacheKey appends the fields requested with ReturnFields to the key, so that the objects read with only some
+// of the fields are not served to the callers expecting the default fields
+func cacheKey(ctx context.Context, key string) string {
+	if v, ok := ctx.Value(fieldsContextKey).([]string); ok {
+		return key + "?fields=" + strings.Join(v, ",")
+	}
+	return key
+}
+
+// invalidates checks whether invalidating the path drops the object at objectPath
+func invalidates(path string, descendants bool, objectPath string) bool {
+	return objectPath == path || strings.HasPrefix(path, objectPath+"/") ||
+		(descendants && strings.HasPrefix(objectPath, path+"/"))
+}
+
+// lookup returns the cached entry or, where the object is not cached, registers the pending fetch of it
+func (cc *CachingClient) lookup(kind, key string) (*cacheEntry, *pendingFetch) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	sh := cc.shelf(kind)
+	if e, ok := cc.entries[key]; ok {
+		if cc.now().Before(e.expires) {
+			sh.lru.MoveToFront(e.elem)
+			sh.stats.Hits++
+			return e, nil
+		}
+		cc.remove(e)
+	}
+
+	sh.stats.Misses++
+	if cc.pending == nil {
+		cc.pending = map[*pendingFetch]bool{}
+	}
+	pf := &pendingFetch{path: keyPath(key)}
+	cc.pending[pf] = true
+	return nil, pf
+}
+
+// store completes the pending fetch and caches its value, unless the object was invalidated during the fetch
+// or the fetch has failed
+func (cc *CachingClient) store(kind, key string, pf *pendingFetch, value interface{}, exists bool, err error) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	delete(cc.pending, pf)
+
+	pol := cc.policy(kind)
+	if pol.TTL <= 0 || pf.stale || err != nil {
+		return
+	}
+
+	if e, ok := cc.entries[key]; ok {
+		cc.remove(e)
+	}
+
+	sh := cc.shelf(kind)
+	e := &cacheEntry{
+		kind:    kind,
+		key:     key,
+		path:    pf.path,
+		value:   value,
+		exists:  exists,
+		expires: cc.now().Add(pol.TTL),
+	}
+	e.elem = sh.lru.PushFront(e)
+	cc.entries[key] = e
+
+	for pol.MaxEntries > 0 && sh.lru.Len() > pol.MaxEntries {
+		cc.remove(sh.lru.Back().Value.(*cacheEntry))
+		sh.stats.Evictions++
+	}
+}
+
+// Invalidate drops the object at the resource path, the objects containing it and, where descendants is set,
+// the objects it contains. The path has the form of the V3 API resource path without the leading slash,
+// e.g. `services/abc/endpoints/def`. The reads of these objects that are in progress are not cached.
+func (cc *CachingClient) Invalidate(path string, descendants bool) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	for _, e := range cc.entries {
+		if invalidates(path, descendants, e.path) {
+			cc.remove(e)
+			cc.shelf(e.kind).stats.Invalidations++
+		}
+	}
+	for pf := range cc.pending {
+		if invalidates(path, descendants, pf.path) {
+			pf.stale = true
+		}
+	}
+}
+
+// Purge drops all cached objects. The reads that are in progress are not cached.
+func (cc *CachingClient) Purge() {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	for _, e := range cc.entries {
+		cc.remove(e)
+	}
+	for pf := range cc.pending {
+		pf.stale = true
+	}
+}
+
+// Stats returns the statistics of each cached kind
+func (cc *CachingClient) Stats() map[string]CacheStats {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	rv := map[string]CacheStats{}
+	for kind, sh := range cc.shelves {
+		rv[kind] = sh.stats
+	}
+	return rv
+}
+
+func cachedGet[T any](ctx context.Context, cc *CachingClient, kind, key string, f func() (T, bool, error)) (T, bool, error) {
+	key = cacheKey(ctx, key)
+	e, pf := cc.lookup(kind, key)
+	if e != nil {
+		return e.value.(T), e.exists, nil
+	}
+
+	rv, exists, err := f()
+	cc.store(kind, key, pf, rv, exists, err)
+	return rv, exists, err
+}
+
+func cachedList[T any](ctx context.Context, cc *CachingClient, kind, key string, f func() (T, error)) (T, error) {
+	rv, _, err := cachedGet(ctx, cc, kind, key, func() (T, bool, error) {
+		lst, err := f()
+		return lst, true, err
+	})
+	return rv, err
+}
+
+// -----------------------------------------------------------------------------------------------------------------
+// Resource paths of the cached objects
+
+func servicePath(id masherytypes.ServiceIdentifier) string {
+	return "services/" + id.ServiceId
+}
+
+func endpointPath(id masherytypes.ServiceEndpointIdentifier) string {
+	return servicePath(id.ServiceIdentifier) + "/endpoints/" + id.EndpointId
+}
+
+func endpointMethodPath(id masherytypes.ServiceEndpointMethodIdentifier) string {
+	return endpointPath(id.ServiceEndpointIdentifier) + "/methods/" + id.MethodId
+}
+
+func endpointMethodFilterPath(id masherytypes.ServiceEndpointMethodFilterIdentifier) string {
+	return endpointMethodPath(id.ServiceEndpointMethodIdentifier) + "/responseFilters/" + id.FilterId
+}
+
+func errorSetPath(id masherytypes.ErrorSetIdentifier) string {
+	return servicePath(id.ServiceIdentifier) + "/errorSets/" + id.ErrorSetId
+}
+
+func packagePath(id masherytypes.PackageIdentifier) string {
+	return "packages/" + id.PackageId
+}
+
+func planPath(id masherytypes.PackagePlanIdentifier) string {
+	return packagePath(id.PackageIdentifier) + "/plans/" + id.PlanId
+}
+
+func planServicePath(id masherytypes.PackagePlanIdentifier, serviceId masherytypes.ServiceIdentifier) string {
+	return planPath(id) + "/services/" + serviceId.ServiceId
+}
+
+// -----------------------------------------------------------------------------------------------------------------
+// Domains and roles
+
+func (cc *CachingClient) GetPublicDomains(ctx context.Context) ([]masherytypes.DomainAddress, error) {
+	return cachedList(ctx, cc, CacheDomains, "domains/public", func() ([]masherytypes.DomainAddress, error) {
+		return cc.Client.GetPublicDomains(ctx)
+	})
+}
+
+func (cc *CachingClient) GetSystemDomains(ctx context.Context) ([]masherytypes.DomainAddress, error) {
+	return cachedList(ctx, cc, CacheDomains, "domains/system", func() ([]masherytypes.DomainAddress, error) {
+		return cc.Client.GetSystemDomains(ctx)
+	})
+}
+
+func (cc *CachingClient) GetRole(ctx context.Context, id string) (masherytypes.Role, bool, error) {
+	return cachedGet(ctx, cc, CacheRoles, "roles/"+id, func() (masherytypes.Role, bool, error) {
+		return cc.Client.GetRole(ctx, id)
+	})
+}
+
+func (cc *CachingClient) ListRoles(ctx context.Context) ([]masherytypes.Role, error) {
+	return cachedList(ctx, cc, CacheRoles, "roles", func() ([]masherytypes.Role, error) {
+		return cc.Client.ListRoles(ctx)
+	})
+}
+
+// -----------------------------------------------------------------------------------------------------------------
+// Services
+
+func (cc *CachingClient) GetService(ctx context.Context, id masherytypes.ServiceIdentifier) (masherytypes.Service, bool, error) {
+	return cachedGet(ctx, cc, CacheServices, servicePath(id), func() (masherytypes.Service, bool, error) {
+		return cc.Client.GetService(ctx, id)
+	})
+}
+
+func (cc *CachingClient) ListServices(ctx context.Context) ([]masherytypes.Service, error) {
+	return cachedList(ctx, cc, CacheServices, "services", func() ([]masherytypes.Service, error) {
+		return cc.Client.ListServices(ctx)
+	})
+}
+
+func (cc *CachingClient) CreateService(ctx context.Context, service masherytypes.Service) (masherytypes.Service, error) {
+	defer cc.Invalidate("services", false)
+	return cc.Client.CreateService(ctx, service)
+}
+
+func (cc *CachingClient) UpdateService(ctx context.Context, service masherytypes.Service) (masherytypes.Service, error) {
+	defer cc.Invalidate(servicePath(service.Identifier()), false)
+	return cc.Client.UpdateService(ctx, service)
+}
+
+func (cc *CachingClient) DeleteService(ctx context.Context, serviceId masherytypes.ServiceIdentifier) error {
+	defer cc.Invalidate("domains", true)
+	defer cc.Invalidate(servicePath(serviceId), true)
+	return cc.Client.DeleteService(ctx, serviceId)
+}
+
+// -----------------------------------------------------------------------------------------------------------------
+// Endpoints
+
+func (cc *CachingClient) GetEndpoint(ctx context.Context, ident masherytypes.ServiceEndpointIdentifier) (masherytypes.Endpoint, bool, error) {
+	return cachedGet(ctx, cc, CacheEndpoints, endpointPath(ident), func() (masherytypes.Endpoint, bool, error) {
+		return cc.Client.GetEndpoint(ctx, ident)
+	})
+}
+
+func (cc *CachingClient) ListEndpoints(ctx context.Context, serviceId masherytypes.ServiceIdentifier) ([]masherytypes.AddressableV3Object, error) {
+	return cachedList(ctx, cc, CacheEndpoints, servicePath(serviceId)+"/endpoints#summary", func() ([]masherytypes.AddressableV3Object, error) {
+		return cc.Client.ListEndpoints(ctx, serviceId)
+	})
+}
+
+func (cc *CachingClient) ListEndpointsWithFullInfo(ctx context.Context, serviceId masherytypes.ServiceIdentifier) ([]masherytypes.Endpoint, error) {
+	return cachedList(ctx, cc, CacheEndpoints, servicePath(serviceId)+"/endpoints#full", func() ([]masherytypes.Endpoint, error) {
+		return cc.Client.ListEndpointsWithFullInfo(ctx, serviceId)
+	})
+}
+
+func (cc *CachingClient) CreateEndpoint(ctx context.Context, serviceId masherytypes.ServiceIdentifier, endp masherytypes.Endpoint) (masherytypes.Endpoint, error) {
+	defer cc.Invalidate("domains", true)
+	defer cc.Invalidate(servicePath(serviceId)+"/endpoints", false)
+	return cc.Client.CreateEndpoint(ctx, serviceId, endp)
+}
+
+func (cc *CachingClient) UpdateEndpoint(ctx context.Context, endp masherytypes.Endpoint) (masherytypes.Endpoint, error) {
+	defer cc.Invalidate("domains", true)
+	defer cc.Invalidate(endpointPath(endp.Identifier()), false)
+	return cc.Client.UpdateEndpoint(ctx, endp)
+}
+
+func (cc *CachingClient) DeleteEndpoint(ctx context.Context, ident masherytypes.ServiceEndpointIdentifier) error {
+	defer cc.Invalidate("domains", true)
+	defer cc.Invalidate(endpointPath(ident), true)
+	return cc.Client.DeleteEndpoint(ctx, ident)
+}
+
+// -----------------------------------------------------------------------------------------------------------------
+// Endpoint methods and filters
+
+func (cc *CachingClient) GetEndpointMethod(ctx context.Context, ident masherytypes.ServiceEndpointMethodIdentifier) (masherytypes.ServiceEndpointMethod, bool, error) {
+	return cachedGet(ctx, cc, CacheEndpointMethods, endpointMethodPath(ident), func() (masherytypes.ServiceEndpointMethod, bool, error) {
+		return cc.Client.GetEndpointMethod(ctx, ident)
+	})
+}
+
+func (cc *CachingClient) ListEndpointMethods(ctx context.Context, ident masherytypes.ServiceEndpointIdentifier) ([]masherytypes.AddressableV3Object, error) {
+	return cachedList(ctx, cc, CacheEndpointMethods, endpointPath(ident)+"/methods#summary", func() ([]masherytypes.AddressableV3Object, error) {
+		return cc.Client.ListEndpointMethods(ctx, ident)
+	})
+}
+
+func (cc *CachingClient) ListEndpointMethodsWithFullInfo(ctx context.Context, ident masherytypes.ServiceEndpointIdentifier) ([]masherytypes.ServiceEndpointMethod, error) {
+	return cachedList(ctx, cc, CacheEndpointMethods, endpointPath(ident)+"/methods#full", func() ([]masherytypes.ServiceEndpointMethod, error) {
+		return cc.Client.ListEndpointMethodsWithFullInfo(ctx, ident)
+	})
+}
+
+func (cc *CachingClient) CreateEndpointMethod(ctx context.Context, ident masherytypes.ServiceEndpointIdentifier, methodUpsert masherytypes.ServiceEndpointMethod) (masherytypes.ServiceEndpointMethod, error) {
+	defer cc.Invalidate(endpointPath(ident)+"/methods", false)
+	return cc.Client.CreateEndpointMethod(ctx, ident, methodUpsert)
+}
+
+func (cc *CachingClient) UpdateEndpointMethod(ctx context.Context, methUpsert masherytypes.ServiceEndpointMethod) (masherytypes.ServiceEndpointMethod, error) {
+	defer cc.Invalidate(endpointMethodPath(methUpsert.Identifier()), false)
+	return cc.Client.UpdateEndpointMethod(ctx, methUpsert)
+}
+
+func (cc *CachingClient) DeleteEndpointMethod(ctx context.Context, ident masherytypes.ServiceEndpointMethodIdentifier) error {
+	defer cc.Invalidate(endpointMethodPath(ident), true)
+	return cc.Client.DeleteEndpointMethod(ctx, ident)
+}
+
+func (cc *CachingClient) GetEndpointMethodFilter(ctx context.Context, ident masherytypes.ServiceEndpointMethodFilterIdentifier) (masherytypes.ServiceEndpointMethodFilter, bool, error) {
+	return cachedGet(ctx, cc, CacheEndpointMethodFilters, endpointMethodFilterPath(ident), func() (masherytypes.ServiceEndpointMethodFilter, bool, error) {
+		return cc.Client.GetEndpointMethodFilter(ctx, ident)
+	})
+}
+
+func (cc *CachingClient) ListEndpointMethodFilters(ctx context.Context, ident masherytypes.ServiceEndpointMethodIdentifier) ([]masherytypes.AddressableV3Object, error) {
+	return cachedList(ctx, cc, CacheEndpointMethodFilters, endpointMethodPath(ident)+"/responseFilters#summary", func() ([]masherytypes.AddressableV3Object, error) {
+		return cc.Client.ListEndpointMethodFilters(ctx, ident)
+	})
+}
+
+func (cc *CachingClient) ListEndpointMethodFiltersWithFullInfo(ctx context.Context, ident masherytypes.ServiceEndpointMethodIdentifier) ([]masherytypes.ServiceEndpointMethodFilter, error) {
+	return cachedList(ctx, cc, CacheEndpointMethodFilters, endpointMethodPath(ident)+"/responseFilters#full", func() ([]masherytypes.ServiceEndpointMethodFilter, error) {
+		return cc.Client.ListEndpointMethodFiltersWithFullInfo(ctx, ident)
+	})
+}
+
+func (cc *CachingClient) CreateEndpointMethodFilter(ctx context.Context, ident masherytypes.ServiceEndpointMethodIdentifier, filterUpsert masherytypes.ServiceEndpointMethodFilter) (masherytypes.ServiceEndpointMethodFilter, error) {
+	defer cc.Invalidate(endpointMethodPath(ident)+"/responseFilters", false)
+	return cc.Client.CreateEndpointMethodFilter(ctx, ident, filterUpsert)
+}
+
+func (cc *CachingClient) UpdateEndpointMethodFilter(ctx context.Context, methUpsert masherytypes.ServiceEndpointMethodFilter) (masherytypes.ServiceEndpointMethodFilter, error) {
+	defer cc.Invalidate(endpointMethodFilterPath(methUpsert.Identifier()), false)
+	return cc.Client.UpdateEndpointMethodFilter(ctx, methUpsert)
+}
+
+func (cc *CachingClient) DeleteEndpointMethodFilter(ctx context.Context, ident masherytypes.ServiceEndpointMethodFilterIdentifier) error {
+	defer cc.Invalidate(endpointMethodFilterPath(ident), true)
+	return cc.Client.DeleteEndpointMethodFilter(ctx, ident)
+}
+
+// -----------------------------------------------------------------------------------------------------------------
+// Service settings: cache, OAuth security profile, roles and error sets
+
+func (cc *CachingClient) GetServiceCache(ctx context.Context, id masherytypes.ServiceIdentifier) (masherytypes.ServiceCache, bool, error) {
+	return cachedGet(ctx, cc, CacheServiceSettings, servicePath(id)+"/cache", func() (masherytypes.ServiceCache, bool, error) {
+		return cc.Client.GetServiceCache(ctx, id)
+	})
+}
+
+func (cc *CachingClient) CreateServiceCache(ctx context.Context, id masherytypes.ServiceIdentifier, service masherytypes.ServiceCache) (masherytypes.ServiceCache, error) {
+	defer cc.Invalidate(servicePath(id)+"/cache", true)
+	return cc.Client.CreateServiceCache(ctx, id, service)
+}
+
+func (cc *CachingClient) UpdateServiceCache(ctx context.Context, service masherytypes.ServiceCache) (masherytypes.ServiceCache, error) {
+	defer cc.Invalidate(servicePath(service.ParentServiceId)+"/cache", true)
+	return cc.Client.UpdateServiceCache(ctx, service)
+}
+
+func (cc *CachingClient) DeleteServiceCache(ctx context.Context, id masherytypes.ServiceIdentifier) error {
+	defer cc.Invalidate(servicePath(id)+"/cache", true)
+	return cc.Client.DeleteServiceCache(ctx, id)
+}
+
+func (cc *CachingClient) GetServiceOAuthSecurityProfile(ctx context.Context, id masherytypes.ServiceIdentifier) (masherytypes.MasheryOAuth, bool, error) {
+	return cachedGet(ctx, cc, CacheServiceSettings, servicePath(id)+"/securityProfile/oauth", func() (masherytypes.MasheryOAuth, bool, error) {
+		return cc.Client.GetServiceOAuthSecurityProfile(ctx, id)
+	})
+}
+
+func (cc *CachingClient) CreateServiceOAuthSecurityProfile(ctx context.Context, id masherytypes.ServiceIdentifier, service masherytypes.MasheryOAuth) (masherytypes.MasheryOAuth, error) {
+	defer cc.Invalidate(servicePath(id)+"/securityProfile/oauth", true)
+	return cc.Client.CreateServiceOAuthSecurityProfile(ctx, id, service)
+}
+
+func (cc *CachingClient) UpdateServiceOAuthSecurityProfile(ctx context.Context, service masherytypes.MasheryOAuth) (masherytypes.MasheryOAuth, error) {
+	defer cc.Invalidate(servicePath(service.ParentService)+"/securityProfile/oauth", true)
+	return cc.Client.UpdateServiceOAuthSecurityProfile(ctx, service)
+}
+
+func (cc *CachingClient) DeleteServiceOAuthSecurityProfile(ctx context.Context, id masherytypes.ServiceIdentifier) error {
+	defer cc.Invalidate(servicePath(id)+"/securityProfile/oauth", true)
+	return cc.Client.DeleteServiceOAuthSecurityProfile(ctx, id)
+}
+
+func (cc *CachingClient) GetServiceRoles(ctx context.Context, serviceId masherytypes.ServiceIdentifier) ([]masherytypes.RolePermission, bool, error) {
+	return cachedGet(ctx, cc, CacheServiceSettings, servicePath(serviceId)+"/roles", func() ([]masherytypes.RolePermission, bool, error) {
+		return cc.Client.GetServiceRoles(ctx, serviceId)
+	})
+}
+
+func (cc *CachingClient) SetServiceRoles(ctx context.Context, id masherytypes.ServiceIdentifier, roles []masherytypes.RolePermission) error {
+	defer cc.Invalidate(servicePath(id)+"/roles", true)
+	return cc.Client.SetServiceRoles(ctx, id, roles)
+}
+
+func (cc *CachingClient) DeleteServiceRoles(ctx context.Context, id masherytypes.ServiceIdentifier) error {
+	defer cc.Invalidate(servicePath(id)+"/roles", true)
+	return cc.Client.DeleteServiceRoles(ctx, id)
+}
+
+func (cc *CachingClient) GetErrorSet(ctx context.Context, ident masherytypes.ErrorSetIdentifier) (masherytypes.ErrorSet, bool, error) {
+	return cachedGet(ctx, cc, CacheServiceSettings, errorSetPath(ident), func() (masherytypes.ErrorSet, bool, error) {
+		return cc.Client.GetErrorSet(ctx, ident)
+	})
+}
+
+func (cc *CachingClient) CreateErrorSet(ctx context.Context, serviceId masherytypes.ServiceIdentifier, set masherytypes.ErrorSet) (masherytypes.ErrorSet, error) {
+	defer cc.Invalidate(servicePath(serviceId)+"/errorSets", false)
+	return cc.Client.CreateErrorSet(ctx, serviceId, set)
+}
+
+func (cc *CachingClient) UpdateErrorSet(ctx context.Context, setData masherytypes.ErrorSet) (masherytypes.ErrorSet, error) {
+	defer cc.Invalidate(errorSetPath(setData.Identifier()), true)
+	return cc.Client.UpdateErrorSet(ctx, setData)
+}
+
+func (cc *CachingClient) DeleteErrorSet(ctx context.Context, ident masherytypes.ErrorSetIdentifier) error {
+	defer cc.Invalidate(errorSetPath(ident), true)
+	return cc.Client.DeleteErrorSet(ctx, ident)
+}
+
+func (cc *CachingClient) UpdateErrorSetMessage(ctx context.Context, msg masherytypes.MasheryErrorMessage) (masherytypes.MasheryErrorMessage, error) {
+	defer cc.Invalidate(errorSetPath(msg.ParentErrorSet), true)
+	return cc.Client.UpdateErrorSetMessage(ctx, msg)
+}
+
+// -----------------------------------------------------------------------------------------------------------------
+// Packages and plans
+
+func (cc *CachingClient) GetPackage(ctx context.Context, id masherytypes.PackageIdentifier) (masherytypes.Package, bool, error) {
+	return cachedGet(ctx, cc, CachePackages, packagePath(id), func() (masherytypes.Package, bool, error) {
+		return cc.Client.GetPackage(ctx, id)
+	})
+}
+
+func (cc *CachingClient) ListPackages(ctx context.Context) ([]masherytypes.Package, error) {
+	return cachedList(ctx, cc, CachePackages, "packages", func() ([]masherytypes.Package, error) {
+		return cc.Client.ListPackages(ctx)
+	})
+}
+
+func (cc *CachingClient) CreatePackage(ctx context.Context, pack masherytypes.Package) (masherytypes.Package, error) {
+	defer cc.Invalidate("packages", false)
+	return cc.Client.CreatePackage(ctx, pack)
+}
+
+func (cc *CachingClient) UpdatePackage(ctx context.Context, pack masherytypes.Package) (masherytypes.Package, error) {
+	defer cc.Invalidate(packagePath(pack.Identifier()), false)
+	return cc.Client.UpdatePackage(ctx, pack)
+}
+
+func (cc *CachingClient) ResetPackageOwnership(ctx context.Context, pack masherytypes.PackageIdentifier) (masherytypes.Package, error) {
+	defer cc.Invalidate(packagePath(pack), false)
+	return cc.Client.ResetPackageOwnership(ctx, pack)
+}
+
+func (cc *CachingClient) DeletePackage(ctx context.Context, packId masherytypes.PackageIdentifier) error {
+	defer cc.Invalidate(packagePath(packId), true)
+	return cc.Client.DeletePackage(ctx, packId)
+}
+
+func (cc *CachingClient) GetPlan(ctx context.Context, ident masherytypes.PackagePlanIdentifier) (masherytypes.Plan, bool, error) {
+	return cachedGet(ctx, cc, CachePlans, planPath(ident), func() (masherytypes.Plan, bool, error) {
+		return cc.Client.GetPlan(ctx, ident)
+	})
+}
+
+func (cc *CachingClient) ListPlans(ctx context.Context, packageId masherytypes.PackageIdentifier) ([]masherytypes.Plan, error) {
+	return cachedList(ctx, cc, CachePlans, packagePath(packageId)+"/plans", func() ([]masherytypes.Plan, error) {
+		return cc.Client.ListPlans(ctx, packageId)
+	})
+}
+
+func (cc *CachingClient) CreatePlan(ctx context.Context, packageId masherytypes.PackageIdentifier, plan masherytypes.Plan) (masherytypes.Plan, error) {
+	defer cc.Invalidate(packagePath(packageId)+"/plans", false)
+	return cc.Client.CreatePlan(ctx, packageId, plan)
+}
+
+func (cc *CachingClient) UpdatePlan(ctx context.Context, plan masherytypes.Plan) (masherytypes.Plan, error) {
+	defer cc.Invalidate(planPath(plan.Identifier()), false)
+	return cc.Client.UpdatePlan(ctx, plan)
+}
+
+func (cc *CachingClient) DeletePlan(ctx context.Context, ident masherytypes.PackagePlanIdentifier) error {
+	defer cc.Invalidate(planPath(ident), true)
+	return cc.Client.DeletePlan(ctx, ident)
+}
+
+func (cc *CachingClient) CreatePlanService(ctx context.Context, planService masherytypes.PackagePlanServiceIdentifier) (masherytypes.AddressableV3Object, error) {
+	defer cc.Invalidate(planServicePath(planService.PackagePlanIdentifier, planService.ServiceIdentifier), true)
+	return cc.Client.CreatePlanService(ctx, planService)
+}
+
+func (cc *CachingClient) DeletePlanService(ctx context.Context, planService masherytypes.PackagePlanServiceIdentifier) error {
+	defer cc.Invalidate(planServicePath(planService.PackagePlanIdentifier, planService.ServiceIdentifier), true)
+	return cc.Client.DeletePlanService(ctx, planService)
+}
+
+func (cc *CachingClient) CreatePlanEndpoint(ctx context.Context, planEndp masherytypes.PackagePlanServiceEndpointIdentifier) (masherytypes.AddressableV3Object, error) {
+	defer cc.Invalidate(planServicePath(planEndp.PackagePlanIdentifier, planEndp.ServiceIdentifier), true)
+	return cc.Client.CreatePlanEndpoint(ctx, planEndp)
+}
+
+func (cc *CachingClient) DeletePlanEndpoint(ctx context.Context, planEndp masherytypes.PackagePlanServiceEndpointIdentifier) error {
+	defer cc.Invalidate(planServicePath(planEndp.PackagePlanIdentifier, planEndp.ServiceIdentifier), true)
+	return cc.Client.DeletePlanEndpoint(ctx, planEndp)
+}
+
+func (cc *CachingClient) CreatePackagePlanMethod(ctx context.Context, id masherytypes.PackagePlanServiceEndpointMethodIdentifier) (masherytypes.PackagePlanServiceEndpointMethod, error) {
+	defer cc.Invalidate(planServicePath(id.PackagePlanIdentifier, id.ServiceIdentifier), true)
+	return cc.Client.CreatePackagePlanMethod(ctx, id)
+}
+
+func (cc *CachingClient) DeletePackagePlanMethod(ctx context.Context, id masherytypes.PackagePlanServiceEndpointMethodIdentifier) error {
+	defer cc.Invalidate(planServicePath(id.PackagePlanIdentifier, id.ServiceIdentifier), true)
+	return cc.Client.DeletePackagePlanMethod(ctx, id)
+}
+
+func (cc *CachingClient) CreatePackagePlanMethodFilter(ctx context.Context, id masherytypes.PackagePlanServiceEndpointMethodFilterIdentifier) (masherytypes.PackagePlanServiceEndpointMethodFilter, error) {
+	defer cc.Invalidate(planServicePath(id.PackagePlanIdentifier, id.ServiceIdentifier), true)
+	return cc.Client.CreatePackagePlanMethodFilter(ctx, id)
+}
+
+func (cc *CachingClient) DeletePackagePlanMethodFilter(ctx context.Context, id masherytypes.PackagePlanServiceEndpointMethodIdentifier) error {
+	defer cc.Invalidate(planServicePath(id.PackagePlanIdentifier, id.ServiceIdentifier), true)
+	return cc.Client.DeletePackagePlanMethodFilter(ctx, id)
+}
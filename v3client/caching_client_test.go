@@ -0,0 +1,223 @@
+package v3client
+
+import (
+	"context"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client/fakeserver"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func countCalls(srv *fakeserver.Server, method string) int {
+	rv := 0
+	for _, c := range srv.Calls() {
+		if c.Method == method {
+			rv++
+		}
+	}
+	return rv
+}
+
+func newCachingClient(srv *fakeserver.Server, params CachingClientParams) *CachingClient {
+	return NewCachingClient(NewHttpClient(Params{
+		MashEndpoint: srv.Endpoint(),
+		QPS:          100,
+	}), params)
+}
+
+func TestCachingClientCachesReads(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRole(masherytypes.Role{AddressableV3Object: masherytypes.AddressableV3Object{Name: "role"}})
+
+	cl := newCachingClient(srv, DefaultCachingClientParams())
+	ctx := context.Background()
+
+	svc, err := cl.CreateService(ctx, masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+	assert.Nil(t, err)
+
+	srv.ResetCalls()
+	for i := 0; i < 3; i++ {
+		got, exists, err := cl.GetService(ctx, svc.Identifier())
+		assert.Nil(t, err)
+		assert.True(t, exists)
+		assert.Equal(t, "svc", got.Name)
+
+		roles, err := cl.ListRoles(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(roles))
+	}
+	assert.Equal(t, 2, countCalls(srv, http.MethodGet))
+
+	stats := cl.Stats()
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 1}, stats[CacheServices])
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 1}, stats[CacheRoles])
+}
+
+func TestCachingClientInvalidatesOnWrites(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+
+	cl := newCachingClient(srv, DefaultCachingClientParams())
+	ctx := context.Background()
+
+	svc, err := cl.CreateService(ctx, masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+	assert.Nil(t, err)
+	endp, err := cl.CreateEndpoint(ctx, svc.Identifier(), masherytypes.Endpoint{
+		AddressableV3Object: masherytypes.AddressableV3Object{Name: "endp"},
+		PublicDomains:       []masherytypes.Domain{{Address: "api.example.com"}},
+	})
+	assert.Nil(t, err)
+
+	_, _, _ = cl.GetService(ctx, svc.Identifier())
+	_, _ = cl.ListEndpointsWithFullInfo(ctx, svc.Identifier())
+	_, _, _ = cl.GetEndpoint(ctx, endp.Identifier())
+	_, _ = cl.GetPublicDomains(ctx)
+
+	endp.Name = "changed"
+	_, err = cl.UpdateEndpoint(ctx, endp)
+	assert.Nil(t, err)
+
+	srv.ResetCalls()
+	got, _, err := cl.GetEndpoint(ctx, endp.Identifier())
+	assert.Nil(t, err)
+	assert.Equal(t, "changed", got.Name)
+	lst, err := cl.ListEndpointsWithFullInfo(ctx, svc.Identifier())
+	assert.Nil(t, err)
+	assert.Equal(t, "changed", lst[0].Name)
+	_, _, _ = cl.GetService(ctx, svc.Identifier())
+	_, _ = cl.GetPublicDomains(ctx)
+	assert.Equal(t, 4, countCalls(srv, http.MethodGet))
+
+	assert.Nil(t, cl.DeleteService(ctx, svc.Identifier()))
+	_, exists, err := cl.GetEndpoint(ctx, endp.Identifier())
+	assert.Nil(t, err)
+	assert.False(t, exists)
+	assert.True(t, cl.Stats()[CacheEndpoints].Invalidations >= 3)
+}
+
+func TestCachingClientExpiresAndEvicts(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+
+	cl := newCachingClient(srv, CachingClientParams{
+		Default: CachePolicy{TTL: time.Minute, MaxEntries: 1},
+	})
+	now := time.Now()
+	cl.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	first, err := cl.CreateService(ctx, masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "first"}})
+	assert.Nil(t, err)
+	second, err := cl.CreateService(ctx, masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "second"}})
+	assert.Nil(t, err)
+
+	srv.ResetCalls()
+	_, _, _ = cl.GetService(ctx, first.Identifier())
+	_, _, _ = cl.GetService(ctx, second.Identifier())
+	_, _, _ = cl.GetService(ctx, first.Identifier())
+	assert.Equal(t, 3, countCalls(srv, http.MethodGet))
+	assert.Equal(t, int64(2), cl.Stats()[CacheServices].Evictions)
+
+	_, _, _ = cl.GetService(ctx, first.Identifier())
+	assert.Equal(t, 3, countCalls(srv, http.MethodGet))
+
+	now = now.Add(time.Minute * 2)
+	_, _, _ = cl.GetService(ctx, first.Identifier())
+	assert.Equal(t, 4, countCalls(srv, http.MethodGet))
+}
+
+// interleavingClient performs the action after the service is read, but before the read returns
+type interleavingClient struct {
+	Client
+	during func()
+}
+
+func (ic *interleavingClient) GetService(ctx context.Context, id masherytypes.ServiceIdentifier) (masherytypes.Service, bool, error) {
+	rv, exists, err := ic.Client.GetService(ctx, id)
+	if f := ic.during; f != nil {
+		ic.during = nil
+		f()
+	}
+	return rv, exists, err
+}
+
+func TestCachingClientDoesNotCacheReadsInvalidatedWhileInProgress(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+
+	ic := &interleavingClient{Client: NewHttpClient(Params{MashEndpoint: srv.Endpoint(), QPS: 100})}
+	cl := NewCachingClient(ic, DefaultCachingClientParams())
+	ctx := context.Background()
+
+	svc, err := cl.CreateService(ctx, masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+	assert.Nil(t, err)
+
+	ic.during = func() {
+		upd := svc
+		upd.Name = "changed"
+		_, err := cl.UpdateService(ctx, upd)
+		assert.Nil(t, err)
+	}
+
+	// The read started before the update returns the old value, which must not be cached.
+	got, _, err := cl.GetService(ctx, svc.Identifier())
+	assert.Nil(t, err)
+	assert.Equal(t, "svc", got.Name)
+
+	got, _, err = cl.GetService(ctx, svc.Identifier())
+	assert.Nil(t, err)
+	assert.Equal(t, "changed", got.Name)
+}
+
+// projectingClient returns only the name of the service where the fields are restricted with ReturnFields
+type projectingClient struct {
+	Client
+}
+
+func (pc *projectingClient) GetService(ctx context.Context, id masherytypes.ServiceIdentifier) (masherytypes.Service, bool, error) {
+	rv, exists, err := pc.Client.GetService(ctx, id)
+	if ctx.Value(fieldsContextKey) != nil {
+		rv = masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: rv.Name}}
+	}
+	return rv, exists, err
+}
+
+func TestCachingClientKeepsRestrictedReadsApart(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+
+	cl := NewCachingClient(&projectingClient{Client: NewHttpClient(Params{MashEndpoint: srv.Endpoint(), QPS: 100})},
+		DefaultCachingClientParams())
+	ctx := context.Background()
+	restricted := ReturnFields(ctx, []string{"name"})
+
+	svc, err := cl.CreateService(ctx, masherytypes.Service{
+		AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"},
+		Description:         "described",
+	})
+	assert.Nil(t, err)
+
+	got, _, err := cl.GetService(restricted, svc.Identifier())
+	assert.Nil(t, err)
+	assert.Equal(t, "", got.Description)
+
+	got, _, err = cl.GetService(ctx, svc.Identifier())
+	assert.Nil(t, err)
+	assert.Equal(t, "described", got.Description)
+
+	// Both reads are cached separately, and are dropped by the update.
+	srv.ResetCalls()
+	got, _, _ = cl.GetService(restricted, svc.Identifier())
+	assert.Equal(t, "", got.Description)
+	got, _, _ = cl.GetService(ctx, svc.Identifier())
+	assert.Equal(t, "described", got.Description)
+	assert.Equal(t, 0, countCalls(srv, http.MethodGet))
+
+	_, err = cl.UpdateService(ctx, svc)
+	assert.Nil(t, err)
+	_, _, _ = cl.GetService(restricted, svc.Identifier())
+	assert.Equal(t, 1, countCalls(srv, http.MethodGet))
+}
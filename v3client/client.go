@@ -40,6 +40,8 @@ type Client interface {
 	CountApplicationsOfMember(ctx context.Context, memberId masherytypes.MemberIdentifier) (int64, error)
 	ListApplications(ctx context.Context) ([]masherytypes.Application, error)
 	ListApplicationsFiltered(ctx context.Context, p map[string]string) ([]masherytypes.Application, error)
+	// IterateApplications streams the applications matching the filter page by page; the filter may be nil
+	IterateApplications(ctx context.Context, p map[string]string) (*transport.ObjectIterator[masherytypes.Application], error)
 
 	// Email template sets
 	GetEmailTemplateSet(ctx context.Context, id string) (masherytypes.EmailTemplateSet, bool, error)
@@ -81,6 +83,8 @@ type Client interface {
 	DeleteMember(ctx context.Context, memberId masherytypes.MemberIdentifier) error
 	ListMembers(ctx context.Context) ([]masherytypes.Member, error)
 	ListMembersFiltered(ctx context.Context, params map[string]string) ([]masherytypes.Member, error)
+	// IterateMembers streams the members matching the filter page by page; the filter may be nil
+	IterateMembers(ctx context.Context, params map[string]string) (*transport.ObjectIterator[masherytypes.Member], error)
 
 	// Packages
 	GetPackage(ctx context.Context, id masherytypes.PackageIdentifier) (masherytypes.Package, bool, error)
@@ -135,6 +139,8 @@ type Client interface {
 
 	ListPackageKeysFiltered(ctx context.Context, params map[string]string) ([]masherytypes.PackageKey, error)
 	ListPackageKeys(ctx context.Context) ([]masherytypes.PackageKey, error)
+	// IteratePackageKeys streams the package keys matching the filter page by page; the filter may be nil
+	IteratePackageKeys(ctx context.Context, params map[string]string) (*transport.ObjectIterator[masherytypes.PackageKey], error)
 
 	// Roles
 	GetRole(ctx context.Context, id string) (masherytypes.Role, bool, error)
@@ -225,6 +231,7 @@ type ClientMethodSchema struct {
 	CountApplicationsOfMember   func(ctx context.Context, memberId masherytypes.MemberIdentifier, c *transport.HttpTransport) (int64, error)
 	ListApplications            func(ctx context.Context, c *transport.HttpTransport) ([]masherytypes.Application, error)
 	ListApplicationsFiltered    func(ctx context.Context, params map[string]string, c *transport.HttpTransport) ([]masherytypes.Application, error)
+	IterateApplications         func(ctx context.Context, params map[string]string, c *transport.HttpTransport) (*transport.ObjectIterator[masherytypes.Application], error)
 
 	// Email template set
 	GetEmailTemplateSet           func(ctx context.Context, id string, c *transport.HttpTransport) (masherytypes.EmailTemplateSet, bool, error)
@@ -266,6 +273,7 @@ type ClientMethodSchema struct {
 	DeleteMember        func(ctx context.Context, memberId masherytypes.MemberIdentifier, c *transport.HttpTransport) error
 	ListMembers         func(ctx context.Context, c *transport.HttpTransport) ([]masherytypes.Member, error)
 	ListMembersFiltered func(ctx context.Context, m map[string]string, c *transport.HttpTransport) ([]masherytypes.Member, error)
+	IterateMembers      func(ctx context.Context, m map[string]string, c *transport.HttpTransport) (*transport.ObjectIterator[masherytypes.Member], error)
 
 	// Packages
 	GetPackage            func(ctx context.Context, id masherytypes.PackageIdentifier, c *transport.HttpTransport) (masherytypes.Package, bool, error)
@@ -321,6 +329,7 @@ type ClientMethodSchema struct {
 
 	ListPackageKeysFiltered func(ctx context.Context, params map[string]string, c *transport.HttpTransport) ([]masherytypes.PackageKey, error)
 	ListPackageKeys         func(ctx context.Context, c *transport.HttpTransport) ([]masherytypes.PackageKey, error)
+	IteratePackageKeys      func(ctx context.Context, params map[string]string, c *transport.HttpTransport) (*transport.ObjectIterator[masherytypes.PackageKey], error)
 
 	// Roles
 	GetRole           func(ctx context.Context, id string, c *transport.HttpTransport) (masherytypes.Role, bool, error)
@@ -545,6 +554,14 @@ func (c *PluggableClient) ListApplicationsFiltered(ctx context.Context, params m
 	}
 }
 
+func (c *PluggableClient) IterateApplications(ctx context.Context, params map[string]string) (*transport.ObjectIterator[masherytypes.Application], error) {
+	if c.schema.IterateApplications != nil {
+		return c.schema.IterateApplications(ctx, params, c.transport)
+	} else {
+		return nil, c.notImplemented("IterateApplications")
+	}
+}
+
 // -----------------------------------------------------------------------------------------------------------------
 // Email template set
 // -----------------------------------------------------------------------------------------------------------------
@@ -807,6 +824,14 @@ func (c *PluggableClient) ListMembersFiltered(ctx context.Context, params map[st
 	}
 }
 
+func (c *PluggableClient) IterateMembers(ctx context.Context, params map[string]string) (*transport.ObjectIterator[masherytypes.Member], error) {
+	if c.schema.IterateMembers != nil {
+		return c.schema.IterateMembers(ctx, params, c.transport)
+	} else {
+		return nil, c.notImplemented("IterateMembers")
+	}
+}
+
 // ---------------------------------------------
 // Packages
 
@@ -1150,6 +1175,14 @@ func (c *PluggableClient) ListPackageKeys(ctx context.Context) ([]masherytypes.P
 	}
 }
 
+func (c *PluggableClient) IteratePackageKeys(ctx context.Context, params map[string]string) (*transport.ObjectIterator[masherytypes.PackageKey], error) {
+	if c.schema.IteratePackageKeys != nil {
+		return c.schema.IteratePackageKeys(ctx, params, c.transport)
+	} else {
+		return nil, c.notImplemented("IteratePackageKeys")
+	}
+}
+
 // ---------------------
 // Roles
 
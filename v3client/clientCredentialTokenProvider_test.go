@@ -1,9 +1,20 @@
 package v3client_test
 
 import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client/fakeserver"
+	"github.com/stretchr/testify/assert"
 	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 //func TestClientCredentialsProviderIsImplementedCorrectly(t *testing.T) {
@@ -94,3 +105,310 @@ func TestMasheryV3Credentials_Inherit(t *testing.T) {
 	}
 
 }
+
+// tokenServer issues the tokens with the password grant, and exchanges the refresh tokens unless rejectRefresh is set
+type tokenServer struct {
+	passwordGrants int32
+	refreshGrants  int32
+	rejectRefresh  bool
+	issued         int32
+}
+
+func (ts *tokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	switch r.PostForm.Get("grant_type") {
+	case "password":
+		atomic.AddInt32(&ts.passwordGrants, 1)
+	case "refresh_token":
+		atomic.AddInt32(&ts.refreshGrants, 1)
+		if ts.rejectRefresh {
+			w.WriteHeader(400)
+			return
+		}
+	}
+
+	// Keep the exchange in progress long enough for the concurrent callers to overlap.
+	time.Sleep(time.Millisecond * 50)
+
+	n := atomic.AddInt32(&ts.issued, 1)
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = fmt.Fprintf(w, `{"token_type":"bearer","access_token":"t%d","expires_in":3600,"refresh_token":"r%d"}`, n, n)
+}
+
+func credentialsProvider(ts *tokenServer) (*v3client.ClientCredentialsProvider, func()) {
+	srv := httptest.NewServer(ts)
+	creds := v3client.MasheryV3Credentials{AreaId: "a", ApiKey: "k", Secret: "s", Username: "u", Password: "p"}
+	return v3client.NewLiveCredentialsProviderUsing(creds, srv.URL, &tls.Config{}), srv.Close
+}
+
+func TestClientCredentialsProviderSharesSingleExchange(t *testing.T) {
+	ts := &tokenServer{}
+	provider, closer := credentialsProvider(ts)
+	defer closer()
+
+	wg := sync.WaitGroup{}
+	tokens := make([]string, 10)
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], _ = provider.AccessToken(context.Background())
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ts.passwordGrants))
+	for _, tkn := range tokens {
+		assert.Equal(t, "t1", tkn)
+	}
+
+	hdr, err := provider.HeaderAuthorization(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer t1", hdr["Authorization"])
+}
+
+func TestClientCredentialsProviderRefreshesExpiredToken(t *testing.T) {
+	ts := &tokenServer{}
+	provider, closer := credentialsProvider(ts)
+	defer closer()
+
+	provider.SetTokenData(&masherytypes.TimedAccessTokenResponse{
+		Obtained:            time.Now().Add(-time.Hour),
+		AccessTokenResponse: masherytypes.AccessTokenResponse{AccessToken: "old", RefreshToken: "r0", ExpiresIn: 3600},
+	})
+
+	tkn, err := provider.AccessToken(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "t1", tkn)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ts.refreshGrants))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&ts.passwordGrants))
+}
+
+func TestClientCredentialsProviderFallsBackToPasswordGrant(t *testing.T) {
+	ts := &tokenServer{rejectRefresh: true}
+	provider, closer := credentialsProvider(ts)
+	defer closer()
+
+	_, err := provider.TokenData()
+	assert.Nil(t, err)
+	assert.Nil(t, provider.Refresh())
+
+	tkn, err := provider.AccessToken(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "t2", tkn)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ts.refreshGrants))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&ts.passwordGrants))
+}
+
+func TestClientCredentialsProviderRefreshesAhead(t *testing.T) {
+	ts := &tokenServer{}
+	provider, closer := credentialsProvider(ts)
+	defer closer()
+
+	// The hour-long token is renewed six seconds after it is obtained.
+	provider.RefreshAhead = time.Hour - time.Second*6
+	provider.RefreshJitter = time.Millisecond
+
+	refreshed := make(chan struct{}, 10)
+	provider.OnPostRefresh(func() {
+		refreshed <- struct{}{}
+	})
+
+	_, err := provider.TokenData()
+	assert.Nil(t, err)
+
+	provider.EnsureRefresh()
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second * 15):
+		assert.Fail(t, "token was not refreshed ahead of expiry")
+	}
+	provider.Close()
+
+	// The renewed token is not renewed again before the provider is closed.
+	time.Sleep(time.Second)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ts.refreshGrants))
+	assert.Equal(t, 0, len(refreshed))
+}
+
+func TestClientCredentialsProviderCloseDoesNotBlock(t *testing.T) {
+	ts := &tokenServer{}
+	provider, closer := credentialsProvider(ts)
+	closer()
+
+	// The refresher waits to retry, as the token cannot be obtained from the stopped server.
+	provider.EnsureRefresh()
+	time.Sleep(time.Millisecond * 100)
+
+	closed := make(chan struct{})
+	go func() {
+		provider.Close()
+		provider.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		assert.Fail(t, "close has blocked")
+	}
+}
+
+func TestClientCredentialsProviderZeroValueCloses(t *testing.T) {
+	provider := &v3client.ClientCredentialsProvider{}
+	provider.Close()
+	provider.Close()
+}
+
+func TestClientCredentialsProviderDoesNotRefreshShortLivedTokensInLoop(t *testing.T) {
+	ts := &tokenServer{}
+	provider, closer := credentialsProvider(ts)
+	defer closer()
+
+	// The token lives no longer than it should be renewed ahead; it is renewed after half of its lifetime.
+	provider.RefreshAhead = time.Hour
+	provider.RefreshJitter = time.Millisecond
+
+	_, err := provider.TokenData()
+	assert.Nil(t, err)
+
+	provider.EnsureRefresh()
+	time.Sleep(time.Second)
+	provider.Close()
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&ts.refreshGrants))
+}
+
+func TestClientCredentialsProviderObtainsNewTokenAfterInvalidate(t *testing.T) {
+	ts := &tokenServer{}
+	provider, closer := credentialsProvider(ts)
+	defer closer()
+
+	var invalidating v3client.InvalidatingTokenProvider = provider
+
+	tkn, err := invalidating.AccessToken(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "t1", tkn)
+
+	invalidating.Invalidate()
+	tkn, err = invalidating.AccessToken(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "t2", tkn)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ts.refreshGrants))
+}
+
+func TestClientReauthenticatesWhenTokenIsRevoked(t *testing.T) {
+	ts := &tokenServer{}
+	provider, closer := credentialsProvider(ts)
+	defer closer()
+
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.RequireAccessToken("t1")
+
+	cl := v3client.NewHttpClient(v3client.Params{MashEndpoint: srv.Endpoint(), Authorizer: provider, QPS: 100})
+	ctx := context.Background()
+
+	_, err := cl.ListServices(ctx)
+	assert.Nil(t, err)
+
+	// Mashery revokes the token t1 before it expires; the provider obtains t2 with the refresh token.
+	srv.RequireAccessToken("t2")
+	_, err = cl.ListServices(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ts.passwordGrants))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ts.refreshGrants))
+}
+
+func TestClientCredentialsProviderReusesSavedToken(t *testing.T) {
+	ts := &tokenServer{}
+	path := filepath.Join(t.TempDir(), "token.json")
+
+	first, closer := credentialsProvider(ts)
+	defer closer()
+	first.UseTokenFile(path)
+
+	tkn, err := first.AccessToken(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "t1", tkn)
+
+	second, closer2 := credentialsProvider(ts)
+	defer closer2()
+	second.UseTokenFile(path)
+
+	tkn, err = second.AccessToken(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "t1", tkn)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ts.passwordGrants))
+}
+
+func TestClientCredentialsProviderRefreshesSavedToken(t *testing.T) {
+	ts := &tokenServer{}
+	path := filepath.Join(t.TempDir(), "token.json")
+	assert.Nil(t, v3client.PersistV3TokenResponse(&masherytypes.TimedAccessTokenResponse{
+		Obtained:            time.Now().Add(-time.Hour),
+		AccessTokenResponse: masherytypes.AccessTokenResponse{AccessToken: "old", RefreshToken: "r0", ExpiresIn: 3600},
+	}, path))
+
+	provider, closer := credentialsProvider(ts)
+	defer closer()
+	provider.UseTokenFile(path)
+
+	tkn, err := provider.AccessToken(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "t1", tkn)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ts.refreshGrants))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&ts.passwordGrants))
+
+	saved, err := v3client.LoadV3TokenResponse(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "t1", saved.AccessToken)
+}
+
+func TestClientCredentialsProvidersSharingTokenFileMakeSingleGrant(t *testing.T) {
+	ts := &tokenServer{}
+	path := filepath.Join(t.TempDir(), "token.json")
+
+	// The providers stand for the processes started at the same time without the saved token.
+	providers := make([]*v3client.ClientCredentialsProvider, 4)
+	for i := range providers {
+		provider, closer := credentialsProvider(ts)
+		defer closer()
+		provider.UseTokenFile(path)
+		providers[i] = provider
+	}
+
+	wg := sync.WaitGroup{}
+	tokens := make([]string, len(providers))
+	for i, provider := range providers {
+		wg.Add(1)
+		go func(i int, provider *v3client.ClientCredentialsProvider) {
+			defer wg.Done()
+			tokens[i], _ = provider.AccessToken(context.Background())
+		}(i, provider)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ts.passwordGrants))
+	for _, tkn := range tokens {
+		assert.Equal(t, "t1", tkn)
+	}
+}
+
+func TestClientCredentialsProviderIgnoresTokenSavedForOtherKey(t *testing.T) {
+	ts := &tokenServer{}
+	path := filepath.Join(t.TempDir(), "token.json")
+	assert.Nil(t, v3client.PersistV3TokenResponse(&masherytypes.TimedAccessTokenResponse{
+		Obtained:            time.Now(),
+		AccessTokenResponse: masherytypes.AccessTokenResponse{AccessToken: "other", ApiKey: "other-key", ExpiresIn: 3600},
+	}, path))
+
+	provider, closer := credentialsProvider(ts)
+	defer closer()
+	provider.UseTokenFile(path)
+
+	tkn, err := provider.AccessToken(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "t1", tkn)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ts.passwordGrants))
+}
@@ -5,7 +5,10 @@ import (
 	"crypto/tls"
 	"errors"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"log/slog"
+	"math/rand"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -29,18 +32,54 @@ func (m *MasheryV3Credentials) FullySpecified() bool {
 //------------------------------------------------------------------------
 // Abstract credentials provider
 
+const defaultRefreshAhead = time.Minute * 5
+const defaultRefreshJitter = time.Minute
+
+// minRefreshDelay the shortest wait before the background renewal, also the first wait after the renewal fails
+const minRefreshDelay = time.Second * 5
+
+// maxRefreshRetryDelay the longest wait after the failed background renewal
+const maxRefreshRetryDelay = time.Minute * 5
+
+// ClientCredentialsProvider obtains the access token from Mashery with the client credentials and keeps it
+// fresh. The provider is safe for the concurrent use: the concurrent callers finding the token missing or expired
+// share a single exchange with Mashery. The token is renewed by exchanging the refresh token; where the exchange
+// fails, a fresh token is obtained with the password grant.
+//
+// EnsureRefresh starts the background renewal of the token ahead of its expiry; Close stops it. With UseTokenFile,
+// the token is shared with the other processes using the same credentials and token file.
 type ClientCredentialsProvider struct {
 	V3OAuthHelper
 
-	Response            *masherytypes.TimedAccessTokenResponse
-	tokenFile           string
-	credentials         MasheryV3Credentials
-	comm                chan int
-	asyncRefreshRunning bool
-
+	// Deprecated: Response is the current token, which is not safe to access while the provider is in use.
+	// Use TokenData and SetTokenData instead.
+	Response *masherytypes.TimedAccessTokenResponse
+
+	// RefreshAhead how long before the expiry the token is renewed in the background; 5 minutes where zero
+	RefreshAhead time.Duration
+	// RefreshJitter the upper bound of the random interval the background renewal is brought forward by, so that
+	// the processes sharing the credentials don't renew the tokens at the same moment; 1 minute where zero
+	RefreshJitter time.Duration
+
+	credentials MasheryV3Credentials
+	tokenFile   string
+
+	mutex             sync.Mutex
+	invalidated       bool
+	inflight          *tokenFlight
+	refresherRunning  bool
+	done              chan struct{}
+	closeOnce         sync.Once
 	postRefreshAction func()
 }
 
+// tokenFlight the exchange with Mashery the concurrent callers are waiting for
+type tokenFlight struct {
+	done chan struct{}
+	resp *masherytypes.TimedAccessTokenResponse
+	err  error
+}
+
 func NewClientCredentialsProvider(credentials MasheryV3Credentials, tlsCfg *tls.Config) *ClientCredentialsProvider {
 	return NewLiveCredentialsProviderUsing(credentials, MasheryTokenEndpoint, tlsCfg)
 }
@@ -62,7 +101,6 @@ func NewLiveCredentialsProviderUsing(credentials MasheryV3Credentials, endpoint
 		},
 
 		credentials: credentials,
-		comm:        make(chan int),
 	}
 
 	return &retVal
@@ -95,46 +133,149 @@ func (m *MasheryV3Credentials) Inherit(other *MasheryV3Credentials) {
 }
 
 func (lcp *ClientCredentialsProvider) OnPostRefresh(f func()) {
+	lcp.mutex.Lock()
+	defer lcp.mutex.Unlock()
+
 	lcp.postRefreshAction = f
 }
 
+// Invalidate discards the access token Mashery has rejected; the next caller obtains a new one
+func (lcp *ClientCredentialsProvider) Invalidate() {
+	lcp.mutex.Lock()
+	defer lcp.mutex.Unlock()
+
+	lcp.invalidated = true
+}
+
+// UseTokenFile makes the provider save each obtained token into the file, and reuse the token saved there, e.g. by
+// the earlier run of the process, instead of obtaining a new one. The token saved for other credentials is ignored.
+func (lcp *ClientCredentialsProvider) UseTokenFile(path string) {
+	lcp.mutex.Lock()
+	defer lcp.mutex.Unlock()
+
+	lcp.tokenFile = path
+}
+
+// SetTokenData makes the provider use the previously obtained token, e.g. the one read from the file
+func (lcp *ClientCredentialsProvider) SetTokenData(resp *masherytypes.TimedAccessTokenResponse) {
+	lcp.mutex.Lock()
+	defer lcp.mutex.Unlock()
+
+	lcp.Response = resp
+	lcp.invalidated = false
+}
+
+// EnsureRefresh starts the background renewal of the token, unless it is already running. Where the token cannot
+// be renewed, the renewal is retried with the increasing delay until the provider is closed.
 func (lcp *ClientCredentialsProvider) EnsureRefresh() {
-	if !lcp.asyncRefreshRunning {
+	lcp.mutex.Lock()
+	defer lcp.mutex.Unlock()
+
+	if !lcp.refresherRunning {
+		lcp.refresherRunning = true
 		go lcp.doEnsureRefresh()
-		lcp.asyncRefreshRunning = true
 	}
 }
 
+// Close stops the background renewal of the token. Close doesn't block and can be called more than once.
 func (lcp *ClientCredentialsProvider) Close() {
-	// Send the command to the refresh thread if it is running
-	if lcp.asyncRefreshRunning {
-		lcp.comm <- 1
+	lcp.closeOnce.Do(func() {
+		close(lcp.doneChannel())
+	})
+}
+
+// doneChannel the channel closed by Close, created where the provider was not created with the constructor
+func (lcp *ClientCredentialsProvider) doneChannel() chan struct{} {
+	lcp.mutex.Lock()
+	defer lcp.mutex.Unlock()
+
+	if lcp.done == nil {
+		lcp.done = make(chan struct{})
+	}
+	return lcp.done
+}
+
+// refreshDelay the time until the background renewal of the token. The token that is due for the renewal sooner
+// than minRefreshDelay, e.g. which lifetime is shorter than RefreshAhead, is renewed after half of its remaining
+// lifetime, but not sooner than minRefreshDelay.
+func (lcp *ClientCredentialsProvider) refreshDelay(resp *masherytypes.TimedAccessTokenResponse) time.Duration {
+	if resp == nil {
+		return 0
+	}
+
+	ahead := lcp.RefreshAhead
+	if ahead <= 0 {
+		ahead = defaultRefreshAhead
+	}
+	jitter := lcp.RefreshJitter
+	if jitter <= 0 {
+		jitter = defaultRefreshJitter
 	}
+
+	remaining := time.Until(resp.ExpiryTime())
+	rv := remaining - ahead - time.Duration(rand.Int63n(int64(jitter)))
+	if rv < minRefreshDelay {
+		rv = remaining / 2
+	}
+	if rv < minRefreshDelay {
+		rv = minRefreshDelay
+	}
+	return rv
 }
 
 func (lcp *ClientCredentialsProvider) doEnsureRefresh() {
-	for lcp.Response != nil {
-		waitDur := lcp.Response.ExpiryTime().Sub(time.Now())
-		waitDur -= time.Minute * 5
+	defer func() {
+		lcp.mutex.Lock()
+		lcp.refresherRunning = false
+		lcp.mutex.Unlock()
+	}()
+
+	done := lcp.doneChannel()
+	retryDelay := time.Duration(0)
+
+	for {
+		wait := retryDelay
+		if wait == 0 {
+			lcp.mutex.Lock()
+			wait = lcp.refreshDelay(lcp.Response)
+			lcp.mutex.Unlock()
+		}
 
+		timer := time.NewTimer(wait)
 		select {
-		case <-lcp.comm:
-			lcp.asyncRefreshRunning = false
+		case <-done:
+			timer.Stop()
 			return
-		case <-time.After(waitDur):
-			err := lcp.Refresh()
-			if err != nil {
-				lcp.asyncRefreshRunning = false
-				return
-			}
+		case <-timer.C:
 		}
 
-		if lcp.postRefreshAction != nil {
-			lcp.postRefreshAction()
+		if _, err := lcp.acquire(context.Background(), true); err != nil {
+			slog.Warn("could not renew the access token", "error", err)
+			retryDelay = nextRefreshRetryDelay(retryDelay)
+			continue
+		}
+		retryDelay = 0
+
+		lcp.mutex.Lock()
+		action := lcp.postRefreshAction
+		lcp.mutex.Unlock()
+
+		if action != nil {
+			action()
 		}
 	}
 }
 
+// nextRefreshRetryDelay doubles the delay before the next attempt to renew the token
+func nextRefreshRetryDelay(d time.Duration) time.Duration {
+	if d < minRefreshDelay {
+		return minRefreshDelay
+	} else if d*2 > maxRefreshRetryDelay {
+		return maxRefreshRetryDelay
+	}
+	return d * 2
+}
+
 func ResponseDate(resp *http.Response) time.Time {
 	if val := resp.Header.Get("Date"); len(val) > 0 {
 		if t, err := time.Parse(time.RFC1123, val); err == nil {
@@ -145,32 +286,124 @@ func ResponseDate(resp *http.Response) time.Time {
 	return time.Unix(0, 0)
 }
 
+// Refresh renews the token, falling back to the password grant where the refresh token cannot be exchanged
 func (lcp *ClientCredentialsProvider) Refresh() error {
-	if lcp.Response == nil {
-		return errors.New("cannot refresh without having a previous response")
-	} else if lcp.Response.Expired() {
-		return errors.New("refresh token has already expired")
+	_, err := lcp.acquire(context.Background(), true)
+	return err
+}
+
+// acquire obtains the token from Mashery, unless the current one is still valid and the renewal is not forced.
+// The concurrent callers share the single exchange with Mashery.
+func (lcp *ClientCredentialsProvider) acquire(ctx context.Context, force bool) (*masherytypes.TimedAccessTokenResponse, error) {
+	lcp.mutex.Lock()
+	if f := lcp.inflight; f != nil {
+		lcp.mutex.Unlock()
+
+		select {
+		case <-f.done:
+			return f.resp, f.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
 
-	resp, err := lcp.ExchangeRefreshToken(&lcp.credentials, lcp.Response.RefreshToken)
-	lcp.Response = resp
+	current := lcp.Response
+	if !force && !lcp.invalidated && current != nil && !current.Expired() {
+		lcp.mutex.Unlock()
+		return current, nil
+	}
 
-	return err
+	// The waiting callers receive the error also where the exchange panics.
+	f := &tokenFlight{done: make(chan struct{}), err: errors.New("access token exchange has not completed")}
+	lcp.inflight = f
+	lcp.mutex.Unlock()
+
+	defer func() {
+		lcp.mutex.Lock()
+		lcp.inflight = nil
+		lcp.mutex.Unlock()
+
+		close(f.done)
+	}()
+
+	f.resp, f.err = lcp.obtain(current)
+	if f.err == nil {
+		lcp.mutex.Lock()
+		lcp.Response = f.resp
+		lcp.invalidated = false
+		lcp.mutex.Unlock()
+	}
+	return f.resp, f.err
 }
 
-func (lcp *ClientCredentialsProvider) TokenData() (*masherytypes.TimedAccessTokenResponse, error) {
-	if lcp.Response != nil && !lcp.Response.Expired() {
-		return lcp.Response, nil
+// obtain reuses the newer token saved in the token file, if any; otherwise, exchanges the refresh token of
+// the current response, falling back to the password grant. The obtained token is saved into the token file.
+// The token file is locked from reading until saving the token, so that the processes sharing it make a single
+// exchange with Mashery; the others reuse the token saved by the first one.
+func (lcp *ClientCredentialsProvider) obtain(current *masherytypes.TimedAccessTokenResponse) (*masherytypes.TimedAccessTokenResponse, error) {
+	lcp.mutex.Lock()
+	tokenFile := lcp.tokenFile
+	lcp.mutex.Unlock()
+
+	if len(tokenFile) == 0 {
+		return lcp.exchange(current)
+	}
+
+	if unlock, err := lockTokenExchange(tokenFile); err != nil {
+		slog.Warn("could not lock the access token file", "file", tokenFile, "error", err)
 	} else {
-		resp, err := lcp.RetrieveAccessTokenFor(&lcp.credentials)
-		lcp.Response = resp
+		defer unlock()
+	}
 
-		return resp, err
+	saved := lcp.savedToken(tokenFile)
+	if saved != nil && !saved.Expired() && (current == nil ||
+		(saved.AccessToken != current.AccessToken && saved.Obtained.After(current.Obtained))) {
+		return saved, nil
+	}
+	if current == nil {
+		current = saved
 	}
+
+	resp, err := lcp.exchange(current)
+	if err == nil {
+		if persistErr := PersistV3TokenResponse(resp, tokenFile); persistErr != nil {
+			slog.Warn("could not save the access token", "file", tokenFile, "error", persistErr)
+		}
+	}
+	return resp, err
 }
 
-func (lcp *ClientCredentialsProvider) AccessToken(_ context.Context) (string, error) {
-	if dat, err := lcp.TokenData(); err != nil {
+// savedToken reads the token saved for the credentials of this provider
+func (lcp *ClientCredentialsProvider) savedToken(path string) *masherytypes.TimedAccessTokenResponse {
+	saved, err := LoadV3TokenResponse(path)
+	if err != nil || saved == nil {
+		return nil
+	}
+
+	if (len(saved.ApiKey) > 0 && saved.ApiKey != lcp.credentials.ApiKey) ||
+		(len(saved.Scope) > 0 && saved.Scope != lcp.credentials.AreaId) {
+		return nil
+	}
+	return saved
+}
+
+// exchange exchanges the refresh token of the current response, if any, falling back to the password grant
+func (lcp *ClientCredentialsProvider) exchange(current *masherytypes.TimedAccessTokenResponse) (*masherytypes.TimedAccessTokenResponse, error) {
+	if current != nil && len(current.RefreshToken) > 0 {
+		if resp, err := lcp.ExchangeRefreshToken(&lcp.credentials, current.RefreshToken); err == nil {
+			return resp, nil
+		}
+	}
+
+	return lcp.RetrieveAccessTokenFor(&lcp.credentials)
+}
+
+func (lcp *ClientCredentialsProvider) TokenData() (*masherytypes.TimedAccessTokenResponse, error) {
+	return lcp.acquire(context.Background(), false)
+}
+
+func (lcp *ClientCredentialsProvider) AccessToken(ctx context.Context) (string, error) {
+	if dat, err := lcp.acquire(ctx, false); err != nil {
 		return "", err
 	} else if dat == nil {
 		return "", errors.New("empty token data returned while trying to provide access token")
@@ -180,15 +413,13 @@ func (lcp *ClientCredentialsProvider) AccessToken(_ context.Context) (string, er
 }
 
 func (lcp *ClientCredentialsProvider) HeaderAuthorization(ctx context.Context) (map[string]string, error) {
-	var token string
-	var err error
-	var rv map[string]string
-
-	if token, err = lcp.AccessToken(ctx); err == nil {
-		rv["Authorization"] = token
+	if token, err := lcp.AccessToken(ctx); err != nil {
+		return nil, err
+	} else {
+		return map[string]string{
+			"Authorization": "Bearer " + token,
+		}, nil
 	}
-
-	return rv, err
 }
 
 func (lcp *ClientCredentialsProvider) QueryStringAuthorization(_ context.Context) (map[string]string, error) {
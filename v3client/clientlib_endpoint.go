@@ -44,6 +44,8 @@ func init() {
 
 			return "", errors.New("unresolvable identification")
 		},
+		VersionField:  "updated",
+		VersionOf:     func(t masherytypes.Endpoint) string { return updatedVersion(t.AddressableV3Object) },
 		DefaultFields: MasheryEndpointFields,
 		Pagination:    transport.PerPage,
 	}
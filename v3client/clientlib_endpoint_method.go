@@ -45,6 +45,8 @@ func init() {
 		ResourceForParent: func(ident masherytypes.ServiceEndpointIdentifier) (string, error) {
 			return fmt.Sprintf("/services/%s/endpoints/%s/methods", ident.ServiceId, ident.EndpointId), nil
 		},
+		VersionField:  "updated",
+		VersionOf:     func(t masherytypes.ServiceEndpointMethod) string { return updatedVersion(t.AddressableV3Object) },
 		DefaultFields: MasheryMethodsFields,
 		Pagination:    transport.PerPage,
 	}
@@ -46,6 +46,8 @@ func init() {
 		ResourceForParent: func(ident masherytypes.ServiceEndpointMethodIdentifier) (string, error) {
 			return fmt.Sprintf("/services/%s/endpoints/%s/methods/%s/responseFilters", ident.ServiceId, ident.EndpointId, ident.MethodId), nil
 		},
+		VersionField:  "updated",
+		VersionOf:     func(t masherytypes.ServiceEndpointMethodFilter) string { return updatedVersion(t.AddressableV3Object) },
 		DefaultFields: MasheryResponseFilterFields,
 		Pagination:    transport.PerPage,
 	}
@@ -30,6 +30,8 @@ func init() {
 		ResourceForParent: func(_ int) (string, error) {
 			return "/packages", nil
 		},
+		VersionField:  "updated",
+		VersionOf:     func(t masherytypes.Package) string { return updatedVersion(t.AddressableV3Object) },
 		DefaultFields: MasheryPackageFields,
 		Pagination:    transport.PerItem,
 	}
@@ -38,6 +38,8 @@ func init() {
 		ResourceForParent: func(ident masherytypes.PackageIdentifier) (string, error) {
 			return fmt.Sprintf("/packages/%s/plans", ident.PackageId), nil
 		},
+		VersionField:  "updated",
+		VersionOf:     func(t masherytypes.Plan) string { return updatedVersion(t.AddressableV3Object) },
 		DefaultFields: MasheryPlanFields,
 		Pagination:    transport.PerPage,
 	}
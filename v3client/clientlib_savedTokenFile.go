@@ -3,8 +3,11 @@ package v3client
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/errwrap"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/internal/flock"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
-	"io/ioutil"
+	"io"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -23,21 +26,88 @@ func DefaultSavedAccessTokenFilePath() string {
 	}
 }
 
+// lockTokenFile locks the token file; where the platform doesn't support locking, the file is used without the lock
+func lockTokenFile(f *os.File) (func(), error) {
+	if err := flock.Lock(f); err != nil {
+		if errors.Is(err, flock.ErrUnsupported) {
+			return func() {}, nil
+		}
+		return nil, &errwrap.WrappedError{Context: fmt.Sprintf("locking token file %s", f.Name()), Cause: err}
+	}
+	return func() { _ = flock.Unlock(f) }, nil
+}
+
+// lockTokenExchange locks the sibling `.lock` file of the token file, so that the processes sharing the token file
+// don't obtain the tokens at the same time. The token file itself is not locked, as it is read and written
+// while the lock is held.
+func lockTokenExchange(path string) (func(), error) {
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, &errwrap.WrappedError{Context: fmt.Sprintf("opening lock of token file %s", path), Cause: err}
+	}
+
+	unlock, err := lockTokenFile(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return func() {
+		unlock()
+		_ = f.Close()
+	}, nil
+}
+
+// PersistV3TokenResponse saves the token into the file, which only the current user can read. The file is locked
+// while it is written, so that the concurrent LoadV3TokenResponse never reads the partially written token.
 func PersistV3TokenResponse(dat *masherytypes.TimedAccessTokenResponse, path string) error {
 	if stat, err := os.Stat(path); (err == nil || os.IsExist(err)) && stat.IsDir() {
 		return errors.New("cannot persis a file into existing directory")
 	}
 
-	if m, err := json.Marshal(dat); err == nil {
-		return ioutil.WriteFile(path, m, 0644)
-	} else {
+	m, err := json.Marshal(dat)
+	if err != nil {
 		return err
 	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	unlock, err := lockTokenFile(f)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// The file could have been created before with the broader permissions.
+	if err = f.Chmod(0600); err != nil {
+		return err
+	}
+	if err = f.Truncate(0); err != nil {
+		return err
+	}
+	_, err = f.WriteAt(m, 0)
+	return err
 }
 
+// LoadV3TokenResponse reads the token saved with PersistV3TokenResponse; returns nil where the file doesn't exist
 func LoadV3TokenResponse(path string) (*masherytypes.TimedAccessTokenResponse, error) {
 	if stat, err := os.Stat(path); (err == nil || os.IsExist(err)) && !stat.IsDir() {
-		if dat, err := ioutil.ReadFile(path); err == nil {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		unlock, err := lockTokenFile(f)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+
+		if dat, err := io.ReadAll(f); err == nil {
 			resp := masherytypes.TimedAccessTokenResponse{}
 			err = json.Unmarshal(dat, &resp)
 			return &resp, err
@@ -0,0 +1,42 @@
+package v3client
+
+import (
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPersistAndLoadV3TokenResponse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+	// The file left by the earlier versions is readable by everyone.
+	assert.Nil(t, os.WriteFile(path, []byte("{}"), 0644))
+
+	resp := &masherytypes.TimedAccessTokenResponse{
+		Obtained: time.Now().Truncate(time.Second),
+		AccessTokenResponse: masherytypes.AccessTokenResponse{
+			AccessToken:  "t",
+			RefreshToken: "r",
+			ExpiresIn:    3600,
+		},
+	}
+	assert.Nil(t, PersistV3TokenResponse(resp, path))
+
+	stat, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+
+	loaded, err := LoadV3TokenResponse(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "t", loaded.AccessToken)
+	assert.Equal(t, "r", loaded.RefreshToken)
+	assert.True(t, resp.Obtained.Equal(loaded.Obtained))
+}
+
+func TestLoadV3TokenResponseFromMissingFile(t *testing.T) {
+	loaded, err := LoadV3TokenResponse(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Nil(t, loaded)
+	assert.Nil(t, err)
+}
@@ -31,6 +31,8 @@ func init() {
 		ResourceForParent: func(ident int) (string, error) {
 			return "/services", nil
 		},
+		VersionField:  "revisionNumber",
+		VersionOf:     func(t masherytypes.Service) string { return revisionVersion(t.RevisionNumber) },
 		DefaultFields: MasheryServiceFields,
 		Pagination:    transport.PerItem,
 	}
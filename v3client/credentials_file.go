@@ -0,0 +1,220 @@
+package v3client
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/errwrap"
+	"golang.org/x/crypto/scrypt"
+	"io"
+	"os"
+)
+
+// The encrypted credentials file starts with the header, which is authenticated together with the encrypted content:
+//
+//	magic (8) | version (1) | scrypt log2(N) (1) | scrypt r (1) | scrypt p (1) | salt length (1) | salt | nonce
+//
+// The key is derived from the passphrase of any length with scrypt. The files written before the header was
+// introduced are encrypted with the 32-character passphrase used directly as AES key; these are still readable.
+var credentialsFileMagic = []byte("MASHCRED")
+
+const credentialsFileVersion1 byte = 1
+
+// Default scrypt parameters: N=2^15, r=8, p=1
+const defaultScryptLogN byte = 15
+const defaultScryptR byte = 8
+const defaultScryptP byte = 1
+
+// Upper bounds of the scrypt parameters accepted from the file header, so that the crafted file cannot force
+// the excessive memory allocation or computation. The key derivation uses 128*r*2^logN bytes of memory.
+const maxScryptLogN byte = 20
+const maxScryptR byte = 32
+const maxScryptP byte = 16
+
+const credentialsSaltSize = 16
+const credentialsKeySize = 32
+
+// legacyPassphraseSize the passphrase length required by the files without the header
+const legacyPassphraseSize = 32
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if cphr, err := aes.NewCipher(key); err != nil {
+		return nil, &errwrap.WrappedError{Context: "obtaining cipher", Cause: err}
+	} else if gcm, err := cipher.NewGCM(cphr); err != nil {
+		return nil, &errwrap.WrappedError{Context: "initializing counter", Cause: err}
+	} else {
+		return gcm, nil
+	}
+}
+
+func deriveCredentialsKey(pass string, salt []byte, logN, r, p byte) ([]byte, error) {
+	if logN < 10 || logN > maxScryptLogN || r == 0 || r > maxScryptR || p == 0 || p > maxScryptP {
+		return nil, errors.New(fmt.Sprintf("unsupported key derivation parameters N=2^%d, r=%d, p=%d", logN, r, p))
+	}
+
+	if key, err := scrypt.Key([]byte(pass), salt, 1<<logN, int(r), int(p), credentialsKeySize); err != nil {
+		return nil, &errwrap.WrappedError{Context: "deriving key", Cause: err}
+	} else {
+		return key, nil
+	}
+}
+
+// encryptCredentials encrypts the data into the current format
+func encryptCredentials(data []byte, pass string) ([]byte, error) {
+	if len(pass) == 0 {
+		return nil, errors.New("password must not be empty")
+	}
+
+	salt := make([]byte, credentialsSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, &errwrap.WrappedError{Context: "cannot initialize salt", Cause: err}
+	}
+
+	key, err := deriveCredentialsKey(pass, salt, defaultScryptLogN, defaultScryptR, defaultScryptP)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := bytes.Buffer{}
+	header.Write(credentialsFileMagic)
+	header.Write([]byte{credentialsFileVersion1, defaultScryptLogN, defaultScryptR, defaultScryptP, byte(len(salt))})
+	header.Write(salt)
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, &errwrap.WrappedError{Context: "cannot initialize nonce", Cause: err}
+	}
+	header.Write(nonce)
+
+	return gcm.Seal(header.Bytes(), nonce, data, header.Bytes()), nil
+}
+
+// decryptCredentials decrypts the data in the current or in the legacy format
+func decryptCredentials(ciphertext []byte, pass string) ([]byte, error) {
+	if !bytes.HasPrefix(ciphertext, credentialsFileMagic) {
+		return decryptLegacyCredentials(ciphertext, pass)
+	}
+
+	const fixedSize = 5
+	hdr := ciphertext[len(credentialsFileMagic):]
+	if len(hdr) < fixedSize {
+		return nil, errors.New("encrypted file header is truncated")
+	}
+	if hdr[0] != credentialsFileVersion1 {
+		return nil, errors.New(fmt.Sprintf("unsupported encrypted file version %d", hdr[0]))
+	}
+
+	logN, r, p, saltLen := hdr[1], hdr[2], hdr[3], int(hdr[4])
+	if len(hdr) < fixedSize+saltLen {
+		return nil, errors.New("encrypted file header is truncated")
+	}
+	salt := hdr[fixedSize : fixedSize+saltLen]
+
+	key, err := deriveCredentialsKey(pass, salt, logN, r, p)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	headerLen := len(credentialsFileMagic) + fixedSize + saltLen + gcm.NonceSize()
+	if len(ciphertext) < headerLen {
+		return nil, errors.New("encrypted file header is truncated")
+	}
+
+	nonce := ciphertext[headerLen-gcm.NonceSize() : headerLen]
+	if dat, err := gcm.Open(nil, nonce, ciphertext[headerLen:], ciphertext[:headerLen]); err != nil {
+		return nil, &errwrap.WrappedError{Context: "decrypting (is the password correct?)", Cause: err}
+	} else {
+		return dat, nil
+	}
+}
+
+// decryptLegacyCredentials decrypts the file written before the header was introduced
+func decryptLegacyCredentials(ciphertext []byte, pass string) ([]byte, error) {
+	if len(pass) != legacyPassphraseSize {
+		return nil, errors.New("password must be exactly 32 characters")
+	}
+
+	gcm, err := newGCM([]byte(pass))
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, errors.New("encrypted file is truncated")
+	}
+	nonce, encryptedMessage := ciphertext[:nonceSize], ciphertext[nonceSize:]
+
+	return gcm.Open(nil, nonce, encryptedMessage, nil)
+}
+
+// writePrivateFile writes the file which only the current user can read, also where the file already exists
+func writePrivateFile(path string, dat []byte) error {
+	if err := os.WriteFile(path, dat, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0600)
+}
+
+// EncryptInPlace encrypts the file with the key derived from the passphrase. The file is left readable only by
+// the current user.
+func EncryptInPlace(path string, pass string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return &errwrap.WrappedError{Context: "reading", Cause: err}
+	}
+
+	if ciphertext, err := encryptCredentials(data, pass); err != nil {
+		return err
+	} else {
+		return writePrivateFile(path, ciphertext)
+	}
+}
+
+// ReadCiphertext decrypts the file encrypted with EncryptInPlace, including the files encrypted by the earlier
+// versions with the 32-character passphrase
+func ReadCiphertext(fileName string, pass string) ([]byte, error) {
+	if ciphertext, err := os.ReadFile(fileName); err != nil {
+		return []byte{}, &errwrap.WrappedError{Context: "reading source file", Cause: err}
+	} else if dat, err := decryptCredentials(ciphertext, pass); err != nil {
+		return []byte{}, err
+	} else {
+		return dat, nil
+	}
+}
+
+// IsLegacyCiphertext checks whether the file was encrypted by the earlier versions, and should be migrated
+// with MigrateCiphertext
+func IsLegacyCiphertext(fileName string) (bool, error) {
+	if ciphertext, err := os.ReadFile(fileName); err != nil {
+		return false, &errwrap.WrappedError{Context: "reading source file", Cause: err}
+	} else {
+		return !bytes.HasPrefix(ciphertext, credentialsFileMagic), nil
+	}
+}
+
+// MigrateCiphertext re-encrypts the file, in the current or the legacy format, into the current format with
+// the new passphrase, which can be the same as the old one. The file is left readable only by the current user.
+func MigrateCiphertext(fileName string, oldPass, newPass string) error {
+	dat, err := ReadCiphertext(fileName, oldPass)
+	if err != nil {
+		return err
+	}
+
+	if ciphertext, err := encryptCredentials(dat, newPass); err != nil {
+		return err
+	} else {
+		return writePrivateFile(fileName, ciphertext)
+	}
+}
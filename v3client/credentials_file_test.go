@@ -0,0 +1,101 @@
+package v3client
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const credentialsYaml = "areaId: a\napiKey: k\nsecret: s\n"
+
+func TestEncryptInPlaceWithArbitraryPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "creds.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte(credentialsYaml), 0644))
+
+	assert.Nil(t, EncryptInPlace(path, "correct horse battery staple"))
+
+	stat, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+
+	legacy, err := IsLegacyCiphertext(path)
+	assert.Nil(t, err)
+	assert.False(t, legacy)
+
+	dat, err := ReadCiphertext(path, "correct horse battery staple")
+	assert.Nil(t, err)
+	assert.Equal(t, credentialsYaml, string(dat))
+
+	_, err = ReadCiphertext(path, "wrong passphrase")
+	assert.NotNil(t, err)
+}
+
+func TestTamperedHeaderIsRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "creds.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte(credentialsYaml), 0600))
+	assert.Nil(t, EncryptInPlace(path, "pass"))
+
+	dat, _ := os.ReadFile(path)
+	// Change the salt, which is authenticated with the content.
+	dat[len(credentialsFileMagic)+6] ^= 0xff
+	assert.Nil(t, os.WriteFile(path, dat, 0600))
+
+	_, err := ReadCiphertext(path, "pass")
+	assert.NotNil(t, err)
+}
+
+// writeLegacyCiphertext encrypts the file as the earlier versions did
+func writeLegacyCiphertext(t *testing.T, path string, pass string) {
+	cphr, err := aes.NewCipher([]byte(pass))
+	assert.Nil(t, err)
+	gcm, err := cipher.NewGCM(cphr)
+	assert.Nil(t, err)
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(path, gcm.Seal(nonce, nonce, []byte(credentialsYaml), nil), 0644))
+}
+
+func TestReadAndMigrateLegacyCiphertext(t *testing.T) {
+	legacyPass := "0123456789abcdef0123456789abcdef"
+	path := filepath.Join(t.TempDir(), "creds.yaml")
+	writeLegacyCiphertext(t, path, legacyPass)
+
+	legacy, err := IsLegacyCiphertext(path)
+	assert.Nil(t, err)
+	assert.True(t, legacy)
+
+	dat, err := ReadCiphertext(path, legacyPass)
+	assert.Nil(t, err)
+	assert.Equal(t, credentialsYaml, string(dat))
+
+	assert.Nil(t, MigrateCiphertext(path, legacyPass, "new passphrase"))
+
+	legacy, err = IsLegacyCiphertext(path)
+	assert.Nil(t, err)
+	assert.False(t, legacy)
+
+	stat, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+
+	dat, err = ReadCiphertext(path, "new passphrase")
+	assert.Nil(t, err)
+	assert.Equal(t, credentialsYaml, string(dat))
+}
+
+func TestExcessiveKeyDerivationParametersAreRejected(t *testing.T) {
+	for _, params := range [][3]byte{{30, 8, 1}, {15, 255, 1}, {15, 8, 255}} {
+		_, err := deriveCredentialsKey("pass", []byte("salt"), params[0], params[1], params[2])
+		assert.NotNil(t, err, "parameters %v", params)
+	}
+
+	_, err := deriveCredentialsKey("pass", []byte("salt"), defaultScryptLogN, defaultScryptR, defaultScryptP)
+	assert.Nil(t, err)
+}
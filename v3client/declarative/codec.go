@@ -0,0 +1,69 @@
+package declarative
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"gopkg.in/yaml.v2"
+)
+
+// Format file format of the exported objects
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+)
+
+// ParseFormat converts the command-line option into the format.
+func ParseFormat(str string) (Format, error) {
+	switch Format(str) {
+	case FormatYAML, "yml", "":
+		return FormatYAML, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	default:
+		return "", errors.New(fmt.Sprintf("unsupported format: %s", str))
+	}
+}
+
+// Extension returns the file extension for this format, including the leading dot.
+func (f Format) Extension() string {
+	if f == FormatJSON {
+		return ".json"
+	}
+	return ".yaml"
+}
+
+// encode converts the object into the file content. The object is converted into its JSON representation first,
+// so that the property names match those of Mashery V3 API; server-managed fields are then removed. The keys
+// are written in sorted order.
+func encode(obj interface{}, format Format) ([]byte, error) {
+	generic, err := toGeneric(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == FormatJSON {
+		buf := bytes.Buffer{}
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		err = enc.Encode(generic)
+		return buf.Bytes(), err
+	}
+
+	return yaml.Marshal(generic)
+}
+
+// toGeneric converts the object into its JSON representation without server-managed fields. JSON numbers are
+// converted into integers where possible, so that YAML output does not use exponent notation.
+func toGeneric(obj interface{}) (interface{}, error) {
+	if generic, err := masherytypes.GenericJSON(obj); err != nil {
+		return nil, err
+	} else {
+		return masherytypes.RemoveServerManagedFields(generic), nil
+	}
+}
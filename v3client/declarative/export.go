@@ -0,0 +1,356 @@
+package declarative
+
+import (
+	"context"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/errwrap"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"os"
+	"path/filepath"
+)
+
+const (
+	servicesDir = "services"
+	packagesDir = "packages"
+)
+
+// ExportSummary counts of objects written by the export
+type ExportSummary struct {
+	Directory string `json:"directory"`
+	Services  int    `json:"services"`
+	Endpoints int    `json:"endpoints"`
+	Methods   int    `json:"methods"`
+	Packages  int    `json:"packages"`
+	Plans     int    `json:"plans"`
+	Files     int    `json:"files"`
+}
+
+// ExportArea reads the services and packages of the area and writes these into the directory tree. Any previous
+// export in this directory is replaced.
+func ExportArea(ctx context.Context, cl v3client.Client, dir string, format Format) (ExportSummary, error) {
+	if area, err := ReadArea(ctx, cl); err != nil {
+		return ExportSummary{Directory: dir}, err
+	} else {
+		return WriteTree(area, dir, format)
+	}
+}
+
+// ReadArea reads the configuration of all services and packages in the area
+func ReadArea(ctx context.Context, cl v3client.Client) (*Area, error) {
+	rv := &Area{}
+
+	services, err := cl.ListServices(ctx)
+	if err != nil {
+		return nil, &errwrap.WrappedError{Context: "listing services", Cause: err}
+	}
+	for _, svc := range services {
+		if st, err := readService(ctx, cl, svc); err != nil {
+			return nil, &errwrap.WrappedError{Context: fmt.Sprintf("reading service %s", svc.Id), Cause: err}
+		} else {
+			rv.Services = append(rv.Services, st)
+		}
+	}
+
+	packages, err := cl.ListPackages(ctx)
+	if err != nil {
+		return nil, &errwrap.WrappedError{Context: "listing packages", Cause: err}
+	}
+	for _, pack := range packages {
+		if pt, err := readPackage(ctx, cl, pack); err != nil {
+			return nil, &errwrap.WrappedError{Context: fmt.Sprintf("reading package %s", pack.Id), Cause: err}
+		} else {
+			rv.Packages = append(rv.Packages, pt)
+		}
+	}
+
+	rv.Sort()
+	return rv, nil
+}
+
+func readService(ctx context.Context, cl v3client.Client, svc masherytypes.Service) (ServiceTree, error) {
+	ident := svc.Identifier()
+	rv := ServiceTree{Service: svc}
+
+	if cache, exists, err := cl.GetServiceCache(ctx, ident); err != nil {
+		return rv, err
+	} else if exists {
+		rv.Cache = &cache
+	}
+
+	if oauth, exists, err := cl.GetServiceOAuthSecurityProfile(ctx, ident); err != nil {
+		return rv, err
+	} else if exists {
+		rv.OAuth = &oauth
+	}
+
+	if roles, exists, err := cl.GetServiceRoles(ctx, ident); err != nil {
+		return rv, err
+	} else if exists {
+		rv.Roles = &roles
+	}
+
+	if sets, err := cl.ListErrorSets(ctx, ident, nil); err != nil {
+		return rv, err
+	} else {
+		rv.ErrorSets = sets
+	}
+
+	endpoints, err := cl.ListEndpointsWithFullInfo(ctx, ident)
+	if err != nil {
+		return rv, err
+	}
+
+	for _, endp := range endpoints {
+		endp.ParentServiceId = ident
+		et := EndpointTree{Endpoint: endp}
+
+		methods, err := cl.ListEndpointMethodsWithFullInfo(ctx, endp.Identifier())
+		if err != nil {
+			return rv, err
+		}
+
+		for _, meth := range methods {
+			meth.ParentEndpointId = endp.Identifier()
+			filters, err := cl.ListEndpointMethodFiltersWithFullInfo(ctx, meth.Identifier())
+			if err != nil {
+				return rv, err
+			}
+			et.Methods = append(et.Methods, MethodTree{Method: meth, Filters: filters})
+		}
+
+		rv.Endpoints = append(rv.Endpoints, et)
+	}
+
+	return rv, nil
+}
+
+func readPackage(ctx context.Context, cl v3client.Client, pack masherytypes.Package) (PackageTree, error) {
+	rv := PackageTree{Package: pack}
+
+	plans, err := cl.ListPlans(ctx, pack.Identifier())
+	if err != nil {
+		return rv, err
+	}
+
+	for _, plan := range plans {
+		plan.ParentPackageId = pack.Identifier()
+		pt := PlanTree{Plan: plan}
+
+		services, err := cl.ListPlanServices(ctx, plan.Identifier())
+		if err != nil {
+			return rv, err
+		}
+
+		for _, svc := range services {
+			planSvc := masherytypes.PackagePlanServiceIdentifier{
+				PackagePlanIdentifier: plan.Identifier(),
+				ServiceIdentifier:     svc.Identifier(),
+			}
+			pst := PlanServiceTree{Service: reference(svc.AddressableV3Object)}
+
+			endpoints, err := cl.ListPlanEndpoints(ctx, planSvc)
+			if err != nil {
+				return rv, err
+			}
+
+			for _, endp := range endpoints {
+				planEndp := masherytypes.PackagePlanServiceEndpointIdentifier{
+					PackagePlanIdentifier: plan.Identifier(),
+					ServiceEndpointIdentifier: masherytypes.ServiceEndpointIdentifier{
+						ServiceIdentifier: svc.Identifier(),
+						EndpointId:        endp.Id,
+					},
+				}
+				pet := PlanEndpointTree{Endpoint: reference(endp)}
+
+				methods, err := cl.ListPackagePlanMethods(ctx, planEndp)
+				if err != nil {
+					return rv, err
+				}
+
+				for _, meth := range methods {
+					pm := PlanMethod{Id: meth.Id, Name: meth.Name}
+
+					methIdent := masherytypes.PackagePlanServiceEndpointMethodIdentifier{
+						PackagePlanIdentifier: plan.Identifier(),
+						ServiceEndpointMethodIdentifier: masherytypes.ServiceEndpointMethodIdentifier{
+							ServiceEndpointIdentifier: planEndp.ServiceEndpointIdentifier,
+							MethodId:                  meth.Id,
+						},
+					}
+					if filter, exists, err := cl.GetPackagePlanMethodFilter(ctx, methIdent); err != nil {
+						return rv, err
+					} else if exists {
+						ref := reference(filter.AddressableV3Object)
+						pm.ResponseFilter = &ref
+					}
+
+					pet.Methods = append(pet.Methods, pm)
+				}
+
+				pst.Endpoints = append(pst.Endpoints, pet)
+			}
+
+			pt.Services = append(pt.Services, pst)
+		}
+
+		rv.Plans = append(rv.Plans, pt)
+	}
+
+	return rv, nil
+}
+
+func reference(obj masherytypes.AddressableV3Object) masherytypes.AddressableV3Object {
+	return masherytypes.AddressableV3Object{Id: obj.Id, Name: obj.Name}
+}
+
+// treeWriter writes the objects into the files under the root directory
+type treeWriter struct {
+	root    string
+	format  Format
+	summary ExportSummary
+}
+
+func (tw *treeWriter) write(obj interface{}, path ...string) error {
+	elems := append([]string{tw.root}, path...)
+	file := filepath.Join(elems...) + tw.format.Extension()
+
+	dat, err := encode(obj, tw.format)
+	if err != nil {
+		return &errwrap.WrappedError{Context: fmt.Sprintf("encoding %s", file), Cause: err}
+	}
+
+	if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	if err = os.WriteFile(file, dat, 0644); err != nil {
+		return err
+	}
+
+	tw.summary.Files++
+	return nil
+}
+
+// WriteTree writes the area into the directory, one file per object. The services and packages directories
+// are removed before writing, so that the tree doesn't retain objects that were deleted since the previous export.
+func WriteTree(area *Area, dir string, format Format) (ExportSummary, error) {
+	tw := &treeWriter{root: dir, format: format, summary: ExportSummary{Directory: dir}}
+
+	for _, sub := range []string{servicesDir, packagesDir} {
+		if err := os.RemoveAll(filepath.Join(dir, sub)); err != nil {
+			return tw.summary, err
+		}
+	}
+
+	area.Sort()
+
+	for _, st := range area.Services {
+		if err := tw.writeService(st); err != nil {
+			return tw.summary, err
+		}
+	}
+	for _, pt := range area.Packages {
+		if err := tw.writePackage(pt); err != nil {
+			return tw.summary, err
+		}
+	}
+
+	return tw.summary, nil
+}
+
+func (tw *treeWriter) writeService(st ServiceTree) error {
+	svcDir := filepath.Join(servicesDir, st.Service.Id)
+
+	if err := tw.write(serviceContent(st.Service), svcDir, "service"); err != nil {
+		return err
+	}
+	tw.summary.Services++
+
+	if st.Cache != nil {
+		if err := tw.write(st.Cache, svcDir, "cache"); err != nil {
+			return err
+		}
+	}
+	if st.OAuth != nil {
+		if err := tw.write(st.OAuth, svcDir, "oauth"); err != nil {
+			return err
+		}
+	}
+	if st.Roles != nil {
+		if err := tw.write(st.Roles, svcDir, "roles"); err != nil {
+			return err
+		}
+	}
+
+	for _, es := range st.ErrorSets {
+		if err := tw.write(es, svcDir, "errorSets", es.Id); err != nil {
+			return err
+		}
+	}
+
+	for _, et := range st.Endpoints {
+		endpDir := filepath.Join(svcDir, "endpoints", et.Endpoint.Id)
+
+		if err := tw.write(endpointContent(et.Endpoint), endpDir, "endpoint"); err != nil {
+			return err
+		}
+		tw.summary.Endpoints++
+
+		for _, mt := range et.Methods {
+			methDir := filepath.Join(endpDir, "methods", mt.Method.Id)
+			if err := tw.write(mt.Method, methDir, "method"); err != nil {
+				return err
+			}
+			tw.summary.Methods++
+
+			for _, f := range mt.Filters {
+				if err := tw.write(f, methDir, "filters", f.Id); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (tw *treeWriter) writePackage(pt PackageTree) error {
+	packDir := filepath.Join(packagesDir, pt.Package.Id)
+
+	if err := tw.write(packageContent(pt.Package), packDir, "package"); err != nil {
+		return err
+	}
+	tw.summary.Packages++
+
+	for _, plt := range pt.Plans {
+		planDir := filepath.Join(packDir, "plans", plt.Plan.Id)
+
+		if err := tw.write(planContent(plt.Plan), planDir, "plan"); err != nil {
+			return err
+		}
+		tw.summary.Plans++
+
+		for _, pst := range plt.Services {
+			svcDir := filepath.Join(planDir, "services", pst.Service.Id)
+			if err := tw.write(pst.Service, svcDir, "service"); err != nil {
+				return err
+			}
+
+			for _, pet := range pst.Endpoints {
+				endpDir := filepath.Join(svcDir, "endpoints", pet.Endpoint.Id)
+				if err := tw.write(pet.Endpoint, endpDir, "endpoint"); err != nil {
+					return err
+				}
+
+				for _, pm := range pet.Methods {
+					if err := tw.write(pm, endpDir, "methods", pm.Id); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package declarative
+
+import (
+	"context"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client/fakeserver"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type sampleArea struct {
+	svc  masherytypes.Service
+	endp masherytypes.Endpoint
+	meth masherytypes.ServiceEndpointMethod
+	pack masherytypes.Package
+	plan masherytypes.Plan
+}
+
+func createSampleArea(t *testing.T, cl v3client.Client) sampleArea {
+	ctx := context.Background()
+	rv := sampleArea{}
+	var err error
+
+	rv.svc, err = cl.CreateService(ctx, masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}, Version: "1.0"})
+	assert.Nil(t, err)
+
+	_, err = cl.CreateServiceCache(ctx, rv.svc.Identifier(), masherytypes.ServiceCache{CacheTtl: 5})
+	assert.Nil(t, err)
+
+	_, err = cl.CreateErrorSet(ctx, rv.svc.Identifier(), masherytypes.ErrorSet{AddressableV3Object: masherytypes.AddressableV3Object{Name: "errors"}})
+	assert.Nil(t, err)
+
+	rv.endp, err = cl.CreateEndpoint(ctx, rv.svc.Identifier(), masherytypes.Endpoint{
+		AddressableV3Object: masherytypes.AddressableV3Object{Name: "endp"},
+		RequestPathAlias:    "/path",
+	})
+	assert.Nil(t, err)
+
+	rv.meth, err = cl.CreateEndpointMethod(ctx, rv.endp.Identifier(), masherytypes.ServiceEndpointMethod{
+		BaseMethod: masherytypes.BaseMethod{AddressableV3Object: masherytypes.AddressableV3Object{Name: "meth"}},
+	})
+	assert.Nil(t, err)
+
+	_, err = cl.CreateEndpointMethodFilter(ctx, rv.meth.Identifier(), masherytypes.ServiceEndpointMethodFilter{
+		ResponseFilter: masherytypes.ResponseFilter{AddressableV3Object: masherytypes.AddressableV3Object{Name: "filter"}},
+	})
+	assert.Nil(t, err)
+
+	rv.pack, err = cl.CreatePackage(ctx, masherytypes.Package{AddressableV3Object: masherytypes.AddressableV3Object{Name: "pack"}})
+	assert.Nil(t, err)
+
+	rv.plan, err = cl.CreatePlan(ctx, rv.pack.Identifier(), masherytypes.Plan{AddressableV3Object: masherytypes.AddressableV3Object{Name: "plan"}})
+	assert.Nil(t, err)
+
+	_, err = cl.CreatePlanService(ctx, masherytypes.PackagePlanServiceIdentifier{
+		PackagePlanIdentifier: rv.plan.Identifier(),
+		ServiceIdentifier:     rv.svc.Identifier(),
+	})
+	assert.Nil(t, err)
+
+	_, err = cl.CreatePlanEndpoint(ctx, masherytypes.PackagePlanServiceEndpointIdentifier{
+		PackagePlanIdentifier:     rv.plan.Identifier(),
+		ServiceEndpointIdentifier: rv.endp.Identifier(),
+	})
+	assert.Nil(t, err)
+
+	_, err = cl.CreatePackagePlanMethod(ctx, masherytypes.PackagePlanServiceEndpointMethodIdentifier{
+		PackagePlanIdentifier:           rv.plan.Identifier(),
+		ServiceEndpointMethodIdentifier: rv.meth.Identifier(),
+	})
+	assert.Nil(t, err)
+
+	return rv
+}
+
+func newFakeClient() (*fakeserver.Server, v3client.Client) {
+	srv := fakeserver.New()
+	return srv, v3client.NewHttpClient(v3client.Params{MashEndpoint: srv.Endpoint(), QPS: 100})
+}
+
+func TestExportArea(t *testing.T) {
+	srv, cl := newFakeClient()
+	defer srv.Close()
+
+	sample := createSampleArea(t, cl)
+	dir := t.TempDir()
+
+	summary, err := ExportArea(context.Background(), cl, dir, FormatYAML)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, summary.Services)
+	assert.Equal(t, 1, summary.Endpoints)
+	assert.Equal(t, 1, summary.Methods)
+	assert.Equal(t, 1, summary.Packages)
+	assert.Equal(t, 1, summary.Plans)
+
+	svcFile := filepath.Join(dir, "services", sample.svc.Id, "service.yaml")
+	dat, err := os.ReadFile(svcFile)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(dat), "name: svc"))
+	assert.False(t, strings.Contains(string(dat), "created"))
+	assert.False(t, strings.Contains(string(dat), "updated"))
+	assert.False(t, strings.Contains(string(dat), "revisionNumber"))
+
+	for _, f := range []string{
+		filepath.Join("services", sample.svc.Id, "cache.yaml"),
+		filepath.Join("services", sample.svc.Id, "endpoints", sample.endp.Id, "endpoint.yaml"),
+		filepath.Join("services", sample.svc.Id, "endpoints", sample.endp.Id, "methods", sample.meth.Id, "method.yaml"),
+		filepath.Join("packages", sample.pack.Id, "package.yaml"),
+		filepath.Join("packages", sample.pack.Id, "plans", sample.plan.Id, "plan.yaml"),
+		filepath.Join("packages", sample.pack.Id, "plans", sample.plan.Id, "services", sample.svc.Id, "endpoints", sample.endp.Id, "methods", sample.meth.Id+".yaml"),
+	} {
+		_, statErr := os.Stat(filepath.Join(dir, f))
+		assert.Nil(t, statErr, f)
+	}
+
+	// Repeated export has to produce the identical output
+	_, err = ExportArea(context.Background(), cl, dir, FormatYAML)
+	assert.Nil(t, err)
+	again, _ := os.ReadFile(svcFile)
+	assert.Equal(t, string(dat), string(again))
+}
+
+func TestExportAreaAsJSON(t *testing.T) {
+	srv, cl := newFakeClient()
+	defer srv.Close()
+
+	sample := createSampleArea(t, cl)
+	dir := t.TempDir()
+
+	summary, err := ExportArea(context.Background(), cl, dir, FormatJSON)
+	assert.Nil(t, err)
+	assert.True(t, summary.Files > 0)
+
+	dat, err := os.ReadFile(filepath.Join(dir, "services", sample.svc.Id, "endpoints", sample.endp.Id, "endpoint.json"))
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(dat), `"requestPathAlias": "/path"`))
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("json")
+	assert.Nil(t, err)
+	assert.Equal(t, FormatJSON, f)
+
+	f, err = ParseFormat("")
+	assert.Nil(t, err)
+	assert.Equal(t, FormatYAML, f)
+
+	_, err = ParseFormat("xml")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,147 @@
+// Package declarative supports managing Mashery area configuration as files: an area can be exported into
+// a directory tree that is suitable for version control, and later brought back into the state described by the tree.
+package declarative
+
+import (
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"sort"
+)
+
+// Area configuration of the Mashery area, either read from the area itself or from the directory tree.
+type Area struct {
+	Services []ServiceTree
+	Packages []PackageTree
+}
+
+// ServiceTree a service with its dependent objects
+type ServiceTree struct {
+	Service   masherytypes.Service
+	Cache     *masherytypes.ServiceCache
+	OAuth     *masherytypes.MasheryOAuth
+	Roles     *[]masherytypes.RolePermission
+	ErrorSets []masherytypes.ErrorSet
+	Endpoints []EndpointTree
+}
+
+// EndpointTree an endpoint with its methods
+type EndpointTree struct {
+	Endpoint masherytypes.Endpoint
+	Methods  []MethodTree
+}
+
+// MethodTree an endpoint method with its response filters
+type MethodTree struct {
+	Method  masherytypes.ServiceEndpointMethod
+	Filters []masherytypes.ServiceEndpointMethodFilter
+}
+
+// PackageTree a package with its plans
+type PackageTree struct {
+	Package masherytypes.Package
+	Plans   []PlanTree
+}
+
+// PlanTree a plan with the services and endpoints included into it
+type PlanTree struct {
+	Plan     masherytypes.Plan
+	Services []PlanServiceTree
+}
+
+// PlanServiceTree a service included into the plan. Only Id and Name of the referenced object are stored.
+type PlanServiceTree struct {
+	Service   masherytypes.AddressableV3Object
+	Endpoints []PlanEndpointTree
+}
+
+// PlanEndpointTree an endpoint included into the plan
+type PlanEndpointTree struct {
+	Endpoint masherytypes.AddressableV3Object
+	Methods  []PlanMethod
+}
+
+// PlanMethod a method included into the plan, with an optional response filter
+type PlanMethod struct {
+	Id             string                            `json:"id"`
+	Name           string                            `json:"name,omitempty"`
+	ResponseFilter *masherytypes.AddressableV3Object `json:"responseFilter,omitempty"`
+}
+
+// Sort orders all objects of the area by their identifiers, which makes the output stable
+func (a *Area) Sort() {
+	sort.Slice(a.Services, func(i, j int) bool { return a.Services[i].Service.Id < a.Services[j].Service.Id })
+	for i := range a.Services {
+		a.Services[i].sort()
+	}
+
+	sort.Slice(a.Packages, func(i, j int) bool { return a.Packages[i].Package.Id < a.Packages[j].Package.Id })
+	for i := range a.Packages {
+		a.Packages[i].sort()
+	}
+}
+
+func (st *ServiceTree) sort() {
+	sort.Slice(st.ErrorSets, func(i, j int) bool { return st.ErrorSets[i].Id < st.ErrorSets[j].Id })
+	for _, es := range st.ErrorSets {
+		if es.ErrorMessages != nil {
+			msgs := *es.ErrorMessages
+			sort.Slice(msgs, func(i, j int) bool { return msgs[i].Id < msgs[j].Id })
+		}
+	}
+
+	if st.Roles != nil {
+		roles := *st.Roles
+		sort.Slice(roles, func(i, j int) bool { return roles[i].Id < roles[j].Id })
+	}
+
+	sort.Slice(st.Endpoints, func(i, j int) bool { return st.Endpoints[i].Endpoint.Id < st.Endpoints[j].Endpoint.Id })
+	for _, endp := range st.Endpoints {
+		sort.Slice(endp.Methods, func(i, j int) bool { return endp.Methods[i].Method.Id < endp.Methods[j].Method.Id })
+		for _, meth := range endp.Methods {
+			sort.Slice(meth.Filters, func(i, j int) bool { return meth.Filters[i].Id < meth.Filters[j].Id })
+		}
+	}
+}
+
+func (pt *PackageTree) sort() {
+	sort.Slice(pt.Plans, func(i, j int) bool { return pt.Plans[i].Plan.Id < pt.Plans[j].Plan.Id })
+	for _, plan := range pt.Plans {
+		sort.Slice(plan.Services, func(i, j int) bool { return plan.Services[i].Service.Id < plan.Services[j].Service.Id })
+		for _, svc := range plan.Services {
+			sort.Slice(svc.Endpoints, func(i, j int) bool { return svc.Endpoints[i].Endpoint.Id < svc.Endpoints[j].Endpoint.Id })
+			for _, endp := range svc.Endpoints {
+				sort.Slice(endp.Methods, func(i, j int) bool { return endp.Methods[i].Id < endp.Methods[j].Id })
+			}
+		}
+	}
+}
+
+// serviceContent returns the properties of the service itself, without the nested objects that are stored
+// in separate files
+func serviceContent(svc masherytypes.Service) masherytypes.Service {
+	svc.Endpoints = nil
+	svc.ErrorSets = nil
+	svc.Cache = nil
+	svc.SecurityProfile = nil
+	svc.Roles = nil
+	return svc
+}
+
+func endpointContent(endp masherytypes.Endpoint) masherytypes.Endpoint {
+	endp.Methods = nil
+	return endp
+}
+
+func errorSetContent(set masherytypes.ErrorSet) masherytypes.ErrorSet {
+	set.ErrorMessages = nil
+	return set
+}
+
+func packageContent(pack masherytypes.Package) masherytypes.Package {
+	pack.Plans = nil
+	return pack
+}
+
+func planContent(plan masherytypes.Plan) masherytypes.Plan {
+	plan.Services = nil
+	return plan
+}
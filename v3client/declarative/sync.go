@@ -0,0 +1,789 @@
+package declarative
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/errwrap"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"io"
+	"sort"
+)
+
+// Action kind of the change applied to the object
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Phases determine the order in which the operations are applied. Plan bindings are removed first, so that
+// the service objects they refer to can be deleted; the bindings are created last, after the objects they
+// refer to were created.
+const (
+	phaseDeletePlanMethodFilter = iota
+	phaseDeletePlanMethod
+	phaseDeletePlanEndpoint
+	phaseDeletePlanService
+	phaseDeletePlan
+	phaseDeletePackage
+	phaseUpsertService
+	phaseDeleteMethodFilter
+	phaseDeleteMethod
+	phaseDeleteEndpoint
+	phaseDeleteServiceDetail
+	phaseDeleteService
+	phaseUpsertPackage
+	phaseCreatePlanService
+	phaseCreatePlanEndpoint
+	phaseCreatePlanMethod
+	phaseCreatePlanMethodFilter
+)
+
+type operationFunc func(ctx context.Context, cl v3client.Client) error
+
+// Operation a single change to be applied to the area
+type Operation struct {
+	Action Action `json:"action"`
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	// Changes lists the modified properties of the updated objects
+	Changes masherytypes.PropertyChanges `json:"changes,omitempty"`
+
+	phase int
+	exec  operationFunc
+}
+
+func (o *Operation) String() string {
+	return fmt.Sprintf("%s %s %s", o.Action, o.Kind, o.Name)
+}
+
+// ChangeSet operations required to bring the area into the desired state, in the order of their execution.
+type ChangeSet struct {
+	Operations []*Operation `json:"operations"`
+	DryRun     bool         `json:"dryRun"`
+
+	// ids maps identifiers in the desired state to the identifiers of the objects created in the area
+	ids map[string]string
+}
+
+// Empty checks whether the area is already in the desired state
+func (cs *ChangeSet) Empty() bool {
+	return len(cs.Operations) == 0
+}
+
+// Print writes the human-readable list of operations
+func (cs *ChangeSet) Print(w io.Writer) {
+	if cs.Empty() {
+		_, _ = fmt.Fprintln(w, "No changes are required")
+		return
+	}
+
+	markers := map[Action]string{ActionCreate: "+", ActionUpdate: "~", ActionDelete: "-"}
+	for _, op := range cs.Operations {
+		_, _ = fmt.Fprintf(w, "%s %s\n", markers[op.Action], op.String())
+		for _, c := range op.Changes {
+			_, _ = fmt.Fprintf(w, "    %s\n", c.String())
+		}
+	}
+}
+
+// Apply executes the operations in order, stopping at the first error.
+func (cs *ChangeSet) Apply(ctx context.Context, cl v3client.Client) error {
+	for _, op := range cs.Operations {
+		if err := op.exec(ctx, cl); err != nil {
+			return &errwrap.WrappedError{Context: op.String(), Cause: err}
+		}
+	}
+	return nil
+}
+
+// SyncOptions the options of the area synchronization
+type SyncOptions struct {
+	// DryRun the operations are computed but not executed
+	DryRun bool
+	// AllowDeleteAll allows the desired state without objects, and the change sets deleting all services and
+	// packages of the area. Without it, such synchronization is refused, as it is most likely caused by
+	// the wrong source of the desired state.
+	AllowDeleteAll bool
+}
+
+// ErrDeleteAll the synchronization would delete all services and packages of the area
+var ErrDeleteAll = errors.New("synchronization would delete all services and packages of the area")
+
+// deletesAll checks whether the change set deletes all services and packages of the live area
+func (cs *ChangeSet) deletesAll(live *Area) bool {
+	liveObjects := len(live.Services) + len(live.Packages)
+	if liveObjects == 0 {
+		return false
+	}
+
+	deleted := 0
+	for _, op := range cs.Operations {
+		if op.Action == ActionDelete && (op.Kind == "service" || op.Kind == "package") {
+			deleted++
+		}
+	}
+	return deleted >= liveObjects
+}
+
+// SyncArea reads the live state of the area and brings it into the desired state. Unless allowed by the options,
+// the desired state without objects and the change set deleting all services and packages of the area are refused
+// with ErrDeleteAll.
+func SyncArea(ctx context.Context, cl v3client.Client, desired *Area, opts SyncOptions) (*ChangeSet, error) {
+	if !opts.AllowDeleteAll && len(desired.Services) == 0 && len(desired.Packages) == 0 {
+		return nil, &errwrap.WrappedError{Context: "desired state contains no services or packages", Cause: ErrDeleteAll}
+	}
+
+	live, err := ReadArea(ctx, cl)
+	if err != nil {
+		return nil, err
+	}
+
+	cs := Diff(live, desired)
+	cs.DryRun = opts.DryRun
+	if !opts.AllowDeleteAll && cs.deletesAll(live) {
+		return cs, ErrDeleteAll
+	}
+
+	if !opts.DryRun {
+		err = cs.Apply(ctx, cl)
+	}
+	return cs, err
+}
+
+// ApplyTree reads the desired state from the directory tree and synchronizes the area with it.
+func ApplyTree(ctx context.Context, cl v3client.Client, dir string, opts SyncOptions) (*ChangeSet, error) {
+	if desired, err := ReadTree(dir); err != nil {
+		return nil, err
+	} else {
+		return SyncArea(ctx, cl, desired, opts)
+	}
+}
+
+// Diff computes the operations that transform the live state into the desired one. Objects are matched by
+// identifier or, where the desired object carries no identifier, by name. The operations refer to the desired
+// area, which receives the identifiers of the created objects as the change set is applied.
+func Diff(live, desired *Area) *ChangeSet {
+	cs := &ChangeSet{ids: map[string]string{}}
+
+	matched, orphans := pair(live.Services, desired.Services, func(st *ServiceTree) masherytypes.AddressableV3Object {
+		return st.Service.AddressableV3Object
+	})
+	for i := range desired.Services {
+		cs.diffService(matched[i], &desired.Services[i])
+	}
+	for _, l := range orphans {
+		ident := l.Service.Identifier()
+		cs.add(phaseDeleteService, ActionDelete, "service", l.Service.Name, func(ctx context.Context, cl v3client.Client) error {
+			return cl.DeleteService(ctx, ident)
+		})
+	}
+
+	matchedPacks, orphanPacks := pair(live.Packages, desired.Packages, func(pt *PackageTree) masherytypes.AddressableV3Object {
+		return pt.Package.AddressableV3Object
+	})
+	for i := range desired.Packages {
+		cs.diffPackage(matchedPacks[i], &desired.Packages[i])
+	}
+	for _, l := range orphanPacks {
+		ident := l.Package.Identifier()
+		cs.add(phaseDeletePackage, ActionDelete, "package", l.Package.Name, func(ctx context.Context, cl v3client.Client) error {
+			return cl.DeletePackage(ctx, ident)
+		})
+	}
+
+	sort.SliceStable(cs.Operations, func(i, j int) bool {
+		return cs.Operations[i].phase < cs.Operations[j].phase
+	})
+	return cs
+}
+
+func (cs *ChangeSet) add(phase int, action Action, kind, name string, exec operationFunc) *Operation {
+	rv := &Operation{
+		Action: action,
+		Kind:   kind,
+		Name:   name,
+		phase:  phase,
+		exec:   exec,
+	}
+	cs.Operations = append(cs.Operations, rv)
+	return rv
+}
+
+// created records the identifier assigned to the object by Mashery
+func (cs *ChangeSet) created(desiredId *string, newId string) {
+	if len(*desiredId) > 0 {
+		cs.ids[*desiredId] = newId
+	}
+	*desiredId = newId
+}
+
+// resolve returns the identifier of the object in the area
+func (cs *ChangeSet) resolve(id string) string {
+	if v, ok := cs.ids[id]; ok {
+		return v
+	}
+	return id
+}
+
+// pair matches the desired objects with the live ones. The first returned value is aligned with the desired
+// slice and contains nil where no live object matches; the second contains the live objects that were not matched.
+func pair[T any](live, desired []T, key func(*T) masherytypes.AddressableV3Object) ([]*T, []*T) {
+	matched := make([]*T, len(desired))
+	used := make([]bool, len(live))
+
+	for i := range desired {
+		dk := key(&desired[i])
+		for j := range live {
+			if used[j] {
+				continue
+			}
+
+			lk := key(&live[j])
+			if (len(dk.Id) > 0 && dk.Id == lk.Id) || (len(dk.Id) == 0 && dk.Name == lk.Name) {
+				matched[i] = &live[j]
+				used[j] = true
+				break
+			}
+		}
+	}
+
+	var orphans []*T
+	for j := range live {
+		if !used[j] {
+			orphans = append(orphans, &live[j])
+		}
+	}
+
+	return matched, orphans
+}
+
+// contentChanges compares the objects, ignoring server-managed fields and the identifier of the object itself.
+func contentChanges(live, desired interface{}) masherytypes.PropertyChanges {
+	if rv, err := masherytypes.Diff(live, desired); err != nil {
+		return masherytypes.PropertyChanges{{Path: "", Old: live, New: desired}}
+	} else {
+		return rv
+	}
+}
+
+func sameContent(live, desired interface{}) bool {
+	return contentChanges(live, desired).Empty()
+}
+
+// -------------------------------------------------------------------------------------------------------------
+// Services
+
+func (cs *ChangeSet) diffService(l *ServiceTree, d *ServiceTree) {
+	name := d.Service.Name
+	serviceId := func() masherytypes.ServiceIdentifier {
+		return masherytypes.ServiceIdentifier{ServiceId: d.Service.Id}
+	}
+
+	if l == nil {
+		cs.add(phaseUpsertService, ActionCreate, "service", name, func(ctx context.Context, cl v3client.Client) error {
+			upsert := serviceContent(d.Service)
+			upsert.Id = ""
+			created, err := cl.CreateService(ctx, upsert)
+			if err == nil {
+				cs.created(&d.Service.Id, created.Id)
+			}
+			return err
+		})
+		l = &ServiceTree{}
+	} else {
+		d.Service.Id = l.Service.Id
+		if changes := contentChanges(serviceContent(l.Service), serviceContent(d.Service)); !changes.Empty() {
+			cs.add(phaseUpsertService, ActionUpdate, "service", name, func(ctx context.Context, cl v3client.Client) error {
+				_, err := cl.UpdateService(ctx, serviceContent(d.Service))
+				return err
+			}).Changes = changes
+		}
+	}
+
+	cs.diffServiceCache(l, d, name, serviceId)
+	cs.diffServiceOAuth(l, d, name, serviceId)
+	cs.diffServiceRoles(l, d, name, serviceId)
+	cs.diffErrorSets(l, d, name, serviceId)
+
+	matched, orphans := pair(l.Endpoints, d.Endpoints, func(et *EndpointTree) masherytypes.AddressableV3Object {
+		return et.Endpoint.AddressableV3Object
+	})
+	for i := range d.Endpoints {
+		cs.diffEndpoint(matched[i], &d.Endpoints[i], name, serviceId)
+	}
+	for _, o := range orphans {
+		ident := masherytypes.ServiceEndpointIdentifier{ServiceIdentifier: l.Service.Identifier(), EndpointId: o.Endpoint.Id}
+		cs.add(phaseDeleteEndpoint, ActionDelete, "endpoint", name+"/"+o.Endpoint.Name, func(ctx context.Context, cl v3client.Client) error {
+			return cl.DeleteEndpoint(ctx, ident)
+		})
+	}
+}
+
+func (cs *ChangeSet) diffServiceCache(l, d *ServiceTree, name string, serviceId func() masherytypes.ServiceIdentifier) {
+	if d.Cache == nil && l.Cache != nil {
+		ident := l.Service.Identifier()
+		cs.add(phaseDeleteServiceDetail, ActionDelete, "service cache", name, func(ctx context.Context, cl v3client.Client) error {
+			return cl.DeleteServiceCache(ctx, ident)
+		})
+	} else if d.Cache != nil && l.Cache == nil {
+		cs.add(phaseUpsertService, ActionCreate, "service cache", name, func(ctx context.Context, cl v3client.Client) error {
+			_, err := cl.CreateServiceCache(ctx, serviceId(), *d.Cache)
+			return err
+		})
+	} else if d.Cache != nil {
+		if changes := contentChanges(*l.Cache, *d.Cache); !changes.Empty() {
+			cs.add(phaseUpsertService, ActionUpdate, "service cache", name, func(ctx context.Context, cl v3client.Client) error {
+				upsert := *d.Cache
+				upsert.ParentServiceId = serviceId()
+				_, err := cl.UpdateServiceCache(ctx, upsert)
+				return err
+			}).Changes = changes
+		}
+	}
+}
+
+func (cs *ChangeSet) diffServiceOAuth(l, d *ServiceTree, name string, serviceId func() masherytypes.ServiceIdentifier) {
+	if d.OAuth == nil && l.OAuth != nil {
+		ident := l.Service.Identifier()
+		cs.add(phaseDeleteServiceDetail, ActionDelete, "service oauth", name, func(ctx context.Context, cl v3client.Client) error {
+			return cl.DeleteServiceOAuthSecurityProfile(ctx, ident)
+		})
+	} else if d.OAuth != nil && l.OAuth == nil {
+		cs.add(phaseUpsertService, ActionCreate, "service oauth", name, func(ctx context.Context, cl v3client.Client) error {
+			_, err := cl.CreateServiceOAuthSecurityProfile(ctx, serviceId(), *d.OAuth)
+			return err
+		})
+	} else if d.OAuth != nil {
+		if changes := contentChanges(*l.OAuth, *d.OAuth); !changes.Empty() {
+			cs.add(phaseUpsertService, ActionUpdate, "service oauth", name, func(ctx context.Context, cl v3client.Client) error {
+				upsert := *d.OAuth
+				upsert.ParentService = serviceId()
+				_, err := cl.UpdateServiceOAuthSecurityProfile(ctx, upsert)
+				return err
+			}).Changes = changes
+		}
+	}
+}
+
+func (cs *ChangeSet) diffServiceRoles(l, d *ServiceTree, name string, serviceId func() masherytypes.ServiceIdentifier) {
+	if d.Roles == nil && l.Roles != nil {
+		ident := l.Service.Identifier()
+		cs.add(phaseDeleteServiceDetail, ActionDelete, "service roles", name, func(ctx context.Context, cl v3client.Client) error {
+			return cl.DeleteServiceRoles(ctx, ident)
+		})
+	} else if d.Roles != nil && (l.Roles == nil || !sameContent(*l.Roles, *d.Roles)) {
+		action := ActionUpdate
+		if l.Roles == nil {
+			action = ActionCreate
+		}
+		cs.add(phaseUpsertService, action, "service roles", name, func(ctx context.Context, cl v3client.Client) error {
+			return cl.SetServiceRoles(ctx, serviceId(), *d.Roles)
+		})
+	}
+}
+
+func (cs *ChangeSet) diffErrorSets(l, d *ServiceTree, name string, serviceId func() masherytypes.ServiceIdentifier) {
+	matched, orphans := pair(l.ErrorSets, d.ErrorSets, func(es *masherytypes.ErrorSet) masherytypes.AddressableV3Object {
+		return es.AddressableV3Object
+	})
+
+	for i := range d.ErrorSets {
+		ds := &d.ErrorSets[i]
+		ls := matched[i]
+		setName := name + "/" + ds.Name
+
+		if ls == nil {
+			cs.add(phaseUpsertService, ActionCreate, "error set", setName, func(ctx context.Context, cl v3client.Client) error {
+				upsert := errorSetContent(*ds)
+				upsert.Id = ""
+				created, err := cl.CreateErrorSet(ctx, serviceId(), upsert)
+				if err == nil {
+					cs.created(&ds.Id, created.Id)
+				}
+				return err
+			})
+		} else {
+			ds.Id = ls.Id
+			if changes := contentChanges(errorSetContent(*ls), errorSetContent(*ds)); !changes.Empty() {
+				cs.add(phaseUpsertService, ActionUpdate, "error set", setName, func(ctx context.Context, cl v3client.Client) error {
+					upsert := errorSetContent(*ds)
+					upsert.ParentServiceId = serviceId()
+					_, err := cl.UpdateErrorSet(ctx, upsert)
+					return err
+				}).Changes = changes
+			}
+		}
+
+		cs.diffErrorMessages(ls, ds, setName, serviceId)
+	}
+
+	for _, o := range orphans {
+		ident := masherytypes.ErrorSetIdentifier{ServiceIdentifier: l.Service.Identifier(), ErrorSetId: o.Id}
+		cs.add(phaseDeleteServiceDetail, ActionDelete, "error set", name+"/"+o.Name, func(ctx context.Context, cl v3client.Client) error {
+			return cl.DeleteErrorSet(ctx, ident)
+		})
+	}
+}
+
+// diffErrorMessages updates the messages of the error set. The set of messages is fixed by Mashery, so the
+// messages are only ever updated.
+func (cs *ChangeSet) diffErrorMessages(ls, ds *masherytypes.ErrorSet, setName string, serviceId func() masherytypes.ServiceIdentifier) {
+	if ds.ErrorMessages == nil {
+		return
+	}
+
+	liveMessages := map[string]masherytypes.MasheryErrorMessage{}
+	if ls != nil && ls.ErrorMessages != nil {
+		for _, m := range *ls.ErrorMessages {
+			liveMessages[m.Id] = m
+		}
+	}
+
+	for _, msg := range *ds.ErrorMessages {
+		if lm, ok := liveMessages[msg.Id]; ok && sameContent(lm, msg) {
+			continue
+		}
+
+		upsert := msg
+		cs.add(phaseUpsertService, ActionUpdate, "error message", setName+"/"+msg.Id, func(ctx context.Context, cl v3client.Client) error {
+			upsert.ParentErrorSet = masherytypes.ErrorSetIdentifier{ServiceIdentifier: serviceId(), ErrorSetId: ds.Id}
+			_, err := cl.UpdateErrorSetMessage(ctx, upsert)
+			return err
+		})
+	}
+}
+
+func (cs *ChangeSet) diffEndpoint(l *EndpointTree, d *EndpointTree, svcName string, serviceId func() masherytypes.ServiceIdentifier) {
+	name := svcName + "/" + d.Endpoint.Name
+	endpointId := func() masherytypes.ServiceEndpointIdentifier {
+		return masherytypes.ServiceEndpointIdentifier{ServiceIdentifier: serviceId(), EndpointId: d.Endpoint.Id}
+	}
+
+	if l == nil {
+		cs.add(phaseUpsertService, ActionCreate, "endpoint", name, func(ctx context.Context, cl v3client.Client) error {
+			upsert := endpointContent(d.Endpoint)
+			upsert.Id = ""
+			created, err := cl.CreateEndpoint(ctx, serviceId(), upsert)
+			if err == nil {
+				cs.created(&d.Endpoint.Id, created.Id)
+			}
+			return err
+		})
+		l = &EndpointTree{}
+	} else {
+		d.Endpoint.Id = l.Endpoint.Id
+		if changes := contentChanges(endpointContent(l.Endpoint), endpointContent(d.Endpoint)); !changes.Empty() {
+			cs.add(phaseUpsertService, ActionUpdate, "endpoint", name, func(ctx context.Context, cl v3client.Client) error {
+				upsert := endpointContent(d.Endpoint)
+				upsert.ParentServiceId = serviceId()
+				_, err := cl.UpdateEndpoint(ctx, upsert)
+				return err
+			}).Changes = changes
+		}
+	}
+
+	matched, orphans := pair(l.Methods, d.Methods, func(mt *MethodTree) masherytypes.AddressableV3Object {
+		return mt.Method.AddressableV3Object
+	})
+	for i := range d.Methods {
+		cs.diffMethod(matched[i], &d.Methods[i], name, endpointId)
+	}
+	for _, o := range orphans {
+		oid := o.Method.Id
+		cs.add(phaseDeleteMethod, ActionDelete, "method", name+"/"+o.Method.Name, func(ctx context.Context, cl v3client.Client) error {
+			return cl.DeleteEndpointMethod(ctx, masherytypes.ServiceEndpointMethodIdentifier{
+				ServiceEndpointIdentifier: endpointId(),
+				MethodId:                  oid,
+			})
+		})
+	}
+}
+
+func (cs *ChangeSet) diffMethod(l *MethodTree, d *MethodTree, endpName string, endpointId func() masherytypes.ServiceEndpointIdentifier) {
+	name := endpName + "/" + d.Method.Name
+	methodId := func() masherytypes.ServiceEndpointMethodIdentifier {
+		return masherytypes.ServiceEndpointMethodIdentifier{ServiceEndpointIdentifier: endpointId(), MethodId: d.Method.Id}
+	}
+
+	if l == nil {
+		cs.add(phaseUpsertService, ActionCreate, "method", name, func(ctx context.Context, cl v3client.Client) error {
+			upsert := d.Method
+			upsert.Id = ""
+			created, err := cl.CreateEndpointMethod(ctx, endpointId(), upsert)
+			if err == nil {
+				cs.created(&d.Method.Id, created.Id)
+			}
+			return err
+		})
+		l = &MethodTree{}
+	} else {
+		d.Method.Id = l.Method.Id
+		if changes := contentChanges(l.Method, d.Method); !changes.Empty() {
+			cs.add(phaseUpsertService, ActionUpdate, "method", name, func(ctx context.Context, cl v3client.Client) error {
+				upsert := d.Method
+				upsert.ParentEndpointId = endpointId()
+				_, err := cl.UpdateEndpointMethod(ctx, upsert)
+				return err
+			}).Changes = changes
+		}
+	}
+
+	matched, orphans := pair(l.Filters, d.Filters, func(f *masherytypes.ServiceEndpointMethodFilter) masherytypes.AddressableV3Object {
+		return f.AddressableV3Object
+	})
+	for i := range d.Filters {
+		df := &d.Filters[i]
+		lf := matched[i]
+		filterName := name + "/" + df.Name
+
+		if lf == nil {
+			cs.add(phaseUpsertService, ActionCreate, "method filter", filterName, func(ctx context.Context, cl v3client.Client) error {
+				upsert := *df
+				upsert.Id = ""
+				created, err := cl.CreateEndpointMethodFilter(ctx, methodId(), upsert)
+				if err == nil {
+					cs.created(&df.Id, created.Id)
+				}
+				return err
+			})
+		} else {
+			df.Id = lf.Id
+			if changes := contentChanges(*lf, *df); !changes.Empty() {
+				cs.add(phaseUpsertService, ActionUpdate, "method filter", filterName, func(ctx context.Context, cl v3client.Client) error {
+					upsert := *df
+					upsert.ServiceEndpointMethod = methodId()
+					_, err := cl.UpdateEndpointMethodFilter(ctx, upsert)
+					return err
+				}).Changes = changes
+			}
+		}
+	}
+	for _, o := range orphans {
+		oid := o.Id
+		cs.add(phaseDeleteMethodFilter, ActionDelete, "method filter", name+"/"+o.Name, func(ctx context.Context, cl v3client.Client) error {
+			return cl.DeleteEndpointMethodFilter(ctx, masherytypes.ServiceEndpointMethodFilterIdentifier{
+				ServiceEndpointMethodIdentifier: methodId(),
+				FilterId:                        oid,
+			})
+		})
+	}
+}
+
+// -------------------------------------------------------------------------------------------------------------
+// Packages and plans
+
+func (cs *ChangeSet) diffPackage(l *PackageTree, d *PackageTree) {
+	name := d.Package.Name
+	packageId := func() masherytypes.PackageIdentifier {
+		return masherytypes.PackageIdentifier{PackageId: d.Package.Id}
+	}
+
+	if l == nil {
+		cs.add(phaseUpsertPackage, ActionCreate, "package", name, func(ctx context.Context, cl v3client.Client) error {
+			upsert := packageContent(d.Package)
+			upsert.Id = ""
+			created, err := cl.CreatePackage(ctx, upsert)
+			if err == nil {
+				cs.created(&d.Package.Id, created.Id)
+			}
+			return err
+		})
+		l = &PackageTree{}
+	} else {
+		d.Package.Id = l.Package.Id
+		if changes := contentChanges(packageContent(l.Package), packageContent(d.Package)); !changes.Empty() {
+			cs.add(phaseUpsertPackage, ActionUpdate, "package", name, func(ctx context.Context, cl v3client.Client) error {
+				_, err := cl.UpdatePackage(ctx, packageContent(d.Package))
+				return err
+			}).Changes = changes
+		}
+	}
+
+	matched, orphans := pair(l.Plans, d.Plans, func(pt *PlanTree) masherytypes.AddressableV3Object {
+		return pt.Plan.AddressableV3Object
+	})
+	for i := range d.Plans {
+		cs.diffPlan(matched[i], &d.Plans[i], name, packageId)
+	}
+	for _, o := range orphans {
+		ident := masherytypes.PackagePlanIdentifier{PackageIdentifier: l.Package.Identifier(), PlanId: o.Plan.Id}
+		cs.add(phaseDeletePlan, ActionDelete, "plan", name+"/"+o.Plan.Name, func(ctx context.Context, cl v3client.Client) error {
+			return cl.DeletePlan(ctx, ident)
+		})
+	}
+}
+
+func (cs *ChangeSet) diffPlan(l *PlanTree, d *PlanTree, packName string, packageId func() masherytypes.PackageIdentifier) {
+	name := packName + "/" + d.Plan.Name
+	planId := func() masherytypes.PackagePlanIdentifier {
+		return masherytypes.PackagePlanIdentifier{PackageIdentifier: packageId(), PlanId: d.Plan.Id}
+	}
+
+	if l == nil {
+		cs.add(phaseUpsertPackage, ActionCreate, "plan", name, func(ctx context.Context, cl v3client.Client) error {
+			upsert := planContent(d.Plan)
+			upsert.Id = ""
+			created, err := cl.CreatePlan(ctx, packageId(), upsert)
+			if err == nil {
+				cs.created(&d.Plan.Id, created.Id)
+			}
+			return err
+		})
+		l = &PlanTree{}
+	} else {
+		d.Plan.Id = l.Plan.Id
+		if changes := contentChanges(planContent(l.Plan), planContent(d.Plan)); !changes.Empty() {
+			cs.add(phaseUpsertPackage, ActionUpdate, "plan", name, func(ctx context.Context, cl v3client.Client) error {
+				upsert := planContent(d.Plan)
+				upsert.ParentPackageId = packageId()
+				_, err := cl.UpdatePlan(ctx, upsert)
+				return err
+			}).Changes = changes
+		}
+	}
+
+	matched, orphans := pair(l.Services, d.Services, func(pst *PlanServiceTree) masherytypes.AddressableV3Object {
+		return pst.Service
+	})
+	for i := range d.Services {
+		cs.diffPlanService(matched[i], &d.Services[i], name, planId)
+	}
+	for _, o := range orphans {
+		oid := o.Service.Id
+		cs.add(phaseDeletePlanService, ActionDelete, "plan service", name+"/"+o.Service.Name, func(ctx context.Context, cl v3client.Client) error {
+			return cl.DeletePlanService(ctx, masherytypes.PackagePlanServiceIdentifier{
+				PackagePlanIdentifier: planId(),
+				ServiceIdentifier:     masherytypes.ServiceIdentifier{ServiceId: oid},
+			})
+		})
+	}
+}
+
+func (cs *ChangeSet) diffPlanService(l *PlanServiceTree, d *PlanServiceTree, planName string, planId func() masherytypes.PackagePlanIdentifier) {
+	name := planName + "/" + d.Service.Name
+	planServiceId := func() masherytypes.PackagePlanServiceIdentifier {
+		return masherytypes.PackagePlanServiceIdentifier{
+			PackagePlanIdentifier: planId(),
+			ServiceIdentifier:     masherytypes.ServiceIdentifier{ServiceId: cs.resolve(d.Service.Id)},
+		}
+	}
+
+	if l == nil {
+		cs.add(phaseCreatePlanService, ActionCreate, "plan service", name, func(ctx context.Context, cl v3client.Client) error {
+			d.Service.Id = cs.resolve(d.Service.Id)
+			_, err := cl.CreatePlanService(ctx, planServiceId())
+			return err
+		})
+		l = &PlanServiceTree{}
+	}
+
+	matched, orphans := pair(l.Endpoints, d.Endpoints, func(pet *PlanEndpointTree) masherytypes.AddressableV3Object {
+		return pet.Endpoint
+	})
+	for i := range d.Endpoints {
+		cs.diffPlanEndpoint(matched[i], &d.Endpoints[i], name, planServiceId)
+	}
+	for _, o := range orphans {
+		oid := o.Endpoint.Id
+		cs.add(phaseDeletePlanEndpoint, ActionDelete, "plan endpoint", name+"/"+o.Endpoint.Name, func(ctx context.Context, cl v3client.Client) error {
+			psi := planServiceId()
+			return cl.DeletePlanEndpoint(ctx, masherytypes.PackagePlanServiceEndpointIdentifier{
+				PackagePlanIdentifier: psi.PackagePlanIdentifier,
+				ServiceEndpointIdentifier: masherytypes.ServiceEndpointIdentifier{
+					ServiceIdentifier: psi.ServiceIdentifier,
+					EndpointId:        oid,
+				},
+			})
+		})
+	}
+}
+
+func (cs *ChangeSet) diffPlanEndpoint(l *PlanEndpointTree, d *PlanEndpointTree, svcName string, planServiceId func() masherytypes.PackagePlanServiceIdentifier) {
+	name := svcName + "/" + d.Endpoint.Name
+	planEndpointId := func() masherytypes.PackagePlanServiceEndpointIdentifier {
+		psi := planServiceId()
+		return masherytypes.PackagePlanServiceEndpointIdentifier{
+			PackagePlanIdentifier: psi.PackagePlanIdentifier,
+			ServiceEndpointIdentifier: masherytypes.ServiceEndpointIdentifier{
+				ServiceIdentifier: psi.ServiceIdentifier,
+				EndpointId:        cs.resolve(d.Endpoint.Id),
+			},
+		}
+	}
+
+	if l == nil {
+		cs.add(phaseCreatePlanEndpoint, ActionCreate, "plan endpoint", name, func(ctx context.Context, cl v3client.Client) error {
+			d.Endpoint.Id = cs.resolve(d.Endpoint.Id)
+			_, err := cl.CreatePlanEndpoint(ctx, planEndpointId())
+			return err
+		})
+		l = &PlanEndpointTree{}
+	}
+
+	planMethodId := func(methodId string) masherytypes.PackagePlanServiceEndpointMethodIdentifier {
+		pei := planEndpointId()
+		return masherytypes.PackagePlanServiceEndpointMethodIdentifier{
+			PackagePlanIdentifier: pei.PackagePlanIdentifier,
+			ServiceEndpointMethodIdentifier: masherytypes.ServiceEndpointMethodIdentifier{
+				ServiceEndpointIdentifier: pei.ServiceEndpointIdentifier,
+				MethodId:                  methodId,
+			},
+		}
+	}
+
+	matched, orphans := pair(l.Methods, d.Methods, func(pm *PlanMethod) masherytypes.AddressableV3Object {
+		return masherytypes.AddressableV3Object{Id: pm.Id, Name: pm.Name}
+	})
+	for i := range d.Methods {
+		dm := &d.Methods[i]
+		lm := matched[i]
+		methName := name + "/" + dm.Name
+
+		if lm == nil {
+			cs.add(phaseCreatePlanMethod, ActionCreate, "plan method", methName, func(ctx context.Context, cl v3client.Client) error {
+				dm.Id = cs.resolve(dm.Id)
+				_, err := cl.CreatePackagePlanMethod(ctx, planMethodId(dm.Id))
+				return err
+			})
+			lm = &PlanMethod{}
+		}
+
+		sameFilter := (lm.ResponseFilter == nil && dm.ResponseFilter == nil) ||
+			(lm.ResponseFilter != nil && dm.ResponseFilter != nil && lm.ResponseFilter.Id == cs.resolve(dm.ResponseFilter.Id))
+		if sameFilter {
+			continue
+		}
+
+		if lm.ResponseFilter != nil {
+			liveMethodId := lm.Id
+			cs.add(phaseDeletePlanMethodFilter, ActionDelete, "plan method filter", methName+"/"+lm.ResponseFilter.Name, func(ctx context.Context, cl v3client.Client) error {
+				return cl.DeletePackagePlanMethodFilter(ctx, planMethodId(liveMethodId))
+			})
+		}
+		if dm.ResponseFilter != nil {
+			cs.add(phaseCreatePlanMethodFilter, ActionCreate, "plan method filter", methName+"/"+dm.ResponseFilter.Name, func(ctx context.Context, cl v3client.Client) error {
+				dm.ResponseFilter.Id = cs.resolve(dm.ResponseFilter.Id)
+				mi := planMethodId(cs.resolve(dm.Id))
+				_, err := cl.CreatePackagePlanMethodFilter(ctx, masherytypes.PackagePlanServiceEndpointMethodFilterIdentifier{
+					PackagePlanIdentifier: mi.PackagePlanIdentifier,
+					ServiceEndpointMethodFilterIdentifier: masherytypes.ServiceEndpointMethodFilterIdentifier{
+						ServiceEndpointMethodIdentifier: mi.ServiceEndpointMethodIdentifier,
+						FilterId:                        dm.ResponseFilter.Id,
+					},
+				})
+				return err
+			})
+		}
+	}
+	for _, o := range orphans {
+		oid := o.Id
+		cs.add(phaseDeletePlanMethod, ActionDelete, "plan method", name+"/"+o.Name, func(ctx context.Context, cl v3client.Client) error {
+			return cl.DeletePackagePlanMethod(ctx, planMethodId(oid))
+		})
+	}
+}
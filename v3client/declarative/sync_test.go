@@ -0,0 +1,166 @@
+package declarative
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportedTreeIsInSync(t *testing.T) {
+	srv, cl := newFakeClient()
+	defer srv.Close()
+
+	createSampleArea(t, cl)
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	_, err := ExportArea(ctx, cl, dir, FormatYAML)
+	assert.Nil(t, err)
+
+	cs, err := ApplyTree(ctx, cl, dir, SyncOptions{DryRun: true})
+	assert.Nil(t, err)
+	assert.True(t, cs.Empty(), "unexpected changes: %v", cs.Operations)
+}
+
+func TestReadTreeRoundTrip(t *testing.T) {
+	srv, cl := newFakeClient()
+	defer srv.Close()
+
+	createSampleArea(t, cl)
+	ctx := context.Background()
+
+	live, err := ReadArea(ctx, cl)
+	assert.Nil(t, err)
+
+	for _, format := range []Format{FormatYAML, FormatJSON} {
+		dir := t.TempDir()
+		_, err = WriteTree(live, dir, format)
+		assert.Nil(t, err)
+
+		read, err := ReadTree(dir)
+		assert.Nil(t, err)
+		assert.True(t, Diff(live, read).Empty())
+	}
+}
+
+func TestSyncAreaAppliesChangesInDependencyOrder(t *testing.T) {
+	srv, cl := newFakeClient()
+	defer srv.Close()
+
+	sample := createSampleArea(t, cl)
+	ctx := context.Background()
+
+	desired, err := ReadArea(ctx, cl)
+	assert.Nil(t, err)
+
+	// Modify the endpoint, add a new service and include its endpoint into the plan; remove the method filter.
+	desired.Services[0].Endpoints[0].Endpoint.RequestPathAlias = "/changed"
+	desired.Services[0].Endpoints[0].Methods[0].Filters = nil
+	desired.Services = append(desired.Services, ServiceTree{
+		Service: masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Id: "new-svc", Name: "new"}},
+		Endpoints: []EndpointTree{{
+			Endpoint: masherytypes.Endpoint{AddressableV3Object: masherytypes.AddressableV3Object{Id: "new-endp", Name: "new-endp"}},
+		}},
+	})
+	plan := &desired.Packages[0].Plans[0]
+	plan.Services = append(plan.Services, PlanServiceTree{
+		Service:   masherytypes.AddressableV3Object{Id: "new-svc", Name: "new"},
+		Endpoints: []PlanEndpointTree{{Endpoint: masherytypes.AddressableV3Object{Id: "new-endp", Name: "new-endp"}}},
+	})
+
+	cs, err := SyncArea(ctx, cl, desired, SyncOptions{DryRun: true})
+	assert.Nil(t, err)
+
+	buf := bytes.Buffer{}
+	cs.Print(&buf)
+	assert.Equal(t, `~ update endpoint svc/endp
+    requestPathAlias: "/path" -> "/changed"
++ create service new
++ create endpoint new/new-endp
+- delete method filter svc/endp/meth/filter
++ create plan service pack/plan/new
++ create plan endpoint pack/plan/new/new-endp
+`, buf.String())
+
+	// Dry run must not modify the area
+	filters, _ := cl.ListEndpointMethodFiltersWithFullInfo(ctx, sample.meth.Identifier())
+	assert.Equal(t, 1, len(filters))
+
+	cs, err = SyncArea(ctx, cl, desired, SyncOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 6, len(cs.Operations))
+
+	filters, _ = cl.ListEndpointMethodFiltersWithFullInfo(ctx, sample.meth.Identifier())
+	assert.Equal(t, 0, len(filters))
+
+	cnt, err := cl.CountPlanService(ctx, sample.plan.Identifier())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), cnt)
+
+	// Once applied, the area is in the desired state.
+	cs, err = SyncArea(ctx, cl, desired, SyncOptions{DryRun: true})
+	assert.Nil(t, err)
+	assert.True(t, cs.Empty(), "unexpected changes: %v", cs.Operations)
+}
+
+func TestSyncAreaDeletesBindingsBeforeObjects(t *testing.T) {
+	srv, cl := newFakeClient()
+	defer srv.Close()
+
+	createSampleArea(t, cl)
+	ctx := context.Background()
+
+	cs, err := SyncArea(ctx, cl, &Area{}, SyncOptions{AllowDeleteAll: true})
+	assert.Nil(t, err)
+
+	buf := bytes.Buffer{}
+	cs.Print(&buf)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, []string{"- delete package pack", "- delete service svc"}, lines)
+
+	svcs, _ := cl.ListServices(ctx)
+	assert.Equal(t, 0, len(svcs))
+}
+
+func TestReadTreeRequiresDirectory(t *testing.T) {
+	_, err := ReadTree(filepath.Join(t.TempDir(), "typo"))
+	assert.NotNil(t, err)
+
+	file := filepath.Join(t.TempDir(), "file.yaml")
+	assert.Nil(t, os.WriteFile(file, []byte("{}"), 0600))
+	_, err = ReadTree(file)
+	assert.NotNil(t, err)
+}
+
+func TestSyncAreaRefusesToDeleteAll(t *testing.T) {
+	srv, cl := newFakeClient()
+	defer srv.Close()
+
+	createSampleArea(t, cl)
+	ctx := context.Background()
+
+	// The empty desired state is refused, also in dry-run mode.
+	_, err := SyncArea(ctx, cl, &Area{}, SyncOptions{DryRun: true})
+	assert.True(t, errors.Is(err, ErrDeleteAll))
+
+	// An empty, but existing, directory is refused as well.
+	_, err = ApplyTree(ctx, cl, t.TempDir(), SyncOptions{})
+	assert.True(t, errors.Is(err, ErrDeleteAll))
+
+	// The desired state sharing no objects with the live area would delete all live objects.
+	desired := &Area{Services: []ServiceTree{{
+		Service: masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "other"}},
+	}}}
+	cs, err := SyncArea(ctx, cl, desired, SyncOptions{})
+	assert.True(t, errors.Is(err, ErrDeleteAll))
+	assert.NotNil(t, cs)
+
+	svcs, _ := cl.ListServices(ctx)
+	assert.Equal(t, 1, len(svcs))
+}
@@ -0,0 +1,282 @@
+package declarative
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/errwrap"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+)
+
+var readExtensions = []string{".yaml", ".yml", ".json"}
+
+// ReadTree reads the area definition from the directory tree in the layout produced by WriteTree. Both YAML
+// and JSON files are accepted. The directory must exist.
+func ReadTree(dir string) (*Area, error) {
+	if stat, err := os.Stat(dir); err != nil {
+		return nil, &errwrap.WrappedError{Context: fmt.Sprintf("reading tree %s", dir), Cause: err}
+	} else if !stat.IsDir() {
+		return nil, errors.New(fmt.Sprintf("%s is not a directory", dir))
+	}
+
+	rv := &Area{}
+
+	if err := forEachDir(filepath.Join(dir, servicesDir), func(svcDir string) error {
+		if st, err := readServiceTree(svcDir); err != nil {
+			return err
+		} else {
+			rv.Services = append(rv.Services, st)
+			return nil
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := forEachDir(filepath.Join(dir, packagesDir), func(packDir string) error {
+		if pt, err := readPackageTree(packDir); err != nil {
+			return err
+		} else {
+			rv.Packages = append(rv.Packages, pt)
+			return nil
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	rv.Sort()
+	return rv, nil
+}
+
+func readServiceTree(dir string) (ServiceTree, error) {
+	rv := ServiceTree{}
+
+	if err := mustReadObject(filepath.Join(dir, "service"), &rv.Service); err != nil {
+		return rv, err
+	}
+	if err := readOptionalObject(filepath.Join(dir, "cache"), &rv.Cache); err != nil {
+		return rv, err
+	}
+	if err := readOptionalObject(filepath.Join(dir, "oauth"), &rv.OAuth); err != nil {
+		return rv, err
+	}
+	if err := readOptionalObject(filepath.Join(dir, "roles"), &rv.Roles); err != nil {
+		return rv, err
+	}
+
+	if err := forEachFile(filepath.Join(dir, "errorSets"), func(file string) error {
+		return appendObject(file, &rv.ErrorSets)
+	}); err != nil {
+		return rv, err
+	}
+
+	err := forEachDir(filepath.Join(dir, "endpoints"), func(endpDir string) error {
+		et := EndpointTree{}
+		if err := mustReadObject(filepath.Join(endpDir, "endpoint"), &et.Endpoint); err != nil {
+			return err
+		}
+
+		if err := forEachDir(filepath.Join(endpDir, "methods"), func(methDir string) error {
+			mt := MethodTree{}
+			if err := mustReadObject(filepath.Join(methDir, "method"), &mt.Method); err != nil {
+				return err
+			}
+			if err := forEachFile(filepath.Join(methDir, "filters"), func(file string) error {
+				return appendObject(file, &mt.Filters)
+			}); err != nil {
+				return err
+			}
+
+			et.Methods = append(et.Methods, mt)
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		rv.Endpoints = append(rv.Endpoints, et)
+		return nil
+	})
+
+	return rv, err
+}
+
+func readPackageTree(dir string) (PackageTree, error) {
+	rv := PackageTree{}
+
+	if err := mustReadObject(filepath.Join(dir, "package"), &rv.Package); err != nil {
+		return rv, err
+	}
+
+	err := forEachDir(filepath.Join(dir, "plans"), func(planDir string) error {
+		pt := PlanTree{}
+		if err := mustReadObject(filepath.Join(planDir, "plan"), &pt.Plan); err != nil {
+			return err
+		}
+
+		if err := forEachDir(filepath.Join(planDir, "services"), func(svcDir string) error {
+			pst := PlanServiceTree{}
+			if err := mustReadObject(filepath.Join(svcDir, "service"), &pst.Service); err != nil {
+				return err
+			}
+
+			if err := forEachDir(filepath.Join(svcDir, "endpoints"), func(endpDir string) error {
+				pet := PlanEndpointTree{}
+				if err := mustReadObject(filepath.Join(endpDir, "endpoint"), &pet.Endpoint); err != nil {
+					return err
+				}
+				if err := forEachFile(filepath.Join(endpDir, "methods"), func(file string) error {
+					return appendObject(file, &pet.Methods)
+				}); err != nil {
+					return err
+				}
+
+				pst.Endpoints = append(pst.Endpoints, pet)
+				return nil
+			}); err != nil {
+				return err
+			}
+
+			pt.Services = append(pt.Services, pst)
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		rv.Plans = append(rv.Plans, pt)
+		return nil
+	})
+
+	return rv, err
+}
+
+// forEachDir invokes the function for each sub-directory. A missing directory is treated as empty.
+func forEachDir(dir string, f func(string) error) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			if err = f(filepath.Join(dir, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// forEachFile invokes the function for each object file in the directory. A missing directory is treated as empty.
+func forEachFile(dir string, f func(string) error) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() && isObjectFile(e.Name()) {
+			if err = f(filepath.Join(dir, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isObjectFile(name string) bool {
+	ext := filepath.Ext(name)
+	for _, v := range readExtensions {
+		if ext == v {
+			return true
+		}
+	}
+	return false
+}
+
+func appendObject[T any](file string, dest *[]T) error {
+	var obj T
+	if err := decodeFile(file, &obj); err != nil {
+		return err
+	}
+	*dest = append(*dest, obj)
+	return nil
+}
+
+// findObjectFile locates the file for the object, trying supported extensions
+func findObjectFile(base string) (string, bool) {
+	for _, ext := range readExtensions {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext, true
+		}
+	}
+	return "", false
+}
+
+func mustReadObject(base string, dest interface{}) error {
+	if file, ok := findObjectFile(base); !ok {
+		return errors.New(fmt.Sprintf("required file %s is missing", base))
+	} else {
+		return decodeFile(file, dest)
+	}
+}
+
+func readOptionalObject(base string, dest interface{}) error {
+	if file, ok := findObjectFile(base); ok {
+		return decodeFile(file, dest)
+	}
+	return nil
+}
+
+func decodeFile(file string, dest interface{}) error {
+	dat, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	if err = decode(dat, filepath.Ext(file) == ".json", dest); err != nil {
+		return &errwrap.WrappedError{Context: fmt.Sprintf("decoding %s", file), Cause: err}
+	}
+	return nil
+}
+
+// decode reads the file content into the object. YAML content is converted into JSON first, so that the
+// JSON property names of masherytypes structures are honored.
+func decode(dat []byte, isJSON bool, dest interface{}) error {
+	if !isJSON {
+		var generic interface{}
+		if err := yaml.Unmarshal(dat, &generic); err != nil {
+			return err
+		}
+
+		var err error
+		if dat, err = json.Marshal(yamlToJSON(generic)); err != nil {
+			return err
+		}
+	}
+
+	return json.Unmarshal(dat, dest)
+}
+
+// yamlToJSON converts the maps with interface keys produced by the YAML parser into JSON-compatible maps.
+func yamlToJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		rv := map[string]interface{}{}
+		for k, child := range t {
+			rv[fmt.Sprintf("%v", k)] = yamlToJSON(child)
+		}
+		return rv
+	case []interface{}:
+		for i, child := range t {
+			t[i] = yamlToJSON(child)
+		}
+		return t
+	default:
+		return v
+	}
+}
@@ -0,0 +1,78 @@
+package fakeserver
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Fault a failure the server should return instead of processing the request.
+type Fault struct {
+	// Method matched case-insensitively; empty matches any method
+	Method string
+	// Path prefix of the request path (without BasePath) the fault applies to; empty matches any path
+	Path string
+
+	StatusCode int
+	// ErrorCode is returned in the X-Mashery-Error-Code header, if set
+	ErrorCode string
+	Header    http.Header
+	Body      string
+
+	// Times the fault will be returned; zero or negative value means unlimited.
+	Times int
+}
+
+// InjectFault registers a fault. Faults are matched in the order of their registration.
+func (s *Server) InjectFault(f Fault) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes all registered faults.
+func (s *Server) ClearFaults() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.faults = nil
+}
+
+func (s *Server) takeFault(method, path string) *Fault {
+	for i, f := range s.faults {
+		if len(f.Method) > 0 && !strings.EqualFold(f.Method, method) {
+			continue
+		}
+		if !strings.HasPrefix(path, f.Path) {
+			continue
+		}
+
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+
+	return nil
+}
+
+func (f *Fault) write(w http.ResponseWriter) {
+	for k, vals := range f.Header {
+		for _, v := range vals {
+			w.Header().Add(k, v)
+		}
+	}
+	if len(f.ErrorCode) > 0 {
+		w.Header().Set("X-Mashery-Error-Code", f.ErrorCode)
+	}
+
+	status := f.StatusCode
+	if status == 0 {
+		status = 500
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(f.Body))
+}
@@ -0,0 +1,144 @@
+package fakeserver
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// fieldSet requested projection of the object, as specified by the `fields` query parameter. Dotted notation
+// (e.g. `services.endpoints`) selects the properties of the embedded objects.
+type fieldSet map[string]fieldSet
+
+func parseFields(qs url.Values) fieldSet {
+	str := qs.Get("fields")
+	if len(str) == 0 {
+		return nil
+	}
+
+	rv := fieldSet{}
+	for _, f := range strings.Split(str, ",") {
+		cur := rv
+		for _, p := range strings.Split(strings.TrimSpace(f), ".") {
+			if len(p) == 0 {
+				continue
+			}
+			next, ok := cur[p]
+			if !ok {
+				next = fieldSet{}
+				cur[p] = next
+			}
+			cur = next
+		}
+	}
+
+	return rv
+}
+
+// render produces the JSON representation of the node. Where no fields are requested, only the properties of
+// the object itself are returned; embedded objects are rendered with their properties and singletons.
+func render(n *node, fields fieldSet, embedded bool) interface{} {
+	if n.typ.listValued {
+		if v, ok := n.attrs["value"]; ok {
+			return v
+		}
+		return []interface{}{}
+	}
+
+	rv := map[string]interface{}{}
+	values := n.values()
+
+	if len(fields) == 0 {
+		for k, v := range values {
+			rv[k] = v
+		}
+		if embedded {
+			for k, s := range n.singletons {
+				if s.live() {
+					rv[k] = render(s, nil, true)
+				}
+			}
+		}
+		return rv
+	}
+
+	for k, sub := range fields {
+		if v, ok := values[k]; ok {
+			rv[k] = v
+		} else if _, ok := n.typ.collections[k]; ok {
+			var arr []interface{}
+			for _, child := range n.collection(k).list() {
+				arr = append(arr, render(child, sub, true))
+			}
+			if arr == nil {
+				arr = []interface{}{}
+			}
+			rv[k] = arr
+		} else if s, ok := n.singletons[k]; ok && s.live() {
+			rv[k] = render(s, sub, true)
+		}
+	}
+
+	return rv
+}
+
+// matchesFilter checks the node against the `filter` query parameter, which is a comma-separated list of
+// `property:value` pairs.
+func matchesFilter(n *node, filter string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+
+	values := n.values()
+	for _, atom := range strings.Split(filter, ",") {
+		k, v, _ := strings.Cut(atom, ":")
+		if str, ok := values[k]; !ok || stringOf(str) != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func stringOf(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case bool:
+		return strconv.FormatBool(t)
+	case int:
+		return strconv.Itoa(t)
+	case interface{ String() string }:
+		return t.String()
+	default:
+		return ""
+	}
+}
+
+// page selects the requested page of the list. Depending on the collection, the client passes either the
+// index of the page or the index of the first item as the offset.
+func page(items []*node, qs url.Values, pageSize int, perItem bool) []*node {
+	limit := pageSize
+	if v, err := strconv.Atoi(qs.Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+
+	start := 0
+	if v, err := strconv.Atoi(qs.Get("offset")); err == nil && v > 0 {
+		if perItem {
+			start = v
+		} else {
+			start = v * limit
+		}
+	}
+
+	if start >= len(items) {
+		return nil
+	}
+
+	end := start + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
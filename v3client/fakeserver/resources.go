@@ -0,0 +1,278 @@
+package fakeserver
+
+import (
+	"sort"
+)
+
+// resourceType describes how the fake server stores and exposes a particular kind of Mashery V3 object.
+type resourceType struct {
+	name        string
+	collections map[string]*resourceType
+	singletons  map[string]*resourceType
+
+	// required properties that must be non-empty on creation
+	required []string
+	// perItemPagination is set where the client uses item-based offsets for this collection
+	perItemPagination bool
+	readOnly          bool
+	// container singletons hold no data of their own and are created implicitly on write
+	container bool
+	// shared names the root collection objects of this type are also registered in
+	shared string
+	// refCollection is set for the association types (plan services, endpoints, methods and filters). The
+	// body of the POST request references an existing object in this collection.
+	refCollection string
+	// listValued singletons store a JSON array under the property with the singleton's name
+	listValued bool
+	// putCreates allows PUT to an absent object id to create it (e.g. error set messages)
+	putCreates bool
+	// revisioned objects increment revisionNumber on every update
+	revisioned bool
+	// secrets are generated for the named properties if the caller didn't supply them
+	secrets map[string]int
+}
+
+var rootType *resourceType
+
+func init() {
+	filter := &resourceType{name: "response filter", required: []string{"name"}}
+	method := &resourceType{
+		name:        "method",
+		required:    []string{"name"},
+		collections: map[string]*resourceType{"responseFilters": filter},
+	}
+	endpoint := &resourceType{
+		name:        "endpoint",
+		required:    []string{"name"},
+		collections: map[string]*resourceType{"methods": method},
+	}
+	errorMessage := &resourceType{name: "error message", putCreates: true}
+	errorSet := &resourceType{
+		name:        "error set",
+		required:    []string{"name"},
+		collections: map[string]*resourceType{"errorMessages": errorMessage},
+	}
+	securityProfile := &resourceType{
+		name:       "security profile",
+		container:  true,
+		singletons: map[string]*resourceType{"oauth": {name: "oauth security profile"}},
+	}
+	service := &resourceType{
+		name:              "service",
+		required:          []string{"name"},
+		perItemPagination: true,
+		revisioned:        true,
+		collections: map[string]*resourceType{
+			"endpoints": endpoint,
+			"errorSets": errorSet,
+		},
+		singletons: map[string]*resourceType{
+			"cache":           {name: "service cache"},
+			"securityProfile": securityProfile,
+			"roles":           {name: "service roles", listValued: true},
+		},
+	}
+
+	planFilter := &resourceType{name: "plan method filter", refCollection: "responseFilters"}
+	planMethod := &resourceType{
+		name:              "plan method",
+		refCollection:     "methods",
+		perItemPagination: true,
+		singletons:        map[string]*resourceType{"responseFilter": planFilter},
+	}
+	planEndpoint := &resourceType{
+		name:              "plan endpoint",
+		refCollection:     "endpoints",
+		perItemPagination: true,
+		collections:       map[string]*resourceType{"methods": planMethod},
+	}
+	planService := &resourceType{
+		name:          "plan service",
+		refCollection: "services",
+		collections:   map[string]*resourceType{"endpoints": planEndpoint},
+	}
+	plan := &resourceType{
+		name:        "plan",
+		required:    []string{"name"},
+		collections: map[string]*resourceType{"services": planService},
+	}
+	pack := &resourceType{
+		name:              "package",
+		required:          []string{"name"},
+		perItemPagination: true,
+		collections:       map[string]*resourceType{"plans": plan},
+	}
+
+	packageKey := &resourceType{
+		name:    "package key",
+		shared:  "packageKeys",
+		secrets: map[string]int{"apikey": 24, "secret": 10},
+	}
+	application := &resourceType{
+		name:        "application",
+		required:    []string{"name"},
+		shared:      "applications",
+		collections: map[string]*resourceType{"packageKeys": packageKey},
+	}
+	member := &resourceType{
+		name:        "member",
+		required:    []string{"username", "email"},
+		collections: map[string]*resourceType{"applications": application},
+	}
+
+	rootType = &resourceType{
+		name: "area",
+		collections: map[string]*resourceType{
+			"services":          service,
+			"packages":          pack,
+			"members":           member,
+			"applications":      application,
+			"packageKeys":       packageKey,
+			"roles":             {name: "role", readOnly: true},
+			"organizations":     {name: "organization", readOnly: true},
+			"emailTemplateSets": {name: "email template set", readOnly: true},
+		},
+	}
+}
+
+// node a stored object. Association nodes point to the target they reference and take the properties from
+// the target object.
+type node struct {
+	typ         *resourceType
+	attrs       map[string]interface{}
+	collections map[string]*collection
+	singletons  map[string]*node
+
+	target  *node
+	owners  []*collection
+	deleted bool
+}
+
+func newNode(typ *resourceType) *node {
+	return &node{
+		typ:         typ,
+		attrs:       map[string]interface{}{},
+		collections: map[string]*collection{},
+		singletons:  map[string]*node{},
+	}
+}
+
+func (n *node) id() string {
+	if v, ok := n.values()["id"].(string); ok {
+		return v
+	}
+	return ""
+}
+
+func (n *node) values() map[string]interface{} {
+	if n.target != nil {
+		return n.target.attrs
+	}
+	return n.attrs
+}
+
+// live checks that neither this node nor the node it references were deleted.
+func (n *node) live() bool {
+	return !n.deleted && (n.target == nil || n.target.live())
+}
+
+func (n *node) collection(name string) *collection {
+	rv, ok := n.collections[name]
+	if !ok {
+		rv = &collection{items: map[string]*node{}}
+		n.collections[name] = rv
+	}
+	return rv
+}
+
+// markDeleted marks this node and all of its descendants as deleted, which also invalidates any association
+// pointing to these.
+func (n *node) markDeleted() {
+	n.deleted = true
+	for _, o := range n.owners {
+		o.remove(n.id())
+	}
+	for _, c := range n.collections {
+		for _, v := range c.items {
+			v.markDeleted()
+		}
+	}
+	for _, s := range n.singletons {
+		s.markDeleted()
+	}
+}
+
+type collection struct {
+	order []string
+	items map[string]*node
+}
+
+func (c *collection) add(n *node) {
+	id := n.id()
+	if _, exists := c.items[id]; !exists {
+		c.order = append(c.order, id)
+	}
+	c.items[id] = n
+	n.owners = append(n.owners, c)
+}
+
+func (c *collection) get(id string) *node {
+	if rv, ok := c.items[id]; ok && rv.live() {
+		return rv
+	}
+	return nil
+}
+
+func (c *collection) remove(id string) {
+	if _, ok := c.items[id]; !ok {
+		return
+	}
+	delete(c.items, id)
+	for i, v := range c.order {
+		if v == id {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// list returns live nodes in the order of their creation.
+func (c *collection) list() []*node {
+	var rv []*node
+	for _, id := range c.order {
+		if n := c.items[id]; n != nil && n.live() {
+			rv = append(rv, n)
+		}
+	}
+	return rv
+}
+
+// uniqueAddresses collect unique domain addresses from the named property of all endpoints in the area.
+func uniqueAddresses(root *node, prop string) []map[string]interface{} {
+	seen := map[string]bool{}
+	for _, svc := range root.collection("services").list() {
+		for _, endp := range svc.collection("endpoints").list() {
+			if arr, ok := endp.attrs[prop].([]interface{}); ok {
+				for _, v := range arr {
+					if m, ok := v.(map[string]interface{}); ok {
+						if addr, ok := m["address"].(string); ok && len(addr) > 0 {
+							seen[addr] = true
+						}
+					}
+				}
+			}
+		}
+	}
+
+	var addresses []string
+	for k := range seen {
+		addresses = append(addresses, k)
+	}
+	sort.Strings(addresses)
+
+	rv := make([]map[string]interface{}, len(addresses))
+	for i, v := range addresses {
+		rv[i] = map[string]interface{}{"address": v}
+	}
+	return rv
+}
@@ -0,0 +1,627 @@
+// Package fakeserver provides an in-process emulation of the Mashery V3 REST API that can be used to exercise
+// the V3 client end-to-end in tests without hand-wiring the expectations for each individual request.
+package fakeserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BasePath path under which the emulated API is served, mirroring the production Mashery endpoint.
+const BasePath = "/v3/rest"
+
+const masheryTimeFormat = "2006-01-02T15:04:05.000Z0700"
+
+// Call a request received by the server
+type Call struct {
+	Method string
+	Path   string
+	Query  url.Values
+}
+
+// Server in-memory emulation of Mashery V3 API. The server maintains the state of the objects created
+// by the client, supports `fields` projection, `filter` expressions, `X-Total-Count` pagination, and
+// responds with the Mashery-style error bodies.
+type Server struct {
+	httpServer *httptest.Server
+
+	mutex       sync.Mutex
+	root        *node
+	seq         int64
+	lastStamp   time.Time
+	pageSize    int
+	accessToken string
+	faults      []*Fault
+	calls       []Call
+}
+
+// New creates and starts a new fake server. The caller must Close the server when done.
+func New() *Server {
+	rv := NewUnstarted()
+	rv.httpServer = httptest.NewServer(rv)
+	return rv
+}
+
+// NewUnstarted creates the server without starting the listener. Use this where the server should
+// be mounted as http.Handler.
+func NewUnstarted() *Server {
+	return &Server{
+		root:     newNode(rootType),
+		pageSize: 100,
+	}
+}
+
+// Endpoint returns the endpoint that needs to be passed as MashEndpoint to the client.
+func (s *Server) Endpoint() string {
+	if s.httpServer == nil {
+		return BasePath
+	}
+	return s.httpServer.URL + BasePath
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	if s.httpServer != nil {
+		s.httpServer.Close()
+	}
+}
+
+// SetPageSize sets the number of objects returned in a single page where the client does not specify the limit.
+func (s *Server) SetPageSize(sz int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.pageSize = sz
+}
+
+// RequireAccessToken requires all calls to supply the specified bearer token. Empty token disables the check.
+func (s *Server) RequireAccessToken(tkn string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.accessToken = tkn
+}
+
+// Calls returns the requests received by the server so far.
+func (s *Server) Calls() []Call {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	rv := make([]Call, len(s.calls))
+	copy(rv, s.calls)
+	return rv
+}
+
+// ResetCalls clears the log of received calls.
+func (s *Server) ResetCalls() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.calls = nil
+}
+
+// AddRole adds a role to the area. Roles are read-only in V3 API.
+func (s *Server) AddRole(r masherytypes.Role) string {
+	return s.seed("roles", r)
+}
+
+// AddOrganization adds an organization to the area.
+func (s *Server) AddOrganization(o masherytypes.Organization) string {
+	return s.seed("organizations", o)
+}
+
+// AddEmailTemplateSet adds an email template set to the area.
+func (s *Server) AddEmailTemplateSet(set masherytypes.EmailTemplateSet) string {
+	return s.seed("emailTemplateSets", set)
+}
+
+func (s *Server) seed(coll string, obj interface{}) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	dat, _ := json.Marshal(obj)
+	attrs := map[string]interface{}{}
+	_ = json.Unmarshal(dat, &attrs)
+
+	n := newNode(rootType.collections[coll])
+	n.attrs = attrs
+	if len(n.id()) == 0 {
+		n.attrs["id"] = s.nextId()
+	}
+	s.root.collection(coll).add(n)
+
+	return n.id()
+}
+
+func (s *Server) nextId() string {
+	s.seq++
+	return fmt.Sprintf("%08x-0000-4000-8000-%012x", s.seq, s.seq)
+}
+
+// stamp returns the time stamp for created and updated fields. Time stamps are strictly increasing, so that
+// each modification of the object is observable.
+func (s *Server) stamp() string {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	if !now.After(s.lastStamp) {
+		now = s.lastStamp.Add(time.Millisecond)
+	}
+	s.lastStamp = now
+
+	return now.Format(masheryTimeFormat)
+}
+
+// -------------------------------------------------------------------------------------------------------------
+// Request handling
+
+type propertyError struct {
+	Property string `json:"property"`
+	Message  string `json:"message"`
+}
+
+type apiError struct {
+	status  int
+	code    string
+	message string
+	props   []propertyError
+}
+
+func notFound(path string) *apiError {
+	return &apiError{status: 404, code: "ERR_404_NOT_FOUND", message: fmt.Sprintf("resource %s was not found", path)}
+}
+
+func methodNotAllowed(method, path string) *apiError {
+	return &apiError{status: 405, code: "ERR_405_METHOD_NOT_ALLOWED", message: fmt.Sprintf("method %s is not supported on %s", method, path)}
+}
+
+func badRequest(props ...propertyError) *apiError {
+	return &apiError{status: 400, code: "ERR_400_BAD_REQUEST", props: props}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	method := strings.ToUpper(r.Method)
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, BasePath), "/")
+	qs := r.URL.Query()
+
+	s.calls = append(s.calls, Call{Method: method, Path: path, Query: qs})
+
+	if f := s.takeFault(method, path); f != nil {
+		f.write(w)
+		return
+	}
+
+	if len(s.accessToken) > 0 && r.Header.Get("Authorization") != "Bearer "+s.accessToken {
+		w.Header().Set("X-Mashery-Error-Code", "ERR_403_NOT_AUTHORIZED")
+		w.WriteHeader(403)
+		_, _ = w.Write([]byte("<h1>Not Authorized</h1>"))
+		return
+	}
+
+	var body interface{}
+	if r.Body != nil && (method == "POST" || method == "PUT") {
+		dec := json.NewDecoder(r.Body)
+		dec.UseNumber()
+		if err := dec.Decode(&body); err != nil {
+			writeError(w, &apiError{status: 400, code: "ERR_400_BAD_REQUEST", message: fmt.Sprintf("malformed request body: %s", err.Error())})
+			return
+		}
+	}
+
+	rv, total, err := s.handle(method, path, qs, body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if total >= 0 {
+		w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	}
+	if rv == nil {
+		w.WriteHeader(200)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	_ = json.NewEncoder(w).Encode(rv)
+}
+
+func writeError(w http.ResponseWriter, e *apiError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Mashery-Error-Code", e.code)
+	w.WriteHeader(e.status)
+
+	if len(e.props) > 0 {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"errors": e.props})
+	} else {
+		_ = json.NewEncoder(w).Encode(masherytypes.V3GenericErrorResponse{ErrorCode: e.code, ErrorMessage: e.message})
+	}
+}
+
+// location resolved position of the requested resource in the object tree.
+type location struct {
+	owner     *node
+	name      string
+	typ       *resourceType
+	singleton bool
+	id        string
+	hasId     bool
+}
+
+func (s *Server) locate(path string, write bool) (*location, *apiError) {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	cur := s.root
+
+	for i := 0; i < len(segments); {
+		name := segments[i]
+
+		if typ, ok := cur.typ.singletons[name]; ok {
+			if i+1 == len(segments) {
+				return &location{owner: cur, name: name, typ: typ, singleton: true}, nil
+			}
+
+			next := cur.singletons[name]
+			if next == nil || !next.live() {
+				if !(write && typ.container) {
+					return nil, notFound(path)
+				}
+				next = newNode(typ)
+				cur.singletons[name] = next
+			}
+			cur = next
+			i++
+		} else if typ, ok := cur.typ.collections[name]; ok {
+			if i+1 == len(segments) {
+				return &location{owner: cur, name: name, typ: typ}, nil
+			} else if i+2 == len(segments) {
+				return &location{owner: cur, name: name, typ: typ, id: segments[i+1], hasId: true}, nil
+			}
+
+			if cur = cur.collection(name).get(segments[i+1]); cur == nil {
+				return nil, notFound(path)
+			}
+			i += 2
+		} else {
+			return nil, notFound(path)
+		}
+	}
+
+	return nil, notFound(path)
+}
+
+func (s *Server) handle(method, path string, qs url.Values, body interface{}) (interface{}, int, *apiError) {
+	switch path {
+	case "/domains/public/hostnames":
+		return uniqueAddresses(s.root, "publicDomains"), -1, nil
+	case "/domains/system/hostnames":
+		return uniqueAddresses(s.root, "systemDomains"), -1, nil
+	}
+
+	loc, err := s.locate(path, method == "POST" || method == "PUT")
+	if err != nil {
+		return nil, -1, err
+	}
+
+	fields := parseFields(qs)
+
+	if loc.singleton {
+		return s.handleSingleton(method, path, loc, fields, body)
+	} else if loc.hasId {
+		return s.handleItem(method, path, loc, fields, body)
+	}
+
+	coll := loc.owner.collection(loc.name)
+	switch method {
+	case "GET":
+		var matching []*node
+		for _, n := range coll.list() {
+			if matchesFilter(n, qs.Get("filter")) {
+				matching = append(matching, n)
+			}
+		}
+
+		rv := []interface{}{}
+		for _, n := range page(matching, qs, s.pageSize, loc.typ.perItemPagination) {
+			rv = append(rv, render(n, fields, false))
+		}
+		return rv, len(matching), nil
+	case "POST":
+		if n, err := s.create(loc, body); err != nil {
+			return nil, -1, err
+		} else {
+			return render(n, fields, false), -1, nil
+		}
+	default:
+		return nil, -1, methodNotAllowed(method, path)
+	}
+}
+
+func (s *Server) handleItem(method, path string, loc *location, fields fieldSet, body interface{}) (interface{}, int, *apiError) {
+	n := loc.owner.collection(loc.name).get(loc.id)
+
+	switch method {
+	case "GET":
+		if n == nil {
+			return nil, -1, notFound(path)
+		}
+		return render(n, fields, false), -1, nil
+	case "PUT":
+		if loc.typ.readOnly || len(loc.typ.refCollection) > 0 {
+			return nil, -1, methodNotAllowed(method, path)
+		}
+
+		if n == nil {
+			if !loc.typ.putCreates {
+				return nil, -1, notFound(path)
+			}
+
+			created, err := s.build(loc.typ, body, true, loc.id)
+			if err != nil {
+				return nil, -1, err
+			}
+			loc.owner.collection(loc.name).add(created)
+			return render(created, fields, false), -1, nil
+		}
+
+		if err := s.update(n, body); err != nil {
+			return nil, -1, err
+		}
+		return render(n, fields, false), -1, nil
+	case "DELETE":
+		if loc.typ.readOnly {
+			return nil, -1, methodNotAllowed(method, path)
+		} else if n == nil {
+			return nil, -1, notFound(path)
+		}
+
+		n.markDeleted()
+		return nil, -1, nil
+	default:
+		return nil, -1, methodNotAllowed(method, path)
+	}
+}
+
+func (s *Server) handleSingleton(method, path string, loc *location, fields fieldSet, body interface{}) (interface{}, int, *apiError) {
+	n := loc.owner.singletons[loc.name]
+	if n != nil && !n.live() {
+		n = nil
+	}
+
+	switch method {
+	case "GET":
+		if n == nil {
+			return nil, -1, notFound(path)
+		}
+		return render(n, fields, false), -1, nil
+	case "POST":
+		if n != nil {
+			return nil, -1, &apiError{status: 409, code: "ERR_409_CONFLICT", message: fmt.Sprintf("%s already exists", loc.typ.name)}
+		} else if created, err := s.create(loc, body); err != nil {
+			return nil, -1, err
+		} else {
+			return render(created, fields, false), -1, nil
+		}
+	case "PUT":
+		if n == nil {
+			if !loc.typ.listValued {
+				return nil, -1, notFound(path)
+			}
+			n = newNode(loc.typ)
+			loc.owner.singletons[loc.name] = n
+		}
+
+		if loc.typ.listValued {
+			n.attrs["value"] = listValue(loc.name, body)
+		} else if err := s.update(n, body); err != nil {
+			return nil, -1, err
+		}
+		return render(n, fields, false), -1, nil
+	case "DELETE":
+		if n == nil {
+			return nil, -1, notFound(path)
+		}
+		n.markDeleted()
+		delete(loc.owner.singletons, loc.name)
+		return nil, -1, nil
+	default:
+		return nil, -1, methodNotAllowed(method, path)
+	}
+}
+
+// listValue extracts the array from either a bare array or an object wrapping the array under the property name.
+func listValue(name string, body interface{}) interface{} {
+	if m, ok := body.(map[string]interface{}); ok {
+		body = m[name]
+	}
+	if arr, ok := body.([]interface{}); ok {
+		return arr
+	}
+	return []interface{}{}
+}
+
+// create creates the object at the specified location.
+func (s *Server) create(loc *location, body interface{}) (*node, *apiError) {
+	if loc.typ.readOnly {
+		return nil, methodNotAllowed("POST", loc.name)
+	}
+
+	var n *node
+	var err *apiError
+	if len(loc.typ.refCollection) > 0 {
+		n, err = s.reference(loc, body)
+	} else {
+		n, err = s.build(loc.typ, body, !loc.singleton, "")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if loc.singleton {
+		loc.owner.singletons[loc.name] = n
+	} else {
+		loc.owner.collection(loc.name).add(n)
+		if len(loc.typ.shared) > 0 && loc.owner != s.root {
+			s.root.collection(loc.typ.shared).add(n)
+		}
+	}
+
+	return n, nil
+}
+
+// reference creates an association node, e.g. the inclusion of a service endpoint into the package plan.
+func (s *Server) reference(loc *location, body interface{}) (*node, *apiError) {
+	m, _ := body.(map[string]interface{})
+	id, _ := m["id"].(string)
+	if len(id) == 0 {
+		return nil, badRequest(propertyError{Property: "id", Message: "identifier of the referenced object is required"})
+	}
+
+	src := s.root
+	if loc.owner.target != nil {
+		src = loc.owner.target
+	}
+
+	var target *node
+	if c, ok := src.collections[loc.typ.refCollection]; ok {
+		target = c.get(id)
+	}
+	if target == nil {
+		return nil, badRequest(propertyError{Property: "id", Message: fmt.Sprintf("referenced %s %s does not exist", loc.typ.name, id)})
+	}
+
+	if !loc.singleton && loc.owner.collection(loc.name).get(id) != nil {
+		return nil, &apiError{status: 409, code: "ERR_409_CONFLICT", message: fmt.Sprintf("%s %s is already associated", loc.typ.name, id)}
+	}
+
+	rv := newNode(loc.typ)
+	rv.target = target
+	return rv, nil
+}
+
+// build creates a new node of the specified type from the request body, including the nested objects. Keyed
+// nodes are stored in collections and are assigned an identifier.
+func (s *Server) build(typ *resourceType, body interface{}, keyed bool, id string) (*node, *apiError) {
+	n := newNode(typ)
+	if typ.listValued {
+		n.attrs["value"] = body
+		return n, nil
+	}
+
+	obj, ok := body.(map[string]interface{})
+	if !ok {
+		return nil, badRequest(propertyError{Property: typ.name, Message: "object is expected"})
+	}
+
+	var missing []propertyError
+	for _, req := range typ.required {
+		if v, ok := obj[req]; !ok || v == nil || v == "" {
+			missing = append(missing, propertyError{Property: req, Message: "may not be empty"})
+		}
+	}
+	if len(missing) > 0 {
+		return nil, badRequest(missing...)
+	}
+
+	for k, v := range obj {
+		if childType, ok := typ.collections[k]; ok {
+			if arr, ok := v.([]interface{}); ok {
+				for _, childBody := range arr {
+					child, err := s.build(childType, childBody, true, "")
+					if err != nil {
+						return nil, err
+					}
+					n.collection(k).add(child)
+				}
+			}
+		} else if singleType, ok := typ.singletons[k]; ok {
+			if v != nil {
+				child, err := s.build(singleType, v, false, "")
+				if err != nil {
+					return nil, err
+				}
+				n.singletons[k] = child
+			}
+		} else if !isServerManaged(k) {
+			n.attrs[k] = v
+		}
+	}
+
+	if keyed {
+		if len(id) == 0 {
+			id = s.nextId()
+		}
+		n.attrs["id"] = id
+	}
+
+	stamp := s.stamp()
+	n.attrs["created"] = stamp
+	n.attrs["updated"] = stamp
+	if typ.revisioned {
+		n.attrs["revisionNumber"] = 1
+	}
+	for k, length := range typ.secrets {
+		if v, ok := n.attrs[k].(string); !ok || len(v) == 0 {
+			n.attrs[k] = s.secret(length)
+		}
+	}
+
+	return n, nil
+}
+
+func (s *Server) update(n *node, body interface{}) *apiError {
+	obj, ok := body.(map[string]interface{})
+	if !ok {
+		return badRequest(propertyError{Property: n.typ.name, Message: "object is expected"})
+	}
+
+	for k, v := range obj {
+		if _, ok := n.typ.collections[k]; ok {
+			continue
+		} else if _, ok := n.typ.singletons[k]; ok {
+			continue
+		} else if !isServerManaged(k) {
+			n.attrs[k] = v
+		}
+	}
+
+	n.attrs["updated"] = s.stamp()
+	if n.typ.revisioned {
+		rev, _ := strconv.Atoi(stringOf(n.attrs["revisionNumber"]))
+		n.attrs["revisionNumber"] = rev + 1
+	}
+
+	return nil
+}
+
+func isServerManaged(k string) bool {
+	switch k {
+	case "id", "created", "updated", "revisionNumber", "passwdNew":
+		return true
+	default:
+		return false
+	}
+}
+
+const secretAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+func (s *Server) secret(length int) string {
+	s.seq++
+	sb := strings.Builder{}
+	v := uint64(s.seq)
+	for i := 0; i < length; i++ {
+		v = v*6364136223846793005 + 1442695040888963407
+		sb.WriteByte(secretAlphabet[(v>>33)%uint64(len(secretAlphabet))])
+	}
+	return sb.String()
+}
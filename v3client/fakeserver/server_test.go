@@ -0,0 +1,342 @@
+package fakeserver_test
+
+import (
+	"context"
+	"errors"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client/fakeserver"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newClient(srv *fakeserver.Server) v3client.Client {
+	return v3client.NewHttpClient(v3client.Params{
+		MashEndpoint: srv.Endpoint(),
+		QPS:          100,
+	})
+}
+
+func TestServiceLifecycle(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+
+	cl := newClient(srv)
+	ctx := context.Background()
+
+	svc, err := cl.CreateService(ctx, masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+	assert.Nil(t, err)
+	assert.True(t, len(svc.Id) > 0)
+	assert.Equal(t, 1, svc.RevisionNumber)
+
+	svc.Description = "desc"
+	upd, err := cl.UpdateService(ctx, svc)
+	assert.Nil(t, err)
+	assert.Equal(t, "desc", upd.Description)
+	assert.Equal(t, 2, upd.RevisionNumber)
+	assert.True(t, time.Time(*upd.Updated).After(time.Time(*svc.Updated)))
+
+	endp, err := cl.CreateEndpoint(ctx, svc.Identifier(), masherytypes.Endpoint{
+		AddressableV3Object: masherytypes.AddressableV3Object{Name: "endp"},
+		PublicDomains:       []masherytypes.Domain{{Address: "api.example.com"}},
+	})
+	assert.Nil(t, err)
+
+	meth, err := cl.CreateEndpointMethod(ctx, endp.Identifier(), masherytypes.ServiceEndpointMethod{
+		BaseMethod: masherytypes.BaseMethod{AddressableV3Object: masherytypes.AddressableV3Object{Name: "meth"}},
+	})
+	assert.Nil(t, err)
+
+	_, err = cl.CreateEndpointMethodFilter(ctx, meth.Identifier(), masherytypes.ServiceEndpointMethodFilter{
+		ResponseFilter: masherytypes.ResponseFilter{AddressableV3Object: masherytypes.AddressableV3Object{Name: "filter"}},
+	})
+	assert.Nil(t, err)
+
+	filters, err := cl.ListEndpointMethodFiltersWithFullInfo(ctx, meth.Identifier())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(filters))
+
+	domains, err := cl.GetPublicDomains(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []masherytypes.DomainAddress{{Address: "api.example.com"}}, domains)
+
+	assert.Nil(t, cl.SetServiceRoles(ctx, svc.Identifier(), []masherytypes.RolePermission{
+		{Role: masherytypes.Role{AddressableV3Object: masherytypes.AddressableV3Object{Id: "r"}}, Action: "read"},
+	}))
+	roles, exists, err := cl.GetServiceRoles(ctx, svc.Identifier())
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, 1, len(roles))
+
+	assert.Nil(t, cl.DeleteService(ctx, svc.Identifier()))
+	_, exists, err = cl.GetEndpoint(ctx, endp.Identifier())
+	assert.Nil(t, err)
+	assert.False(t, exists)
+}
+
+func TestPlanBindings(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+
+	cl := newClient(srv)
+	ctx := context.Background()
+
+	svc, _ := cl.CreateService(ctx, masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+	endp, _ := cl.CreateEndpoint(ctx, svc.Identifier(), masherytypes.Endpoint{
+		AddressableV3Object: masherytypes.AddressableV3Object{Name: "endp"},
+	})
+
+	pack, err := cl.CreatePackage(ctx, masherytypes.Package{AddressableV3Object: masherytypes.AddressableV3Object{Name: "pack"}})
+	assert.Nil(t, err)
+	plan, err := cl.CreatePlan(ctx, pack.Identifier(), masherytypes.Plan{AddressableV3Object: masherytypes.AddressableV3Object{Name: "plan"}})
+	assert.Nil(t, err)
+
+	planSvc := masherytypes.PackagePlanServiceIdentifier{PackagePlanIdentifier: plan.Identifier(), ServiceIdentifier: svc.Identifier()}
+	_, err = cl.CreatePlanService(ctx, planSvc)
+	assert.Nil(t, err)
+
+	planEndp := masherytypes.PackagePlanServiceEndpointIdentifier{PackagePlanIdentifier: plan.Identifier(), ServiceEndpointIdentifier: endp.Identifier()}
+	_, err = cl.CreatePlanEndpoint(ctx, planEndp)
+	assert.Nil(t, err)
+
+	exists, err := cl.CheckPlanEndpointExists(ctx, planEndp)
+	assert.Nil(t, err)
+	assert.True(t, exists)
+
+	cnt, err := cl.CountPlanEndpoints(ctx, planSvc)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), cnt)
+
+	// Deleting the endpoint removes it from the plan, too.
+	assert.Nil(t, cl.DeleteEndpoint(ctx, endp.Identifier()))
+	exists, err = cl.CheckPlanEndpointExists(ctx, planEndp)
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	// Binding to non-existing service is rejected
+	_, err = cl.CreatePlanService(ctx, masherytypes.PackagePlanServiceIdentifier{
+		PackagePlanIdentifier: plan.Identifier(),
+		ServiceIdentifier:     masherytypes.ServiceIdentifier{ServiceId: "missing"},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestMembersApplicationsAndKeys(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+
+	cl := newClient(srv)
+	ctx := context.Background()
+
+	mem, err := cl.CreateMember(ctx, masherytypes.Member{Username: "user", Email: "user@example.com"})
+	assert.Nil(t, err)
+
+	app, err := cl.CreateApplication(ctx, mem.Identifier(), masherytypes.Application{AddressableV3Object: masherytypes.AddressableV3Object{Name: "app"}})
+	assert.Nil(t, err)
+
+	key, err := cl.CreateApplicationPackageKey(ctx, app.Identifier(), masherytypes.ApplicationPackageKey{})
+	assert.Nil(t, err)
+	assert.Equal(t, 24, len(*key.Apikey))
+
+	keys, err := cl.ListPackageKeys(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(keys))
+
+	apps, err := cl.ListApplications(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(apps))
+
+	assert.Nil(t, cl.DeleteMember(ctx, mem.Identifier()))
+	apps, err = cl.ListApplications(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(apps))
+}
+
+func TestPaginationAndFiltering(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.SetPageSize(2)
+
+	cl := newClient(srv)
+	ctx := context.Background()
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		_, err := cl.CreateService(ctx, masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: name}})
+		assert.Nil(t, err)
+	}
+
+	svcs, err := cl.ListServices(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(svcs))
+
+	cnt, err := cl.CountServices(ctx, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), cnt)
+
+	filtered, err := cl.ListServicesFiltered(ctx, map[string]string{"name": "c"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(filtered))
+	assert.Equal(t, "c", filtered[0].Name)
+}
+
+func TestIteratingMembers(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.SetPageSize(2)
+
+	cl := newClient(srv)
+	ctx := context.Background()
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		_, err := cl.CreateMember(ctx, masherytypes.Member{Username: name, Email: name + "@example.com"})
+		assert.Nil(t, err)
+	}
+
+	it, err := cl.IterateMembers(ctx, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), it.Total())
+
+	var names []string
+	for it.Next() {
+		names = append(names, it.Value().Username)
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, names)
+
+	// Closing the iterator stops fetching further pages
+	srv.ResetCalls()
+	it, err = cl.IterateMembers(ctx, nil)
+	assert.Nil(t, err)
+	assert.True(t, it.Next())
+	it.Close()
+	assert.False(t, it.Next())
+	assert.Equal(t, 1, len(srv.Calls()))
+}
+
+func TestErrorResponses(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.AddRole(masherytypes.Role{AddressableV3Object: masherytypes.AddressableV3Object{Id: "role-id", Name: "Role"}})
+
+	cl := newClient(srv)
+	ctx := context.Background()
+
+	_, exists, err := cl.GetService(ctx, masherytypes.ServiceIdentifier{ServiceId: "missing"})
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	_, err = cl.CreateService(ctx, masherytypes.Service{})
+	assert.NotNil(t, err)
+
+	role, exists, err := cl.GetRole(ctx, "role-id")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "Role", role.Name)
+
+	srv.InjectFault(fakeserver.Fault{Path: "/roles", StatusCode: 500, Times: 1})
+	_, err = cl.ListRoles(ctx)
+	assert.NotNil(t, err)
+
+	roles, err := cl.ListRoles(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(roles))
+}
+
+func TestTypedErrors(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+
+	cl := newClient(srv)
+	ctx := context.Background()
+
+	// Validation failure reports the offending request
+	_, err := cl.CreateService(ctx, masherytypes.Service{})
+	assert.True(t, errors.Is(err, transport.ErrValidation))
+
+	var v3Err *transport.V3Error
+	assert.True(t, errors.As(err, &v3Err))
+	assert.Equal(t, 400, v3Err.StatusCode)
+	assert.Equal(t, "POST", v3Err.Method)
+	assert.True(t, strings.HasPrefix(v3Err.URL, srv.Endpoint()+"/services"))
+
+	// Update of the object that does not exist
+	_, err = cl.UpdateService(ctx, masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Id: "missing", Name: "x"}})
+	assert.True(t, errors.Is(err, transport.ErrNotFound))
+
+	// Server failures are transient and carry the Mashery error code
+	srv.InjectFault(fakeserver.Fault{Path: "/roles", StatusCode: 503, ErrorCode: "ERR_503_SERVICE_UNAVAILABLE", Times: 1})
+	_, err = cl.ListRoles(ctx)
+	assert.True(t, errors.Is(err, transport.ErrTransient))
+	assert.True(t, errors.As(err, &v3Err))
+	assert.Equal(t, "ERR_503_SERVICE_UNAVAILABLE", v3Err.ErrorCode)
+
+	// Identifiers that are not sufficient to address the object are rejected before the call
+	_, err = cl.UpdatePackageKey(ctx, masherytypes.PackageKey{})
+	assert.True(t, errors.Is(err, transport.ErrInvalidIdentifier))
+}
+
+func TestAccessTokenIsRequired(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+	srv.RequireAccessToken("token")
+
+	cl := newClient(srv)
+	_, err := cl.ListServices(context.Background())
+	assert.NotNil(t, err)
+}
+
+func TestCompareAndUpdate(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+
+	cl := newClient(srv)
+	ctx := context.Background()
+	casCtx := v3client.CompareAndUpdate(ctx)
+
+	svc, err := cl.CreateService(ctx, masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+	assert.Nil(t, err)
+	endp, err := cl.CreateEndpoint(ctx, svc.Identifier(), masherytypes.Endpoint{
+		AddressableV3Object: masherytypes.AddressableV3Object{Name: "endp"},
+	})
+	assert.Nil(t, err)
+
+	// The first engineer updates the copies they have read.
+	svc.Description = "first"
+	svcUpd, err := cl.UpdateService(casCtx, svc)
+	assert.Nil(t, err)
+	endp.Name = "first"
+	_, err = cl.UpdateEndpoint(casCtx, endp)
+	assert.Nil(t, err)
+
+	// The second engineer holds the stale copies.
+	svc.Description = "second"
+	_, err = cl.UpdateService(casCtx, svc)
+	assert.True(t, errors.Is(err, transport.ErrConflict))
+	var cmErr *v3client.ConcurrentModificationError
+	assert.True(t, errors.As(err, &cmErr))
+	assert.Equal(t, "1", cmErr.Expected)
+	assert.Equal(t, "2", cmErr.Actual)
+
+	endp.Name = "second"
+	_, err = cl.UpdateEndpoint(casCtx, endp)
+	assert.True(t, errors.Is(err, transport.ErrConflict))
+
+	got, _, err := cl.GetEndpoint(ctx, endp.Identifier())
+	assert.Nil(t, err)
+	assert.Equal(t, "first", got.Name)
+
+	// The fresh copy is updated, and the updates without the check overwrite unconditionally.
+	svcUpd.Description = "second"
+	_, err = cl.UpdateService(casCtx, svcUpd)
+	assert.Nil(t, err)
+	_, err = cl.UpdateEndpoint(ctx, endp)
+	assert.Nil(t, err)
+
+	assert.Nil(t, cl.DeleteEndpoint(ctx, endp.Identifier()))
+	_, err = cl.UpdateEndpoint(casCtx, got)
+	assert.True(t, errors.As(err, &cmErr))
+	assert.Equal(t, "", cmErr.Actual)
+}
@@ -60,6 +60,12 @@ func (f *FileSystemTokenProvider) checkFileSync() {
 	f.lastFSCheck = now
 }
 
+// Invalidate makes the provider re-read the file on the next call, which could have been updated with the new token
+func (f *FileSystemTokenProvider) Invalidate() {
+	f.lastFSCheck = time.Time{}
+	f.sourceLastModified = time.Time{}
+}
+
 func (f *FileSystemTokenProvider) Close() {
 	// Do nothing
 }
@@ -1,17 +1,13 @@
 package v3client
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/aliakseiyanchuk/mashery-v3-go-client/errwrap"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
 	"gopkg.in/yaml.v2"
-	"io"
 	"io/ioutil"
+	"log/slog"
 	"os"
 	"path/filepath"
 )
@@ -53,50 +49,6 @@ func DefaultCredentialsFile() string {
 	}
 }
 
-func EncryptInPlace(path string, pass string) error {
-	if len(pass) != 32 {
-		return errors.New("password should be 32 characters")
-	}
-
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return &errwrap.WrappedError{Context: "reading", Cause: err}
-	}
-
-	if cphr, err := aes.NewCipher([]byte(pass)); err != nil {
-		return &errwrap.WrappedError{Context: "obtaining cipher", Cause: err}
-	} else if gcm, err := cipher.NewGCM(cphr); err != nil {
-		return &errwrap.WrappedError{Context: "failed to initialize counter", Cause: err}
-	} else {
-		nonce := make([]byte, gcm.NonceSize())
-		if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
-			return &errwrap.WrappedError{Context: "cannot initialize nonce", Cause: err}
-		} else {
-			return ioutil.WriteFile(path, gcm.Seal(nonce, nonce, data, nil), 0777)
-		}
-	}
-}
-
-func ReadCiphertext(fileName string, pass string) ([]byte, error) {
-	if len(pass) != 32 {
-		return []byte{}, errors.New("password must be exactly 32 characters")
-	}
-
-	if ciphertext, err := ioutil.ReadFile(fileName); err != nil {
-		return []byte{}, &errwrap.WrappedError{Context: "reading source file", Cause: err}
-	} else if chr, err := aes.NewCipher([]byte(pass)); err != nil {
-		return []byte{}, &errwrap.WrappedError{Context: "initializing cipher", Cause: err}
-	} else if gcmDecrypt, err := cipher.NewGCM(chr); err != nil {
-		return []byte{}, &errwrap.WrappedError{Context: "initializing counter", Cause: err}
-	} else {
-		nonceSize := gcmDecrypt.NonceSize()
-		nonce, encryptedMessage := ciphertext[:nonceSize], ciphertext[nonceSize:]
-
-		dat, err := gcmDecrypt.Open(nil, nonce, encryptedMessage, nil)
-		return dat, err
-	}
-}
-
 func tryReadCredentialSettingsFromYamlFile(m *MasheryV3Credentials, file, pass string) {
 	if _, err := os.Stat(file); err == nil || os.IsExist(err) {
 		if dat, err := ReadCiphertext(file, pass); err == nil {
@@ -105,7 +57,7 @@ func tryReadCredentialSettingsFromYamlFile(m *MasheryV3Credentials, file, pass s
 				m.Inherit(&fsCreds)
 			}
 		} else {
-			fmt.Println(err)
+			slog.Warn("could not read the credentials file", "file", file, "error", err)
 		}
 	}
 	// else: the settings file doesn't exist.
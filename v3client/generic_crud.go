@@ -6,10 +6,13 @@ import (
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const fieldsContextKey = contextKeyType("fields.v3.client.mashery")
+const compareAndUpdateContextKey = contextKeyType("compare.and.update.v3.client.mashery")
 
 type GenericCRUDDecorator[ParentIdent, TIdent, T any] struct {
 	ValueSupplier      transport.Supplier[T]
@@ -28,6 +31,11 @@ type GenericCRUDDecorator[ParentIdent, TIdent, T any] struct {
 	AcceptObjectIdent BiConsumer[TIdent, *T]
 	AcceptParentIdent BiConsumer[ParentIdent, *T]
 	AcceptIdentFrom   BiConsumer[T, *T]
+
+	// VersionField the field carrying the version of the object, which VersionOf extracts; where set, the object
+	// can be updated with CompareAndUpdate.
+	VersionField string
+	VersionOf    func(T) string
 }
 
 // Dummy int supplier
@@ -39,6 +47,64 @@ func ReturnFields(ctx context.Context, v []string) context.Context {
 	return context.WithValue(ctx, fieldsContextKey, v)
 }
 
+// CompareAndUpdate makes the updates performed with the returned context fail with ConcurrentModificationError
+// where the object was modified since the caller has read it, instead of overwriting the concurrent changes.
+// The object is re-read before the update and its version (the `updated` timestamp, or the revision number
+// of the services) is compared with the version of the caller's copy.
+//
+// The check narrows, but cannot close, the window for the conflicting updates, as V3 API does not support
+// conditional updates.
+func CompareAndUpdate(ctx context.Context) context.Context {
+	return context.WithValue(ctx, compareAndUpdateContextKey, true)
+}
+
+func compareAndUpdateRequested(ctx context.Context) bool {
+	if v := ctx.Value(compareAndUpdateContextKey); v != nil {
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	}
+	return false
+}
+
+// ConcurrentModificationError the object was modified, or deleted, since the caller has read it. The error
+// matches transport.ErrConflict.
+type ConcurrentModificationError struct {
+	Object   string
+	Resource string
+	// Expected the version of the caller's copy
+	Expected string
+	// Actual the current version of the object; empty where the object was deleted
+	Actual string
+}
+
+func (e *ConcurrentModificationError) Error() string {
+	if len(e.Actual) == 0 {
+		return fmt.Sprintf("%s %s was deleted concurrently", e.Object, e.Resource)
+	}
+	return fmt.Sprintf("%s %s was modified concurrently: expected version %s, found %s", e.Object, e.Resource, e.Expected, e.Actual)
+}
+
+func (e *ConcurrentModificationError) Unwrap() error {
+	return transport.ErrConflict
+}
+
+// updatedVersion the version of the object, which is the time it was last updated at
+func updatedVersion(obj masherytypes.AddressableV3Object) string {
+	if obj.Updated == nil {
+		return ""
+	}
+	return time.Time(*obj.Updated).UTC().Format(time.RFC3339Nano)
+}
+
+// revisionVersion the version of the service, which is its revision number
+func revisionVersion(rev int) string {
+	if rev <= 0 {
+		return ""
+	}
+	return strconv.Itoa(rev)
+}
+
 func DefaultGetFieldsFromContext(defaultFields []string) func(context.Context) []string {
 	return func(ctx context.Context) []string {
 		if v := ctx.Value(fieldsContextKey); v != nil {
@@ -116,14 +182,48 @@ func NewCRUD[TParent, TIdent, T any](appContext string, d *GenericCRUDDecorator[
 	return &rv
 }
 
+// invalidIdentifier describes the identifier the resource of the object cannot be determined for
+func (crud *GenericCRUD[TParent, TIdent, T]) invalidIdentifier(err error) error {
+	return &transport.V3Error{
+		Message: fmt.Sprintf("%s: cannot determine the resource", crud.AppContext),
+		Kind:    transport.ErrInvalidIdentifier,
+		Cause:   err,
+	}
+}
+
+func (crud *GenericCRUD[TParent, TIdent, T]) resourceFor(ident TIdent) (string, error) {
+	if rv, err := crud.Decorator.ResourceFor(ident); err != nil {
+		return rv, crud.invalidIdentifier(err)
+	} else {
+		return rv, nil
+	}
+}
+
+func (crud *GenericCRUD[TParent, TIdent, T]) resourceForParent(ident TParent) (string, error) {
+	if rv, err := crud.Decorator.ResourceForParent(ident); err != nil {
+		return rv, crud.invalidIdentifier(err)
+	} else {
+		return rv, nil
+	}
+}
+
+func (crud *GenericCRUD[TParent, TIdent, T]) resourceForUpsert(upsert T) (string, error) {
+	if rv, err := crud.Decorator.ResourceForUpsert(upsert); err != nil {
+		return rv, crud.invalidIdentifier(err)
+	} else {
+		return rv, nil
+	}
+}
+
 type CRUDGetter[TIdent, T any] func(ctx context.Context, ident TIdent, c *transport.HttpTransport) (T, bool, error)
 type CRUDCreator[TParent, T any] func(ctx context.Context, ident TParent, upsert T, c *transport.HttpTransport) (T, error)
 type CRUDAllFetcher[TParent, T any] func(ctx context.Context, ident TParent, c *transport.HttpTransport) ([]T, error)
 type CRUDFilteredFetcher[TParent, T any] func(ctx context.Context, ident TParent, filter map[string]string, c *transport.HttpTransport) ([]T, error)
+type CRUDIterator[TParent, T any] func(ctx context.Context, ident TParent, filter map[string]string, c *transport.HttpTransport) (*transport.ObjectIterator[T], error)
 type CRUDFilteredCounter[TParent, T any] func(ctx context.Context, ident TParent, filter map[string]string, c *transport.HttpTransport) (int64, error)
 
 func (crud *GenericCRUD[TParent, TIdent, T]) Get(ctx context.Context, ident TIdent, c *transport.HttpTransport) (T, bool, error) {
-	if resourceURL, err := crud.Decorator.ResourceFor(ident); err != nil {
+	if resourceURL, err := crud.resourceFor(ident); err != nil {
 		return crud.StubValue(), false, err
 	} else {
 		fetchSpecBuilder := transport.ObjectFetchSpecBuilder[T]{}
@@ -152,7 +252,9 @@ func (crud *GenericCRUD[TParent, TIdent, T]) StubValue() T {
 }
 
 func (crud *GenericCRUD[TParent, TIdent, T]) Exists(ctx context.Context, ident TIdent, c *transport.HttpTransport) (bool, error) {
-	if resourceURL, err := crud.Decorator.ResourceFor(ident); err != nil {
+	if resourceURL, err := crud.resourceFor(ident); err != nil {
+		return false, err
+	} else {
 		fetchSpecBuilder := transport.ObjectFetchSpecBuilder[T]{}
 		fetchSpecBuilder.
 			WithValueFactory(crud.Decorator.ValueSupplier).
@@ -161,13 +263,11 @@ func (crud *GenericCRUD[TParent, TIdent, T]) Exists(ctx context.Context, ident T
 			WithReturn404AsNil(true)
 
 		return transport.Exists(ctx, fetchSpecBuilder.Build(), c)
-	} else {
-		return false, err
 	}
 }
 
 func (crud *GenericCRUD[TParent, TIdent, T]) Create(ctx context.Context, ident TParent, upsert T, c *transport.HttpTransport) (T, error) {
-	if resourceURL, err := crud.Decorator.ResourceForParent(ident); err != nil {
+	if resourceURL, err := crud.resourceForParent(ident); err != nil {
 		return crud.StubValue(), err
 	} else {
 		fetchSpecBuilder := transport.ObjectUpsertSpecBuilder[T]{}
@@ -190,9 +290,15 @@ func (crud *GenericCRUD[TParent, TIdent, T]) Create(ctx context.Context, ident T
 }
 
 func (crud *GenericCRUD[TParent, TIdent, T]) Update(ctx context.Context, upsert T, c *transport.HttpTransport) (T, error) {
-	if resourceURL, err := crud.Decorator.ResourceForUpsert(upsert); err != nil {
+	if resourceURL, err := crud.resourceForUpsert(upsert); err != nil {
 		return crud.StubValue(), err
 	} else {
+		if compareAndUpdateRequested(ctx) {
+			if casErr := crud.compareVersion(ctx, resourceURL, upsert, c); casErr != nil {
+				return crud.StubValue(), casErr
+			}
+		}
+
 		if crud.Decorator.UpsertCleaner != nil {
 			crud.Decorator.UpsertCleaner(&upsert)
 		}
@@ -216,8 +322,54 @@ func (crud *GenericCRUD[TParent, TIdent, T]) Update(ctx context.Context, upsert
 	}
 }
 
+// compareVersion checks that the object stored in Mashery has the same version as the caller's copy
+func (crud *GenericCRUD[TParent, TIdent, T]) compareVersion(ctx context.Context, resourceURL string, upsert T, c *transport.HttpTransport) error {
+	if crud.Decorator.VersionOf == nil {
+		return &transport.V3Error{
+			Message: fmt.Sprintf("%s does not support compare-and-update", crud.AppContext),
+			Kind:    transport.ErrValidation,
+		}
+	}
+
+	expected := crud.Decorator.VersionOf(upsert)
+	if len(expected) == 0 {
+		return &transport.V3Error{
+			Message: fmt.Sprintf("%s %s: the object carries no version to compare", crud.AppContext, resourceURL),
+			Kind:    transport.ErrValidation,
+		}
+	}
+
+	fetchSpecBuilder := transport.ObjectFetchSpecBuilder[T]{}
+	fetchSpecBuilder.
+		WithValueFactory(crud.Decorator.ValueSupplier).
+		WithResource(resourceURL).
+		WithQuery(url.Values{
+			"fields": []string{"id," + crud.Decorator.VersionField},
+		}).
+		WithAppContext(crud.AppContext).
+		WithReturn404AsNil(true)
+
+	current, exists, err := crud.doGet(ctx, fetchSpecBuilder.Build(), c)
+	if err != nil {
+		return err
+	}
+
+	rv := &ConcurrentModificationError{
+		Object:   crud.AppContext,
+		Resource: resourceURL,
+		Expected: expected,
+	}
+	if !exists {
+		return rv
+	}
+	if rv.Actual = crud.Decorator.VersionOf(current); rv.Actual != expected {
+		return rv
+	}
+	return nil
+}
+
 func (crud *GenericCRUD[TParent, TIdent, T]) Delete(ctx context.Context, id TIdent, c *transport.HttpTransport) error {
-	if resourceURL, err := crud.Decorator.ResourceFor(id); err != nil {
+	if resourceURL, err := crud.resourceFor(id); err != nil {
 		return err
 	} else {
 		objectUpsertSpecBuilder := transport.ObjectFetchSpecBuilder[T]{}
@@ -237,7 +389,7 @@ func (crud *GenericCRUD[TParent, TIdent, T]) Count(ctx context.Context, id TPare
 }
 
 func (crud *GenericCRUD[TParent, TIdent, T]) CountFiltered(ctx context.Context, id TParent, filter map[string]string, c *transport.HttpTransport) (int64, error) {
-	if resourceURL, err := crud.Decorator.ResourceForParent(id); err != nil {
+	if resourceURL, err := crud.resourceForParent(id); err != nil {
 		return 0, err
 	} else {
 		objectUpsertSpecBuilder := transport.ObjectListFetchSpecBuilder[T]{}
@@ -258,7 +410,7 @@ func (crud *GenericCRUD[TParent, TIdent, T]) FetchAllAsAddressable(ctx context.C
 }
 
 func (crud *GenericCRUD[TParent, TIdent, T]) FetchAllAsAddressableFiltered(ctx context.Context, id TParent, filter map[string]string, c *transport.HttpTransport) ([]masherytypes.AddressableV3Object, error) {
-	if resourceURL, err := crud.Decorator.ResourceForParent(id); err != nil {
+	if resourceURL, err := crud.resourceForParent(id); err != nil {
 		return nil, err
 	} else {
 		objectListSpecBuilder := transport.ObjectListFetchSpecBuilder[masherytypes.AddressableV3Object]{}
@@ -280,7 +432,7 @@ func (crud *GenericCRUD[TParent, TIdent, T]) FetchAll(ctx context.Context, id TP
 }
 
 func (crud *GenericCRUD[TParent, TIdent, T]) FetchFiltered(ctx context.Context, id TParent, filter map[string]string, c *transport.HttpTransport) ([]T, error) {
-	if resourceURL, err := crud.Decorator.ResourceForParent(id); err != nil {
+	if resourceURL, err := crud.resourceForParent(id); err != nil {
 		return nil, err
 	} else {
 		objectListSpecBuilder := transport.ObjectListFetchSpecBuilder[T]{}
@@ -307,6 +459,31 @@ func (crud *GenericCRUD[TParent, TIdent, T]) FetchFiltered(ctx context.Context,
 	}
 }
 
+// Iterate streams the objects of the parent matching the filter page by page. The filter may be nil.
+func (crud *GenericCRUD[TParent, TIdent, T]) Iterate(ctx context.Context, id TParent, filter map[string]string, c *transport.HttpTransport) (*transport.ObjectIterator[T], error) {
+	if resourceURL, err := crud.resourceForParent(id); err != nil {
+		return nil, err
+	} else {
+		objectListSpecBuilder := transport.ObjectListFetchSpecBuilder[T]{}
+		objectListSpecBuilder.
+			WithValueFactory(crud.Decorator.ValueArraySupplier).
+			WithResource(resourceURL).
+			WithQuery(crud.querySupplier(ctx)).
+			WithMergedQuery(crud.toFilterQuery(filter)).
+			WithAppContext(crud.AppContext).
+			WithPagination(crud.Decorator.Pagination)
+
+		rv, iterErr := transport.Iterate(ctx, objectListSpecBuilder.Build(), c)
+		if iterErr == nil && crud.Decorator.AcceptParentIdent != nil {
+			rv.Decorate = func(t *T) {
+				crud.Decorator.AcceptParentIdent(id, t)
+			}
+		}
+
+		return rv, iterErr
+	}
+}
+
 func (crud *GenericCRUD[TParent, TIdent, T]) toFilterQuery(filter map[string]string) url.Values {
 	if len(filter) > 0 {
 		srchAtoms := make([]string, len(filter))
@@ -339,6 +516,13 @@ func RootFetcher[TParent, T any](fetcher CRUDAllFetcher[TParent, T], rootIdent T
 	}
 }
 
+// RootIterator builds an iterating function that wraps a parent context object.
+func RootIterator[TParent, T any](iterator CRUDIterator[TParent, T], rootIdent TParent) func(context.Context, map[string]string, *transport.HttpTransport) (*transport.ObjectIterator[T], error) {
+	return func(ctx context.Context, filter map[string]string, c *transport.HttpTransport) (*transport.ObjectIterator[T], error) {
+		return iterator(ctx, rootIdent, filter, c)
+	}
+}
+
 func RootCreator[TParent, T any](creator CRUDCreator[TParent, T], rootIdent TParent) func(ctx context.Context, t T, c *transport.HttpTransport) (T, error) {
 	return func(ctx context.Context, t T, c *transport.HttpTransport) (T, error) {
 		return creator(ctx, rootIdent, t, c)
@@ -1,5 +1,15 @@
 package v3client
 
+import (
+	"context"
+	"errors"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client/fakeserver"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
 // Method to allow override of the transport interactions.
 
 //func (crud *GenericCRUD[TParent, TIdent, T]) GetUsing(f func(ctx context.Context, opCtx transport.ObjectFetchSpec[T], c *transport.HttpTransport) (T, error)) {
@@ -10,3 +20,29 @@ package v3client
 //func (crud *GenericCRUD[TParent, TIdent, T]) UpdateUsing(f func(ctx context.Context, opCtx transport.ObjectUpsertSpec[T], c *transport.HttpTransport) (T, error)) {
 //	crud.doUpdate = f
 //}
+
+func TestGenericCRUDExists(t *testing.T) {
+	srv := fakeserver.New()
+	defer srv.Close()
+
+	params := Params{MashEndpoint: srv.Endpoint(), QPS: 100}
+	params.FillDefaults()
+	tr := createHTTPTransport(params)
+
+	cl := NewHttpClient(Params{MashEndpoint: srv.Endpoint(), QPS: 100})
+	ctx := context.Background()
+	svc, err := cl.CreateService(ctx, masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+	assert.Nil(t, err)
+
+	exists, err := serviceCRUD.Exists(ctx, svc.Identifier(), &tr)
+	assert.Nil(t, err)
+	assert.True(t, exists)
+
+	exists, err = serviceCRUD.Exists(ctx, masherytypes.ServiceIdentifier{ServiceId: "missing"}, &tr)
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	// The identifier the resource cannot be determined for is not fetched.
+	_, err = serviceCRUD.Exists(ctx, masherytypes.ServiceIdentifier{}, &tr)
+	assert.True(t, errors.Is(err, transport.ErrInvalidIdentifier))
+}
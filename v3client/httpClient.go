@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"log/slog"
 	"net/http"
 	"sync"
 	"time"
@@ -30,6 +31,16 @@ type V3AccessTokenProvider interface {
 	AccessToken(context context.Context) (string, error)
 }
 
+// InvalidatingTokenProvider V3AccessTokenProvider able to discard the access token Mashery has rejected. Where
+// the client is configured with such provider, the calls failing due to the revoked or expired token are replayed
+// once with the new token, see transport.ReauthenticateFunc.
+type InvalidatingTokenProvider interface {
+	V3AccessTokenProvider
+
+	// Invalidate discards the current access token, so that the next call obtains a new one
+	Invalidate()
+}
+
 func NewCustomClient(schema *ClientMethodSchema) Client {
 	rv := FixedSchemeClient{
 		PluggableClient{
@@ -43,11 +54,51 @@ func NewCustomClient(schema *ClientMethodSchema) Client {
 type Params struct {
 	transport.HTTPClientParams
 
-	MashEndpoint  string
-	Authorizer    transport.Authorizer
+	MashEndpoint string
+	Authorizer   transport.Authorizer
+	// QPS the rate of the default limiter. The default limiter is a token bucket permitting QPS calls per second
+	// with the Burst that defaults to QPS; the earlier versions allocated the calls to wall-clock seconds instead,
+	// which transport.PerSecondLimiter still does where set as RateLimiter.
 	QPS           int64
 	AvgNetLatency time.Duration
 
+	// Burst the number of calls that can be sent without waiting after a period of inactivity. Defaults to QPS.
+	Burst int
+	// RateLimiter explicit rate limiter; where set, QPS and Burst are ignored. Clients using the same API key
+	// can share the QPS budget with transport.SharedRateLimiter, or transport.NewFileRateLimiter across processes.
+	RateLimiter transport.RateLimiter
+
+	// AdaptiveThrottle where set, replaces the fixed back-off on ERR_403_DEVELOPER_OVER_QPS in the default
+	// pipeline, adjusting the rate of the limiter to the rate Mashery allows.
+	AdaptiveThrottle *transport.AdaptiveThrottle
+
+	// RetryPolicy where set, retries the calls failing with transient network errors and with the response
+	// statuses listed in its RetryableStatuses; transport.DefaultRetryPolicy retries 502, 503 and 504 responses
+	RetryPolicy *transport.RetryPolicy
+
+	// QuotaScheduler where set, replaces the default handling of ERR_403_DEVELOPER_OVER_RATE in the default
+	// pipeline, e.g. to park the calls until the quota is reset.
+	QuotaScheduler *transport.QuotaScheduler
+
+	// CircuitBreaker where set, fails the calls to the resource families with the high rate of transient failures
+	// immediately, without sending them to Mashery.
+	CircuitBreaker *transport.CircuitBreaker
+
+	// ResponseCache where set, the objects fetched before are requested conditionally, and the objects Mashery
+	// reports as not modified are served from the cache, e.g. transport.NewMemoryResponseCache.
+	ResponseCache transport.ResponseCache
+
+	// Metrics where set, receives the observations of each call, e.g. transport.NewPrometheusMetrics
+	Metrics transport.MetricsSink
+
+	// MaxConcurrentFetches limits the number of pages that are fetched concurrently while listing objects
+	MaxConcurrentFetches int
+
+	// Logger where set, each call is logged with the secrets redacted, see transport.NewSlogListener
+	Logger *slog.Logger
+	// LogOptions the levels and the redaction of the call logging; transport.DefaultLogOptions where nil
+	LogOptions *transport.LogOptions
+
 	Pipeline []transport.ChainedMiddlewareFunc
 }
 
@@ -59,6 +110,12 @@ func (p *Params) FillDefaults() {
 	if p.QPS <= 0 {
 		p.QPS = 2
 	}
+	if p.Burst <= 0 {
+		p.Burst = int(p.QPS)
+	}
+	if p.RateLimiter == nil {
+		p.RateLimiter = transport.NewTokenBucketLimiter(float64(p.QPS), p.Burst)
+	}
 	if p.Timeout <= 0 {
 		p.Timeout = time.Minute * 2
 	}
@@ -73,14 +130,35 @@ func (p *Params) FillDefaults() {
 	}
 
 	if len(p.Pipeline) == 0 {
+		backOff := transport.BackOffOnDeveloperOverQPSFunc
+		if p.AdaptiveThrottle != nil {
+			backOff = p.AdaptiveThrottle.Func
+		}
+		overRate := transport.BreakOnDeveloperOverRateFunc
+		if p.QuotaScheduler != nil {
+			overRate = p.QuotaScheduler.Func
+		}
+
 		p.Pipeline = []transport.ChainedMiddlewareFunc{
 			transport.ThrottleFunc,
-			transport.BreakOnDeveloperOverRateFunc,
-			transport.BackOffOnDeveloperOverQPSFunc,
+		}
+		if p.RetryPolicy != nil {
+			p.Pipeline = append(p.Pipeline, p.RetryPolicy.Func)
+		}
+		p.Pipeline = append(p.Pipeline,
+			overRate,
+			backOff,
 			transport.ErrorOn404Func,
 			transport.RetryOn400Func,
 			transport.EnsureBodyWasRead,
 			transport.UnmarshalServerError,
+			transport.ReauthenticateFunc,
+		)
+		if p.CircuitBreaker != nil {
+			p.Pipeline = append(p.Pipeline, p.CircuitBreaker.Func)
+		}
+		if p.Metrics != nil {
+			p.Pipeline = append(p.Pipeline, transport.MetricsFunc(p.Metrics))
 		}
 	}
 }
@@ -109,6 +187,19 @@ func newHttpClientWithSchema(p Params, s *ClientMethodSchema) Client {
 	return &rv
 }
 
+// exchangeListener the configured exchange listener, chained with the call logging where the logger is set
+func (p Params) exchangeListener() transport.ExchangeListener {
+	if p.Logger == nil {
+		return p.ExchangeListener
+	}
+
+	opts := transport.DefaultLogOptions()
+	if p.LogOptions != nil {
+		opts = *p.LogOptions
+	}
+	return transport.ChainListeners(p.ExchangeListener, transport.NewSlogListener(p.Logger, opts))
+}
+
 func createHTTPTransport(p Params) transport.HttpTransport {
 	return transport.HttpTransport{
 		MashEndpoint:  p.MashEndpoint,
@@ -116,10 +207,14 @@ func createHTTPTransport(p Params) transport.HttpTransport {
 		AvgNetLatency: p.AvgNetLatency,
 		Mutex:         &sync.Mutex{},
 		MaxQPS:        p.QPS,
+		RateLimiter:   p.RateLimiter,
+
+		MaxConcurrentFetches: p.MaxConcurrentFetches,
+		ResponseCache:        p.ResponseCache,
 
 		HttpExecutor: p.CreateHttpExecutor(),
 
-		ExchangeListener: p.ExchangeListener,
+		ExchangeListener: p.exchangeListener(),
 		Pipeline:         transport.BuildPipeline(transport.ExecuteFunction, p.Pipeline),
 	}
 }
@@ -201,6 +296,7 @@ func StandardClientMethodSchema() *ClientMethodSchema {
 		ListApplicationsFiltered: func(ctx context.Context, params map[string]string, c *transport.HttpTransport) ([]masherytypes.Application, error) {
 			return applicationCRUD.FetchFiltered(ctx, masherytypes.MemberIdentifier{}, params, c)
 		},
+		IterateApplications: RootIterator(applicationCRUD.Iterate, masherytypes.MemberIdentifier{}),
 
 		// Email sets
 		GetEmailTemplateSet:           emailTemplateSetCRUD.Get,
@@ -244,6 +340,7 @@ func StandardClientMethodSchema() *ClientMethodSchema {
 		DeleteMember:        memberCRUD.Delete,
 		ListMembers:         RootFetcher(memberCRUD.FetchAll, 0),
 		ListMembersFiltered: RootFilteredFetcher(memberCRUD.FetchFiltered, 0),
+		IterateMembers:      RootIterator(memberCRUD.Iterate, 0),
 
 		// Packages
 		GetPackage:            packageCRUD.Get,
@@ -299,6 +396,7 @@ func StandardClientMethodSchema() *ClientMethodSchema {
 
 		ListPackageKeysFiltered: RootFilteredFetcher(packageKeyCRUD.FetchFiltered, 0),
 		ListPackageKeys:         RootFetcher(packageKeyCRUD.FetchAll, 0),
+		IteratePackageKeys:      RootIterator(packageKeyCRUD.Iterate, 0),
 
 		// Roles
 		GetRole:           roleCRUD.Get,